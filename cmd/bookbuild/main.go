@@ -0,0 +1,211 @@
+// Command bookbuild writes a PolyGlot opening book from PGN games, or merges
+// existing books with -merge.
+//
+//	bookbuild -o book.bin -maxply 20 -mingames 5 games1.pgn games2.pgn
+//	bookbuild -merge -o merged.bin first.bin second.bin
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+	"github.com/likeawizard/tofiks/pkg/book"
+	"github.com/likeawizard/tofiks/pkg/pgn"
+)
+
+// counts are the results of the games a move was played in, from the point of
+// view of the side playing it.
+type counts struct {
+	wins, draws, losses int
+}
+
+// weight scores a move 2 per win and 1 per draw, as PolyGlot does.
+func (c counts) weight() int {
+	return 2*c.wins + c.draws
+}
+
+func (c counts) games() int {
+	return c.wins + c.draws + c.losses
+}
+
+type filter struct {
+	maxPly   int
+	minElo   int
+	winners  bool
+	minGames int
+}
+
+func main() {
+	var (
+		outPath string
+		merge   bool
+		f       filter
+	)
+	flag.StringVar(&outPath, "o", "book.bin", "Output book")
+	flag.BoolVar(&merge, "merge", false, "Merge PolyGlot books instead of reading PGNs")
+	flag.IntVar(&f.maxPly, "maxply", 20, "Only record moves up to this ply")
+	flag.IntVar(&f.minGames, "mingames", 1, "Drop positions reached in fewer games")
+	flag.IntVar(&f.minElo, "minelo", 0, "Only record moves by players rated at least this")
+	flag.BoolVar(&f.winners, "winners", false, "Only record moves by the side that won the game")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		log.Fatal("No input files")
+	}
+
+	var entries []book.RawEntry
+	if merge {
+		var err error
+		if entries, err = mergeBooks(flag.Args()); err != nil {
+			log.Fatalf("Failed to merge books: %v", err)
+		}
+	} else {
+		stats := make(map[uint64]map[uint16]*counts)
+		for _, path := range flag.Args() {
+			games, err := pgn.ParseFile(path)
+			if err != nil {
+				log.Fatalf("Failed to parse %s: %v", path, err)
+			}
+			recorded := 0
+			for _, g := range games {
+				recorded += addGame(stats, g, f)
+			}
+			log.Printf("%s: %d games, %d moves recorded", path, len(games), recorded)
+		}
+		entries = buildEntries(stats, f.minGames)
+	}
+
+	if err := book.WriteFile(outPath, entries); err != nil {
+		log.Fatalf("Failed to write book: %v", err)
+	}
+	log.Printf("Wrote %d entries to %s", len(entries), outPath)
+}
+
+// addGame records the moves of one game that pass the filter and returns how
+// many were recorded.
+func addGame(stats map[uint64]map[uint16]*counts, g pgn.Game, f filter) int {
+	whiteScore, ok := pgn.Score(g.Result)
+	if !ok {
+		return 0
+	}
+	elo := [2]int{rating(g.Headers["WhiteElo"]), rating(g.Headers["BlackElo"])}
+
+	b := board.NewBoard(g.StartFEN)
+	recorded := 0
+	for ply, token := range g.Moves {
+		if ply >= f.maxPly {
+			break
+		}
+		move, err := pgn.ParseMove(b, token)
+		if err != nil {
+			break
+		}
+
+		score := whiteScore
+		if b.Side == board.Black {
+			score = -score
+		}
+		if (!f.winners || score > 0) && elo[b.Side] >= f.minElo {
+			key := book.PolyZobrist(b)
+			moves := stats[key]
+			if moves == nil {
+				moves = make(map[uint16]*counts)
+				stats[key] = moves
+			}
+			poly := book.EncodeMove(move)
+			c := moves[poly]
+			if c == nil {
+				c = &counts{}
+				moves[poly] = c
+			}
+			switch score {
+			case 1:
+				c.wins++
+			case 0:
+				c.draws++
+			default:
+				c.losses++
+			}
+			recorded++
+		}
+		b.MakeMove(move)
+	}
+	return recorded
+}
+
+// rating parses an Elo header. Missing or unknown ratings count as 0.
+func rating(value string) int {
+	elo, err := strconv.Atoi(value)
+	if err != nil {
+		return 0
+	}
+	return elo
+}
+
+// buildEntries turns the move counts into book entries. Positions reached in
+// fewer than minGames recorded games and moves that never scored are dropped.
+// Weights of a position are scaled down together when they overflow 16 bits.
+func buildEntries(stats map[uint64]map[uint16]*counts, minGames int) []book.RawEntry {
+	var entries []book.RawEntry
+	for key, moves := range stats {
+		games, top := 0, 0
+		for _, c := range moves {
+			games += c.games()
+			top = max(top, c.weight())
+		}
+		if games < minGames {
+			continue
+		}
+		for move, c := range moves {
+			if weight := scale(c.weight(), top); weight > 0 {
+				entries = append(entries, book.RawEntry{Key: key, Move: move, Weight: weight})
+			}
+		}
+	}
+	return entries
+}
+
+// scale maps weight into 16 bits relative to the top weight of its position.
+func scale(weight, top int) uint16 {
+	const maxWeight = 1<<16 - 1
+	if top > maxWeight {
+		weight = weight * maxWeight / top
+	}
+	return uint16(weight)
+}
+
+// mergeBooks combines books by adding the weights of entries with the same
+// position and move.
+func mergeBooks(paths []string) ([]book.RawEntry, error) {
+	type entryKey struct {
+		key  uint64
+		move uint16
+	}
+	weights := make(map[entryKey]int)
+	tops := make(map[uint64]int)
+	for _, path := range paths {
+		entries, err := book.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			k := entryKey{e.Key, e.Move}
+			weights[k] += int(e.Weight)
+			tops[e.Key] = max(tops[e.Key], weights[k])
+		}
+		log.Printf("%s: %d entries", path, len(entries))
+	}
+
+	entries := make([]book.RawEntry, 0, len(weights))
+	for k, weight := range weights {
+		if w := scale(weight, tops[k.key]); w > 0 {
+			entries = append(entries, book.RawEntry{Key: k.key, Move: k.move, Weight: w})
+		}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no entries in %v", paths)
+	}
+	return entries, nil
+}
@@ -0,0 +1,229 @@
+// Command bookcheck searches every book move of a PolyGlot book and flags the
+// moves that lose more than a margin against the engine's best move.
+//
+//	bookcheck -book book.bin -depth 12 -margin 80 -o pruned.bin
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"runtime"
+	"sync"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+	"github.com/likeawizard/tofiks/pkg/book"
+	"github.com/likeawizard/tofiks/pkg/search"
+)
+
+// position is a book position with its legal book moves.
+type position struct {
+	board   *board.Board
+	entries []book.Entry
+	key     uint64
+	ply     int
+}
+
+// verdict is the search result for one book move. Unscored moves ran out of
+// nodes before a search iteration completed.
+type verdict struct {
+	move      board.Move
+	bestMove  board.Move
+	score     int
+	bestScore int
+	unscored  bool
+}
+
+func (v verdict) loss() int {
+	return v.bestScore - v.score
+}
+
+// mateScore scores a book move that mates.
+const mateScore = int(search.CheckmateScore)
+
+// searcher runs fixed-depth or fixed-node searches on one engine.
+type searcher struct {
+	e     *search.Engine
+	depth int
+	nodes int
+	last  search.Info
+}
+
+func newSearcher(depth, nodes, hash int) *searcher {
+	s := &searcher{e: search.NewEngine(), depth: depth, nodes: nodes}
+	s.e.TTable = search.NewTTable(hash)
+	s.e.NodeLimit = nodes
+	s.e.OnInfo = func(info search.Info) { s.last = info }
+	return s
+}
+
+// score searches b and returns the score for the side to move and the best
+// move. ok is false if no search iteration completed within the node limit.
+func (s *searcher) score(b *board.Board, depth int) (score int, best board.Move, ok bool) {
+	s.e.Board = b
+	s.e.Ply = 0
+	s.e.TC = &search.TimeControl{}
+	s.last = search.Info{}
+	best, _, _ = s.e.IDSearch(max(depth, 1), true)
+	return int(s.last.Score), best, s.last.Depth > 0
+}
+
+// check searches the position and each of its book moves one ply deeper.
+func (s *searcher) check(p position) []verdict {
+	depth := s.depth
+	if s.nodes > 0 {
+		depth = 100
+	}
+	bestScore, bestMove, searched := s.score(p.board.Copy(), depth)
+
+	verdicts := make([]verdict, 0, len(p.entries))
+	for _, entry := range p.entries {
+		if !searched {
+			verdicts = append(verdicts, verdict{move: entry.Move, unscored: true})
+			continue
+		}
+		child := p.board.Copy()
+		child.MakeMove(entry.Move)
+		score, ok := 0, true
+		switch {
+		case len(child.LegalMoves()) > 0:
+			score, _, ok = s.score(child, depth-1)
+			score = -score
+		case child.InCheck:
+			score = mateScore
+		}
+		verdicts = append(verdicts, verdict{move: entry.Move, bestMove: bestMove, score: score, bestScore: max(bestScore, score), unscored: !ok})
+	}
+	return verdicts
+}
+
+func main() {
+	var (
+		bookPath string
+		outPath  string
+		depth    int
+		nodes    int
+		margin   int
+		maxPly   int
+		workers  int
+		hash     int
+	)
+	flag.StringVar(&bookPath, "book", "book.bin", "PolyGlot book to check")
+	flag.StringVar(&outPath, "o", "", "Write the book without the flagged moves to this file")
+	flag.IntVar(&depth, "depth", 10, "Search depth per position")
+	flag.IntVar(&nodes, "nodes", 0, "Search nodes per position instead of a fixed depth")
+	flag.IntVar(&margin, "margin", 100, "Flag moves losing more centipawns than this against the best move")
+	flag.IntVar(&maxPly, "maxply", 30, "Do not walk the book deeper than this ply")
+	flag.IntVar(&workers, "c", runtime.NumCPU(), "Engines searching in parallel")
+	flag.IntVar(&hash, "hash", 16, "Transposition table size in MB per engine")
+	flag.Parse()
+
+	bk := book.New()
+	if err := bk.Open(bookPath); err != nil {
+		log.Fatalf("Failed to open book: %v", err)
+	}
+	positions, err := walk(bk, maxPly)
+	bk.Close()
+	if err != nil {
+		log.Fatalf("Failed to read book: %v", err)
+	}
+	moves := 0
+	for _, p := range positions {
+		moves += len(p.entries)
+	}
+	log.Printf("Checking %d positions with %d book moves (%d engines)", len(positions), moves, workers)
+
+	results := make([][]verdict, len(positions))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range max(workers, 1) {
+		wg.Go(func() {
+			s := newSearcher(depth, nodes, hash)
+			for i := range jobs {
+				results[i] = s.check(positions[i])
+			}
+		})
+	}
+	for i := range positions {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	type entryKey struct {
+		key  uint64
+		move uint16
+	}
+	flagged := make(map[entryKey]bool)
+	unscored := 0
+	for i, p := range positions {
+		for _, v := range results[i] {
+			if v.unscored {
+				unscored++
+				fmt.Printf("ply %2d  %-8s not scored: no search iteration completed  fen %s\n", p.ply, p.board.SAN(v.move), p.board.ExportFEN())
+				continue
+			}
+			if v.loss() <= margin {
+				continue
+			}
+			flagged[entryKey{p.key, book.EncodeMove(v.move)}] = true
+			fmt.Printf("ply %2d  %-8s loses %5d  score %6d  best %s %d  fen %s\n",
+				p.ply, p.board.SAN(v.move), v.loss(), v.score, p.board.SAN(v.bestMove), v.bestScore, p.board.ExportFEN())
+		}
+	}
+	log.Printf("%d of %d book moves lose more than %d cp", len(flagged), moves, margin)
+	if unscored > 0 {
+		log.Printf("%d book moves were not scored and are kept: raise -nodes to search them", unscored)
+	}
+
+	if outPath == "" {
+		return
+	}
+	entries, err := book.ReadFile(bookPath)
+	if err != nil {
+		log.Fatalf("Failed to read book: %v", err)
+	}
+	kept := entries[:0]
+	for _, e := range entries {
+		if !flagged[entryKey{e.Key, e.Move}] {
+			kept = append(kept, e)
+		}
+	}
+	if err := book.WriteFile(outPath, kept); err != nil {
+		log.Fatalf("Failed to write book: %v", err)
+	}
+	log.Printf("Wrote %d of %d entries to %s", len(kept), len(entries), outPath)
+}
+
+// walk collects every book position reachable from the start position, each
+// once, breadth first.
+func walk(bk *book.Book, maxPly int) ([]position, error) {
+	var positions []position
+	seen := make(map[uint64]bool)
+	queue := []position{{board: board.NewBoard(board.StartPos)}}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		p.key = book.PolyZobrist(p.board)
+		if seen[p.key] || p.ply > maxPly {
+			continue
+		}
+		seen[p.key] = true
+
+		entries, err := bk.Entries(p.board)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		p.entries = entries
+		positions = append(positions, p)
+		for _, entry := range entries {
+			child := p.board.Copy()
+			child.MakeMove(entry.Move)
+			queue = append(queue, position{board: child, ply: p.ply + 1})
+		}
+	}
+	return positions, nil
+}
@@ -0,0 +1,78 @@
+// Command dataconv converts training data between the text ("result fen"),
+// EPD and packed marlinformat and bulletformat formats. Formats follow from the
+// file extensions (.txt, .epd, .marlin, .bullet) unless given. The packed
+// formats store a search score with every position, so only scored data can be
+// converted to them.
+//
+//	dataconv selfplay.txt selfplay.bullet
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"github.com/likeawizard/tofiks/pkg/texel"
+)
+
+func main() {
+	var from, to string
+	var limit int
+	flag.StringVar(&from, "from", "", "Input format: text, epd, marlin or bullet (default: by extension)")
+	flag.StringVar(&to, "to", "", "Output format: text, epd, marlin or bullet (default: by extension)")
+	flag.IntVar(&limit, "lim", 0, "Max positions to convert (0 = all)")
+	flag.Parse()
+	if flag.NArg() != 2 {
+		log.Fatal("usage: dataconv [-from format] [-to format] in out")
+	}
+	inPath, outPath := flag.Arg(0), flag.Arg(1)
+
+	inFormat, outFormat := texel.FormatFromPath(inPath), texel.FormatFromPath(outPath)
+	var err error
+	if from != "" {
+		if inFormat, err = texel.ParseFormat(from); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if to != "" {
+		if outFormat, err = texel.ParseFormat(to); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	in, err := os.Open(inPath)
+	if err != nil {
+		log.Fatalf("Failed to open input: %v", err)
+	}
+	defer in.Close()
+	out, err := os.Create(outPath)
+	if err != nil {
+		log.Fatalf("Failed to create output: %v", err)
+	}
+	defer out.Close()
+
+	r := texel.NewDataReader(in, inFormat)
+	w := texel.NewDataWriter(out, outFormat)
+	count := 0
+	for limit <= 0 || count < limit {
+		s, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatalf("Failed to read %s: %v", inPath, err)
+		}
+		if err := w.Write(s); errors.Is(err, texel.ErrNoScore) {
+			log.Fatalf("Position %d of %s has no search score to store in %v: convert unscored data to text or epd", count+1, inPath, outFormat)
+		} else if err != nil {
+			log.Fatalf("Failed to write %s: %v", outPath, err)
+		}
+		count++
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatalf("Failed to write %s: %v", outPath, err)
+	}
+	log.Printf("Converted %d positions from %v to %v", count, inFormat, outFormat)
+}
@@ -0,0 +1,99 @@
+// Command elostat reports Elo, LOS, draw ratio and SPRT state of an engine
+// from the games of PGN files, for example a fastchess games.pgn. Games
+// played in pairs from the same opening with colors reversed are scored with
+// pentanomial statistics.
+//
+//	elostat -engine tofiks-dev -elo0 0 -elo1 5 games.pgn
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/likeawizard/tofiks/pkg/pgn"
+	"github.com/likeawizard/tofiks/pkg/stats"
+)
+
+func main() {
+	var (
+		engine    string
+		bookPlies int
+		top       int
+		sprt      stats.SPRT
+	)
+	flag.StringVar(&engine, "engine", "", "Engine to report on (default: white of the first game)")
+	flag.IntVar(&bookPlies, "bookplies", 0, "Opening moves in the movetext that belong to the opening")
+	flag.IntVar(&top, "top", 10, "Openings listed, worst score for the engine first (0 = all)")
+	flag.Float64Var(&sprt.Elo0, "elo0", 0, "SPRT H0 Elo")
+	flag.Float64Var(&sprt.Elo1, "elo1", 5, "SPRT H1 Elo")
+	flag.Float64Var(&sprt.Alpha, "alpha", 0.05, "SPRT false positive rate")
+	flag.Float64Var(&sprt.Beta, "beta", 0.05, "SPRT false negative rate")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		log.Fatal("No PGN files")
+	}
+
+	var games []stats.Game
+	for _, path := range flag.Args() {
+		parsed, err := pgn.ParseFile(path)
+		if err != nil {
+			log.Fatalf("Failed to parse %s: %v", path, err)
+		}
+		games = append(games, stats.FromPGN(parsed, bookPlies)...)
+	}
+	if len(games) == 0 {
+		log.Fatal("No finished games")
+	}
+	if engine == "" {
+		engine = games[0].White
+	}
+
+	r := stats.Analyze(games, engine)
+	if r.WDL.Games() == 0 {
+		log.Fatalf("No games of %s", engine)
+	}
+	report(&r, sprt, top)
+}
+
+func report(r *stats.Report, sprt stats.SPRT, top int) {
+	fmt.Printf("%s vs %s: %d games, %d pairs, %d unpaired\n",
+		r.Engine, strings.Join(r.Opponents, ", "), r.WDL.Games(), r.Penta.Pairs(), r.Unpaired)
+	fmt.Printf("Score   : +%d =%d -%d  %.1f%%  draws %.1f%%\n",
+		r.WDL.Wins, r.WDL.Draws, r.WDL.Losses, 100*r.WDL.Score(), 100*r.WDL.DrawRatio())
+
+	elo, margin := r.WDL.Elo()
+	los, llr := r.WDL.LOS(), r.WDL.LLR(sprt.Elo0, sprt.Elo1)
+	model := "trinomial"
+	if r.Paired() {
+		elo, margin = r.Penta.Elo()
+		los, llr = r.Penta.LOS(), r.Penta.LLR(sprt.Elo0, sprt.Elo1)
+		model = "pentanomial"
+		fmt.Printf("Penta   : %v\n", r.Penta)
+	}
+	lower, upper := sprt.Bounds()
+	fmt.Printf("Elo     : %.1f +/- %.1f (%s)\n", elo, margin, model)
+	fmt.Printf("LOS     : %.1f%%\n", 100*los)
+	fmt.Printf("LLR     : %.2f (%.2f, %.2f) [%g, %g] %v\n", llr, lower, upper, sprt.Elo0, sprt.Elo1, sprt.Test(llr))
+
+	fmt.Println("Terminations:")
+	for _, b := range r.Terminations {
+		fmt.Printf("  %-16s %6d  +%d =%d -%d\n", b.Name, b.WDL.Games(), b.WDL.Wins, b.WDL.Draws, b.WDL.Losses)
+	}
+	fmt.Printf("Time losses: %s %d, opponents %d\n", r.Engine, r.TimeLosses, r.OpponentTimeLosses)
+
+	openings := slices.Clone(r.Openings)
+	slices.SortStableFunc(openings, func(a, b stats.Breakdown) int {
+		return cmp.Compare(a.WDL.Score(), b.WDL.Score())
+	})
+	if top > 0 && len(openings) > top {
+		openings = openings[:top]
+	}
+	fmt.Printf("Openings (%d of %d, worst first):\n", len(openings), len(r.Openings))
+	for _, b := range openings {
+		fmt.Printf("  %5.1f%%  +%d =%d -%d  %s\n", 100*b.WDL.Score(), b.WDL.Wins, b.WDL.Draws, b.WDL.Losses, b.Name)
+	}
+}
@@ -0,0 +1,328 @@
+// Command match plays paired games between two engines and reports the Elo
+// difference, optionally stopping early on an SPRT verdict. Engines are UCI
+// binaries or in-process engines, "internal" or "internal:config.yml".
+//
+//	match -engine1 ./tofiks-dev -engine2 ./tofiks-prod -openings UHO.epd -tc 10+0.1 -concurrency 4 -sprt -elo0 0 -elo1 5 -pgnout games.pgn
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/likeawizard/tofiks/pkg/match"
+	"github.com/likeawizard/tofiks/pkg/pgn"
+	"github.com/likeawizard/tofiks/pkg/search"
+	"github.com/likeawizard/tofiks/pkg/stats"
+	"github.com/likeawizard/tofiks/pkg/uci"
+)
+
+// engineSpec describes how to start one of the two engines.
+type engineSpec struct {
+	name    string
+	path    string
+	options []match.Option
+	hash    int
+}
+
+// internalPrefix selects an in-process engine, optionally with a config file.
+const internalPrefix = "internal"
+
+// internal reports whether the engine runs in-process, and its config file.
+func (s *engineSpec) internal() (config string, ok bool) {
+	config, ok = strings.CutPrefix(s.path, internalPrefix)
+	return strings.TrimPrefix(config, ":"), ok
+}
+
+func (s *engineSpec) newPlayer() (match.Player, error) {
+	if config, ok := s.internal(); ok {
+		return match.NewEnginePlayer(s.name, func(e *search.Engine) error {
+			e.TTable = search.NewTTable(s.hash)
+			if config != "" {
+				return uci.LoadConfig(e, config)
+			}
+			return nil
+		})
+	}
+	options := append([]match.Option{{Name: "Hash", Value: strconv.Itoa(s.hash)}}, s.options...)
+	return match.NewUCIPlayer(s.name, s.path, options)
+}
+
+// shareEngines prepares for in-process engines playing side by side. They
+// share the eval weights of the process, so their configs may not set them:
+// two configs could not both have their way. A single internal engine playing
+// several games at a time has its config applied here once, and each of its
+// engines may then repeat the settings.
+func shareEngines(specs [2]engineSpec, workers int) error {
+	var configs []string
+	for i := range specs {
+		if config, ok := specs[i].internal(); ok {
+			configs = append(configs, config)
+		}
+	}
+	if len(configs)*workers <= 1 {
+		return nil
+	}
+	if len(configs) == 1 && configs[0] != "" {
+		if err := uci.LoadConfig(search.NewEngine(), configs[0]); err != nil {
+			return err
+		}
+	}
+	uci.SetShared(true)
+	return nil
+}
+
+// defaultName names an engine after its binary or config file.
+func (s *engineSpec) defaultName() string {
+	if config, ok := strings.CutPrefix(s.path, internalPrefix+":"); ok {
+		return strings.TrimSuffix(filepath.Base(config), filepath.Ext(config))
+	}
+	if s.path == internalPrefix {
+		return "tofiks"
+	}
+	return filepath.Base(s.path)
+}
+
+// parseOptions reads "Name=Value,Name=Value" UCI options.
+func parseOptions(list string) ([]match.Option, error) {
+	var options []match.Option
+	for pair := range strings.SplitSeq(list, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid option %q, want Name=Value", pair)
+		}
+		options = append(options, match.Option{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+	}
+	return options, nil
+}
+
+// parseTC reads a time control in seconds as "base+inc".
+func parseTC(s string) (base, inc time.Duration, err error) {
+	baseStr, incStr, _ := strings.Cut(s, "+")
+	b, err := strconv.ParseFloat(baseStr, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid time control %q", s)
+	}
+	var i float64
+	if incStr != "" {
+		if i, err = strconv.ParseFloat(incStr, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid time control %q", s)
+		}
+	}
+	return time.Duration(b * float64(time.Second)), time.Duration(i * float64(time.Second)), nil
+}
+
+// pair is the outcome of the two games of one opening.
+type pair struct {
+	games [2]*match.Game
+	round int
+}
+
+// points returns what the first engine scored in the pair.
+func (p *pair) points(first string) float64 {
+	points := 0.0
+	for _, g := range p.games {
+		if g.White == first {
+			points += g.Score()
+		} else {
+			points += 1 - g.Score()
+		}
+	}
+	return points
+}
+
+func main() {
+	var (
+		specs      [2]engineSpec
+		optionStrs [2]string
+		openings   string
+		order      string
+		seed       uint64
+		rounds     int
+		workers    int
+		tcStr      string
+		margin     time.Duration
+		pgnOut     string
+		useSPRT    bool
+		sprt       stats.SPRT
+		tc         match.TimeControl
+		adj        match.Adjudication
+	)
+	for i := range specs {
+		n := strconv.Itoa(i + 1)
+		flag.StringVar(&specs[i].path, "engine"+n, "", "Engine "+n+": a UCI binary, or internal[:config.yml] for an in-process engine")
+		flag.StringVar(&specs[i].name, "name"+n, "", "Name of engine "+n)
+		flag.StringVar(&optionStrs[i], "options"+n, "", "UCI options of engine "+n+" as Name=Value,Name=Value")
+	}
+	hash := flag.Int("hash", 16, "Transposition table size in MB per engine")
+	flag.StringVar(&openings, "openings", "", "Opening file, EPD or PGN (default: the start position)")
+	flag.StringVar(&order, "order", "random", "Opening order: random or sequential")
+	flag.Uint64Var(&seed, "seed", 0, "Seed of the random opening order (0 = time based)")
+	flag.IntVar(&rounds, "rounds", 100, "Game pairs to play, each opening with both colors")
+	flag.IntVar(&workers, "concurrency", 1, "Games played in parallel")
+	flag.StringVar(&tcStr, "tc", "10+0.1", "Time control in seconds as base+increment")
+	flag.IntVar(&tc.Nodes, "nodes", 0, "Node limit per move instead of a time control")
+	flag.DurationVar(&margin, "timemargin", 50*time.Millisecond, "Time an engine may overrun its clock")
+	flag.IntVar(&adj.DrawMoveNumber, "draw-movenumber", 40, "Adjudicate draws from this move on")
+	flag.IntVar(&adj.DrawMoveCount, "draw-movecount", 8, "Moves per engine within -draw-score to adjudicate a draw (0 = off)")
+	flag.IntVar(&adj.DrawScore, "draw-score", 10, "Score in cp within which a game counts as drawn")
+	flag.IntVar(&adj.ResignMoveCount, "resign-movecount", 3, "Moves an engine must score below -resign-score to resign (0 = off)")
+	flag.IntVar(&adj.ResignScore, "resign-score", 600, "Score in cp at which an engine resigns")
+	flag.StringVar(&pgnOut, "pgnout", "", "Write the games to this PGN file")
+	flag.BoolVar(&useSPRT, "sprt", false, "Stop on an SPRT verdict")
+	flag.Float64Var(&sprt.Elo0, "elo0", 0, "SPRT H0 Elo")
+	flag.Float64Var(&sprt.Elo1, "elo1", 5, "SPRT H1 Elo")
+	flag.Float64Var(&sprt.Alpha, "alpha", 0.05, "SPRT false positive rate")
+	flag.Float64Var(&sprt.Beta, "beta", 0.05, "SPRT false negative rate")
+	flag.Parse()
+
+	for i := range specs {
+		if specs[i].path == "" {
+			log.Fatalf("-engine%d is required", i+1)
+		}
+		options, err := parseOptions(optionStrs[i])
+		if err != nil {
+			log.Fatal(err)
+		}
+		specs[i].options, specs[i].hash = options, *hash
+		if specs[i].name == "" {
+			specs[i].name = specs[i].defaultName()
+		}
+	}
+	if specs[0].name == specs[1].name {
+		specs[0].name += "-1"
+		specs[1].name += "-2"
+	}
+	if tc.Nodes == 0 {
+		var err error
+		if tc.Base, tc.Inc, err = parseTC(tcStr); err != nil {
+			log.Fatal(err)
+		}
+	}
+	tc.Margin = margin
+
+	book := []match.Opening{{FEN: "startpos"}}
+	if openings != "" {
+		var err error
+		if book, err = match.LoadOpenings(openings); err != nil {
+			log.Fatalf("Failed to load openings: %v", err)
+		}
+	}
+	if order == "random" {
+		if seed == 0 {
+			seed = uint64(time.Now().UnixNano())
+		}
+		rng := rand.New(rand.NewPCG(seed, seed))
+		rng.Shuffle(len(book), func(i, j int) { book[i], book[j] = book[j], book[i] })
+	}
+
+	var pgnFile *os.File
+	if pgnOut != "" {
+		var err error
+		if pgnFile, err = os.OpenFile(pgnOut, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+			log.Fatalf("Failed to open PGN output: %v", err)
+		}
+		defer pgnFile.Close()
+	}
+
+	if err := shareEngines(specs, max(workers, 1)); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("%s vs %s: %d pairs, %d openings, tc %s, concurrency %d", specs[0].name, specs[1].name, rounds, len(book), tc, workers)
+
+	var stop atomic.Bool
+	jobs := make(chan int)
+	results := make(chan pair)
+	var wg sync.WaitGroup
+	for range max(workers, 1) {
+		wg.Go(func() {
+			var players [2]match.Player
+			for i := range specs {
+				p, err := specs[i].newPlayer()
+				if err != nil {
+					log.Fatalf("Failed to start %s: %v", specs[i].name, err)
+				}
+				defer p.Close()
+				players[i] = p
+			}
+			for round := range jobs {
+				if stop.Load() {
+					continue
+				}
+				opening := book[round%len(book)]
+				var pr pair
+				pr.round = round
+				for i := range pr.games {
+					white, black := players[i], players[1-i]
+					g, err := match.Play(white, black, opening, tc, adj)
+					if err != nil {
+						log.Fatalf("Round %d: %v", round+1, err)
+					}
+					pr.games[i] = g
+				}
+				results <- pr
+			}
+		})
+	}
+	go func() {
+		for round := 0; round < rounds && !stop.Load(); round++ {
+			jobs <- round
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	var penta stats.Pentanomial
+	var wins, draws, losses int
+	date := time.Now().Format("2006.01.02")
+	lower, upper := sprt.Bounds()
+	for pr := range results {
+		for i, g := range pr.games {
+			switch s := g.Score(); {
+			case s == 0.5:
+				draws++
+			case (s == 1) == (g.White == specs[0].name):
+				wins++
+			default:
+				losses++
+			}
+			if pgnFile != nil {
+				pg := g.PGN()
+				pg.Headers["Event"] = "match"
+				pg.Headers["Date"] = date
+				pg.Headers["Round"] = fmt.Sprintf("%d.%d", pr.round+1, i+1)
+				pg.Headers["TimeControl"] = tc.String()
+				if err := pgn.Write(pgnFile, pg); err != nil {
+					log.Fatalf("Failed to write PGN: %v", err)
+				}
+			}
+		}
+		penta.Add(pr.points(specs[0].name))
+
+		elo, errMargin := penta.Elo()
+		line := fmt.Sprintf("Games %d: +%d =%d -%d  score %.1f%%  Elo %.1f +/- %.1f  penta %v",
+			wins+draws+losses, wins, draws, losses, 100*penta.Score(), elo, errMargin, penta)
+		if useSPRT {
+			llr := penta.LLR(sprt.Elo0, sprt.Elo1)
+			line += fmt.Sprintf("  LLR %.2f (%.2f, %.2f) [%g, %g]", llr, lower, upper, sprt.Elo0, sprt.Elo1)
+			if v := sprt.Test(llr); v != stats.Continue && !stop.Load() {
+				stop.Store(true)
+				line += "  " + v.String()
+			}
+		}
+		fmt.Println(line)
+	}
+}
@@ -1,13 +1,16 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 
+	"github.com/likeawizard/tofiks/pkg/eval"
 	"github.com/likeawizard/tofiks/pkg/texel"
 )
 
@@ -19,21 +22,70 @@ func main() {
 		workers      int
 		earlyStopStr string
 		earlyStop    float64
+		lambda       float64
+		paramsIn     string
+		paramsOut    string
+		validFrac    float64
+		patience     int
+		checkpoint   string
+		every        int
+		resume       bool
+		freeze       string
+		clamp        string
+		monotone     string
+		l1, l2       float64
 	)
-	flag.StringVar(&file, "f", "texel_data.txt", "Training data file (format: result fen)")
+	flag.StringVar(&file, "f", "texel_data.txt", "Training data file: \"result fen\" lines, EPD with c9 result, or packed .marlin/.bullet positions")
 	flag.IntVar(&limit, "lim", 0, "Max positions to load (0 = all)")
 	flag.IntVar(&iterations, "i", 200, "Max optimization iterations")
 	flag.IntVar(&workers, "c", runtime.NumCPU(), "Worker goroutines for cache building")
-	flag.StringVar(&earlyStopStr, "es", "1e-7", "Early stop threshold for dMSE (0 = disabled)")
+	flag.StringVar(&earlyStopStr, "es", "1e-7", "Early stop threshold for dMSE without a validation set (0 = disabled)")
+	flag.Float64Var(&lambda, "lambda", 1, "Weight of the game result against the search score in the target (1 = result only)")
+	flag.StringVar(&paramsIn, "params", "", "Start from the weights of this parameter file instead of the built-in ones")
+	flag.StringVar(&paramsOut, "o", "tuned_params.json", "Write the tuned weights to this parameter file")
+	flag.Float64Var(&validFrac, "valid", 0.05, "Fraction of the cache, picked by a hash of the entry index, held out to measure validation MSE (0 = none)")
+	flag.IntVar(&patience, "patience", 10, "Stop after this many iterations without a better validation MSE, keeping the best weights (0 = disabled)")
+	flag.StringVar(&checkpoint, "checkpoint", "", "Checkpoint file (default: the cache path with a .ckpt extension)")
+	flag.IntVar(&every, "every", 10, "Save a checkpoint every this many iterations (0 = only at the end)")
+	flag.BoolVar(&resume, "resume", false, "Resume the run saved in the checkpoint file")
+	flag.StringVar(&freeze, "freeze", "", "Comma-separated parameters or groups to keep at their initial values, e.g. PST,Material")
+	flag.StringVar(&clamp, "clamp", "", "Comma-separated bounds of parameters, e.g. PieceWeights=50:1500,Tempo=0:40")
+	flag.StringVar(&monotone, "monotone", "", "Comma-separated array parameters kept non-decreasing, or non-increasing with a - prefix, e.g. PassedPawnBonus")
+	flag.Float64Var(&l1, "l1", 0, "Weight of the L1 penalty on the distance from the initial values")
+	flag.Float64Var(&l2, "l2", 0, "Weight of the L2 penalty on the distance from the initial values")
 	flag.Parse()
+	if lambda < 0 || lambda > 1 {
+		log.Fatalf("Lambda %v is not within [0, 1]", lambda)
+	}
+	if validFrac < 0 || validFrac >= 1 {
+		log.Fatalf("Validation fraction %v is not within [0, 1)", validFrac)
+	}
 
 	if _, err := fmt.Sscanf(earlyStopStr, "%e", &earlyStop); err != nil {
 		log.Fatalf("Invalid early stop threshold %q: %v", earlyStopStr, err)
 	}
 
+	constraints := texel.NewConstraints()
+	constraints.L1, constraints.L2 = l1, l2
+	if paramsIn != "" {
+		if err := loadParams(paramsIn, constraints); err != nil {
+			log.Fatalf("Failed to load parameters: %v", err)
+		}
+	}
+	if err := parseConstraints(constraints, freeze, clamp, monotone); err != nil {
+		log.Fatalf("Invalid constraints: %v", err)
+	}
+
 	// Build binary cache from text data (or reuse existing).
-	cachePath := strings.TrimSuffix(file, ".txt") + ".bin"
-	if _, err := os.Stat(cachePath); err != nil {
+	cachePath := strings.TrimSuffix(file, filepath.Ext(file)) + ".bin"
+	if checkpoint == "" {
+		checkpoint = strings.TrimSuffix(cachePath, ".bin") + ".ckpt"
+	}
+	err := texel.CheckCache(cachePath)
+	if errors.Is(err, texel.ErrCacheLayout) {
+		log.Printf("Rebuilding %s: %v", cachePath, err)
+	}
+	if err != nil {
 		log.Printf("Building cache from %s (limit=%d, workers=%d)", file, limit, workers)
 		n, err := texel.BuildCache(file, cachePath, limit, workers)
 		if err != nil {
@@ -49,16 +101,137 @@ func main() {
 		log.Fatalf("Failed to read cache: %v", err)
 	}
 	log.Printf("Cache contains %d positions", n)
+	if lambda < 1 {
+		scored := 0
+		if err := texel.ForEachEntry(cachePath, func(e *texel.Entry) {
+			if e.HasScore {
+				scored++
+			}
+		}); err != nil {
+			log.Fatalf("Failed to read cache: %v", err)
+		}
+		log.Printf("%d positions have a search score", scored)
+		if scored == 0 {
+			log.Printf("Lambda %v has no effect without search scores: rebuild the cache from scored data", lambda)
+		}
+	}
 
-	weights := texel.InitialParams()
+	var st *texel.TuneState
+	if resume {
+		if st, err = texel.LoadCheckpoint(checkpoint); err != nil {
+			log.Fatalf("Failed to load checkpoint: %v", err)
+		}
+		if st.Train+st.Valid != n {
+			log.Fatalf("Checkpoint %s was saved for a cache of %d positions", checkpoint, st.Train+st.Valid)
+		}
+		// The run keeps its constraints; flags may only repeat them.
+		if !constraints.Equal(texel.NewConstraints()) && !constraints.Equal(st.Constraints) {
+			log.Fatalf("Checkpoint %s was saved with other constraints: resume without -freeze, -clamp, -monotone, -l1, -l2 and parameter file constraints, or with the same ones", checkpoint)
+		}
+		log.Printf("Resuming %s: K=%.6f, lambda=%.2f", checkpoint, st.K, st.Lambda)
+	} else {
+		weights := texel.InitialParams()
+		split := texel.Split{Valid: validFrac}
 
-	log.Println("Optimizing K...")
-	K := texel.StreamOptimizeK(cachePath, n, &weights)
-	log.Printf("Optimal K: %.6f", K)
+		log.Println("Optimizing K...")
+		K := texel.StreamOptimizeK(cachePath, split, &weights)
+		log.Printf("Optimal K: %.6f", K)
+		st = texel.NewTuneState(&weights, K, lambda, n, split, constraints)
+	}
 
-	cfg := texel.DefaultAdamConfig()
-	texel.StreamOptimize(cachePath, n, &weights, K, iterations, cfg, earlyStop)
+	cfg := texel.StreamConfig{
+		Adam:            texel.DefaultAdamConfig(),
+		Iterations:      iterations,
+		EarlyStop:       earlyStop,
+		Patience:        patience,
+		Checkpoint:      checkpoint,
+		CheckpointEvery: every,
+	}
+	if err := texel.StreamOptimize(cachePath, st, cfg); err != nil {
+		log.Fatalf("Failed to save checkpoint: %v", err)
+	}
+	weights := st.Result()
 
 	log.Println("=== Tuned Parameters ===")
-	texel.PrintParams(&weights)
+	texel.PrintParams(weights)
+
+	if err := writeParams(paramsOut, weights); err != nil {
+		log.Fatalf("Failed to write parameters: %v", err)
+	}
+	log.Printf("Wrote tuned parameters to %s", paramsOut)
+}
+
+func writeParams(path string, weights *[texel.NumParams]float64) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := texel.ExportParams(weights).Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// loadParams applies a parameter file and adds the constraints it sets.
+func loadParams(path string, c *texel.Constraints) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	f, err := eval.ReadParamFile(file)
+	if err == nil {
+		err = f.Apply()
+	}
+	if err == nil {
+		err = c.Load(f)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// parseConstraints adds the constraints of the -freeze, -clamp and -monotone
+// flags.
+func parseConstraints(c *texel.Constraints, freeze, clamp, monotone string) error {
+	for name := range strings.SplitSeq(freeze, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if err := c.Freeze(name); err != nil {
+			return err
+		}
+	}
+	for bound := range strings.SplitSeq(clamp, ",") {
+		if bound = strings.TrimSpace(bound); bound == "" {
+			continue
+		}
+		var lo, hi int
+		name, rng, ok := strings.Cut(bound, "=")
+		if ok {
+			_, err := fmt.Sscanf(rng, "%d:%d", &lo, &hi)
+			ok = err == nil
+		}
+		if !ok {
+			return fmt.Errorf("bound %q is not name=min:max", bound)
+		}
+		if err := c.Clamp(name, lo, hi); err != nil {
+			return err
+		}
+	}
+	for name := range strings.SplitSeq(monotone, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		dir := 1
+		if rest, ok := strings.CutPrefix(name, "-"); ok {
+			name, dir = rest, -1
+		}
+		if err := c.Monotone(name, dir); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"runtime"
+
+	"github.com/likeawizard/tofiks/pkg/datagen"
+	"github.com/likeawizard/tofiks/pkg/match"
+	"github.com/likeawizard/tofiks/pkg/search"
+	"github.com/likeawizard/tofiks/pkg/texel"
+	"github.com/likeawizard/tofiks/pkg/uci"
+)
+
+// runDatagen generates training data from self-play:
+// tofiks datagen -games 10000 -nodes 5000 -o data/selfplay.
+func runDatagen(args []string) {
+	fs := flag.NewFlagSet("datagen", flag.ExitOnError)
+	var cfg datagen.Config
+	fs.IntVar(&cfg.Games, "games", 1000, "Games to play")
+	fs.IntVar(&cfg.Workers, "concurrency", runtime.NumCPU(), "Games played in parallel")
+	fs.IntVar(&cfg.Nodes, "nodes", 5000, "Nodes per search")
+	fs.IntVar(&cfg.Hash, "hash", 16, "Transposition table size per game in MB")
+	fs.Uint64Var(&cfg.Seed, "seed", 1, "Seed of the random openings")
+	fs.IntVar(&cfg.RandomPlies, "randomplies", 8, "Random moves played after the opening")
+	book := fs.String("book", "", "Pick openings from this EPD, FEN or PGN file instead of the start position")
+	config := fs.String("config", "", "Load engine options from this YAML file")
+	packed := fs.String("format", "marlin", "Packed output format: marlin or bullet")
+	out := fs.String("o", "datagen", "Output path: writes .epd, .marlin or .bullet, and .progress files")
+	adj := &cfg.Adjudication
+	fs.IntVar(&adj.DrawMoveNumber, "draw-movenumber", 40, "Adjudicate draws from this move on")
+	fs.IntVar(&adj.DrawMoveCount, "draw-movecount", 8, "Moves per side within -draw-score to adjudicate a draw (0 = off)")
+	fs.IntVar(&adj.DrawScore, "draw-score", 10, "Score in cp within which a game counts as drawn")
+	fs.IntVar(&adj.ResignMoveCount, "resign-movecount", 3, "Moves a side must score below -resign-score to resign (0 = off)")
+	fs.IntVar(&adj.ResignScore, "resign-score", 1000, "Score in cp at which a side resigns")
+	_ = fs.Parse(args)
+	if fs.NArg() != 0 {
+		log.Fatal("usage: tofiks datagen [-games N] [-nodes N] [-concurrency N] [-book openings.epd] [-o out]")
+	}
+
+	var err error
+	if cfg.Packed, err = texel.ParseFormat(*packed); err != nil {
+		log.Fatal(err)
+	}
+	if *book != "" {
+		openings, err := match.LoadOpenings(*book)
+		if err != nil {
+			log.Fatalf("Failed to load openings: %v", err)
+		}
+		cfg.Openings = openings
+	}
+	if *config != "" {
+		cfg.Configure = func(e *search.Engine) error {
+			return uci.LoadConfig(e, *config)
+		}
+	}
+
+	stats, err := datagen.Run(cfg, *out, func(s datagen.Stats) {
+		if s.Games%100 != 0 && s.Games != cfg.Games {
+			return
+		}
+		log.Printf("Games %d/%d  positions %d  +%d =%d -%d  %.0f pos/s",
+			s.Games, cfg.Games, s.Positions, s.Wins, s.Draws, s.Losses, s.Rate)
+	})
+	if err != nil {
+		log.Fatalf("Data generation failed: %v", err)
+	}
+	log.Printf("Wrote %d positions from %d games to %s%s and %s%s", stats.Positions, stats.Games, *out, texel.FormatEPD.Ext(), *out, cfg.Packed.Ext())
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/likeawizard/tofiks/pkg/epd"
+	"github.com/likeawizard/tofiks/pkg/search"
+)
+
+// runEPD searches an EPD test suite and reports the solved positions:
+// tofiks epd -movetime 1000 -json results.json suite.epd.
+func runEPD(args []string) {
+	fs := flag.NewFlagSet("epd", flag.ExitOnError)
+	var limits epd.Limits
+	fs.IntVar(&limits.Depth, "depth", 0, "Search depth per position")
+	fs.IntVar(&limits.Nodes, "nodes", 0, "Search nodes per position")
+	fs.IntVar(&limits.MoveTime, "movetime", 0, "Search time per position in milliseconds")
+	hash := fs.Int("hash", 64, "Transposition table size in MB")
+	jsonPath := fs.String("json", "", "Write the results as JSON to this file, - for stdout")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		log.Fatal("usage: tofiks epd [-depth N] [-nodes N] [-movetime ms] [-json out.json] suite.epd")
+	}
+	if limits == (epd.Limits{}) {
+		limits.MoveTime = 1000
+	}
+
+	positions, err := epd.ParseFile(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Failed to read suite: %v", err)
+	}
+
+	e := search.NewEngine()
+	e.TTable = search.NewTTable(*hash)
+	// Keep stdout clean for the JSON report.
+	out := os.Stdout
+	if *jsonPath == "-" {
+		out = os.Stderr
+	}
+	e.Out = out
+
+	n := 0
+	summary := epd.Run(e, positions, limits, func(r epd.Result) {
+		n++
+		status := "FAIL"
+		if r.Solved {
+			status = "ok"
+		}
+		expect := strings.Join(r.Best, " ")
+		if len(r.Avoid) > 0 {
+			expect += " !" + strings.Join(r.Avoid, " !")
+		}
+		if r.MateIn > 0 {
+			expect += fmt.Sprintf(" #%d", r.MateIn)
+		}
+		fmt.Fprintf(out, "%4d %-4s %-24s %-7s expected %-16s %-9s depth %2d  solved %5d ms",
+			n, status, r.ID, r.Move, strings.TrimSpace(expect), r.Score, r.Depth, r.SolvedMs)
+		if r.MaxPoints > 0 {
+			fmt.Fprintf(out, "  points %d/%d", r.Points, r.MaxPoints)
+		}
+		fmt.Fprintln(out)
+	})
+	fmt.Fprintf(out, "Solved %d of %d", summary.Solved, summary.Total)
+	if summary.MaxPoints > 0 {
+		fmt.Fprintf(out, ", points %d of %d", summary.Points, summary.MaxPoints)
+	}
+	fmt.Fprintln(out)
+
+	if *jsonPath == "" {
+		return
+	}
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to encode results: %v", err)
+	}
+	data = append(data, '\n')
+	if *jsonPath == "-" {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = os.WriteFile(*jsonPath, data, 0o644)
+	}
+	if err != nil {
+		log.Fatalf("Failed to write results: %v", err)
+	}
+}
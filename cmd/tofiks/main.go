@@ -3,14 +3,14 @@ package main
 import (
 	"bufio"
 	"flag"
-	"fmt"
 	"log"
 	"os"
 	"runtime/pprof"
-	"time"
+	"strings"
 
 	"github.com/likeawizard/tofiks/pkg/search"
 	"github.com/likeawizard/tofiks/pkg/uci"
+	"github.com/likeawizard/tofiks/pkg/xboard"
 	"github.com/pkg/profile"
 	_ "go.uber.org/automaxprocs"
 )
@@ -18,8 +18,18 @@ import (
 func main() {
 	enableProfile := false
 	enableMemProf := false
+	listenAddr := ""
+	maxConns := 0
+	logPath := ""
+	configPath := ""
+	paramsPath := ""
 	flag.BoolVar(&enableProfile, "pgo", false, "Enable CPU profiling")
 	flag.BoolVar(&enableMemProf, "memprof", false, "Enable memory profiling")
+	flag.StringVar(&listenAddr, "listen", "", "Serve UCI over TCP on this address instead of stdin")
+	flag.IntVar(&maxConns, "maxconn", 0, "Maximum concurrent TCP connections (0 = unlimited)")
+	flag.StringVar(&logPath, "log", "", "Log all UCI traffic to this file")
+	flag.StringVar(&configPath, "config", "", "Load engine options from this YAML file")
+	flag.StringVar(&paramsPath, "params", "", "Load evaluation weights from this parameter file")
 	flag.Parse()
 	if enableProfile {
 		f, err := os.Create("cmd/tofiks/default.pgo")
@@ -40,60 +50,74 @@ func main() {
 		defer profile.Start(profile.MemProfile, profile.ProfilePath("cmd/tofiks/")).Stop()
 	}
 	if len(os.Args) > 1 && os.Args[1] == "bench" {
-		runBench()
+		if err := uci.RunBench(os.Stdout, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "serve" {
+		runServe(flag.Args()[1:])
+		return
+	}
+	if flag.Arg(0) == "epd" {
+		runEPD(flag.Args()[1:])
+		return
+	}
+	if flag.Arg(0) == "datagen" {
+		runDatagen(flag.Args()[1:])
 		return
 	}
 
-	e := search.NewEngine()
-
-	input := bufio.NewScanner(os.Stdin)
-	for {
-		input.Scan()
-		cmd := uci.ParseUCI(input.Text())
-		if cmd != nil {
-			switch cmd.(type) {
-			case *uci.Quit:
-				return
-			case *uci.Stop:
-				go cmd.Exec(e)
-			default:
-				e.WG.Wait()
-				e.WG.Add(1)
-				go cmd.Exec(e)
+	// Options are layered: engine defaults, then the config file, then
+	// -params, then setoption.
+	configure := func(e *search.Engine) error {
+		if configPath != "" {
+			if err := uci.LoadConfig(e, configPath); err != nil {
+				return err
 			}
 		}
+		if paramsPath != "" {
+			return uci.ApplyOption(e, "EvalParams", paramsPath)
+		}
+		return nil
+	}
+	setup := func(e *search.Engine) error {
+		if err := configure(e); err != nil {
+			return err
+		}
+		if logPath != "" {
+			return uci.SetDebugLog(e, logPath)
+		}
+		return nil
 	}
-}
-
-var benchPositions = []string{
-	"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
-	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
-	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
-	"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
-	"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
-	"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
-	"r1bqk2r/pppp1ppp/2n2n2/2b1p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4",
-	"r1bqkbnr/pppppppp/2n5/8/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 1 2",
-}
-
-func runBench() {
-	const benchDepth = 10
-	totalNodes := 0
-	start := time.Now()
 
-	for _, fen := range benchPositions {
-		e := search.NewEngine()
-		if err := e.Board.ImportFEN(fen); err != nil {
-			log.Fatalf("bad bench FEN: %v", err)
+	if listenAddr != "" {
+		// The eval weights are shared by all connections. Set them once before
+		// serving; the setup of each connection then repeats them, which
+		// leaves them alone.
+		if err := configure(search.NewEngine()); err != nil {
+			log.Fatalf("Error configuring engine: %v", err)
 		}
-		e.IDSearch(benchDepth, true)
-		totalNodes += e.Stats.TotalNodes()
+		log.Fatal(uci.Listen(listenAddr, maxConns, setup))
+	}
+
+	e := search.NewEngine()
+	if err := setup(e); err != nil {
+		log.Printf("Error configuring engine: %v", err)
+		return
 	}
 
-	elapsed := time.Since(start)
-	nps := int64(totalNodes)
-	if elapsed.Milliseconds() > 0 {
-		nps = (1000 * int64(totalNodes)) / elapsed.Milliseconds()
+	// The protocol is picked by the first command: xboard or UCI.
+	input := bufio.NewScanner(os.Stdin)
+	if !input.Scan() {
+		return
+	}
+	if strings.TrimSpace(input.Text()) == xboard.CmdXboard {
+		xboard.Run(input, os.Stdout, e)
+		return
+	}
+	if uci.Handle(e, input.Text()) {
+		return
 	}
-	fmt.Printf("%d nodes %d nps\n", totalNodes, nps)
+	uci.Run(input, e)
 }
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"runtime"
+
+	"github.com/likeawizard/tofiks/pkg/server"
+)
+
+// runServe starts the HTTP/JSON analysis server: tofiks serve -addr :8080.
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "Listen address")
+	engines := fs.Int("engines", runtime.GOMAXPROCS(0), "Number of engines serving requests concurrently")
+	hash := fs.Int("hash", 64, "Transposition table size in MB per engine")
+	_ = fs.Parse(args)
+
+	log.Printf("serving on %s with %d engines", *addr, *engines)
+	log.Fatal(server.ListenAndServe(*addr, server.NewPool(max(*engines, 1), *hash)))
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+	"github.com/likeawizard/tofiks/pkg/pgn"
+	"github.com/likeawizard/tofiks/pkg/search"
+	"github.com/likeawizard/tofiks/pkg/texel"
+	"github.com/likeawizard/tofiks/pkg/wdl"
+)
+
+// position is a FEN with the game result from white's point of view.
+type position struct {
+	fen    string
+	result float64
+}
+
+func main() {
+	var (
+		dataPath   string
+		pgnPath    string
+		skipPlies  int
+		limit      int
+		depth      int
+		workers    int
+		iterations int
+		lr         float64
+		maxScore   int
+	)
+	flag.StringVar(&dataPath, "f", "", "Texel data file (lines of \"result fen\" or EPD with c9 result)")
+	flag.StringVar(&pgnPath, "pgn", "", "PGN file with moves in UCI notation, used instead of -f")
+	flag.IntVar(&skipPlies, "skip", 8, "Skip first N plies of each PGN game (opening book moves)")
+	flag.IntVar(&limit, "lim", 0, "Max positions to use (0 = all)")
+	flag.IntVar(&depth, "depth", 0, "Search depth used to score positions (0 = static eval)")
+	flag.IntVar(&workers, "c", runtime.NumCPU(), "Worker goroutines for scoring positions")
+	flag.IntVar(&iterations, "i", 2000, "Optimization iterations")
+	flag.Float64Var(&lr, "lr", 2, "Adam learning rate")
+	flag.IntVar(&maxScore, "max", 2000, "Ignore positions scored beyond this many centipawns")
+	flag.Parse()
+
+	var positions []position
+	var err error
+	switch {
+	case pgnPath != "":
+		positions, err = loadPGN(pgnPath, skipPlies)
+	case dataPath != "":
+		positions, err = loadTexel(dataPath)
+	default:
+		log.Fatal("Either -f or -pgn is required")
+	}
+	if err != nil {
+		log.Fatalf("Failed to load positions: %v", err)
+	}
+	if limit > 0 && len(positions) > limit {
+		positions = positions[:limit]
+	}
+	log.Printf("Scoring %d positions (depth %d, workers %d)", len(positions), depth, workers)
+
+	samples := score(positions, depth, workers, maxScore)
+	log.Printf("Fitting %d samples", len(samples))
+
+	start := wdl.Default
+	fitted := wdl.Fit(samples, start, iterations, lr)
+	log.Printf("Log loss: %.6f -> %.6f", start.LogLoss(samples), fitted.LogLoss(samples))
+
+	fmt.Println(fitted)
+	fmt.Println()
+	fmt.Println("material  win@0  draw@0  50%-win score")
+	for material := 18; material <= 78; material += 10 {
+		w, d, _ := fitted.WDL(0, material)
+		cp := 0
+		for cp < 5000 && fitted.Normalize(cp, material) < 100 {
+			cp++
+		}
+		fmt.Printf("%8d  %5d  %6d  %13d\n", material, w, d, cp)
+	}
+}
+
+// score evaluates every position and returns the samples from white's point of view.
+func score(positions []position, depth, workers, maxScore int) []wdl.Sample {
+	samples := make([]wdl.Sample, len(positions))
+	valid := make([]bool, len(positions))
+
+	var wg sync.WaitGroup
+	chunk := (len(positions) + workers - 1) / max(workers, 1)
+	for from := 0; from < len(positions); from += chunk {
+		to := min(from+chunk, len(positions))
+		wg.Go(func() {
+			e := search.NewEngine()
+			e.TTable = search.NewTTable(16)
+			var last int16
+			e.OnInfo = func(info search.Info) { last = info.Score }
+
+			for i := from; i < to; i++ {
+				b := &board.Board{}
+				if err := b.ImportFEN(positions[i].fen); err != nil {
+					continue
+				}
+				var cp int
+				if depth > 0 {
+					e.Board = b
+					e.Ply = 0
+					e.TC = &search.TimeControl{}
+					e.IDSearch(depth, true)
+					cp = int(last)
+					if b.Side == board.Black {
+						cp = -cp
+					}
+				} else {
+					cp = e.Eval.GetEvaluation(b)
+				}
+				if cp > maxScore || cp < -maxScore {
+					continue
+				}
+				samples[i] = wdl.Sample{Score: cp, Material: wdl.Material(b), Result: positions[i].result}
+				valid[i] = true
+			}
+		})
+	}
+	wg.Wait()
+
+	out := samples[:0]
+	for i, s := range samples {
+		if valid[i] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func loadTexel(path string) ([]position, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var positions []position
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fen, r, ok := texel.ParseDataLine(s.Text())
+		if !ok || (r != 0 && r != 0.5 && r != 1) {
+			continue
+		}
+		positions = append(positions, position{fen: fen, result: r})
+	}
+	return positions, s.Err()
+}
+
+func loadPGN(path string, skipPlies int) ([]position, error) {
+	games, err := pgn.ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var positions []position
+	for _, g := range games {
+		var result float64
+		switch g.Result {
+		case "1-0":
+			result = 1
+		case "0-1":
+			result = 0
+		case "1/2-1/2":
+			result = 0.5
+		default:
+			continue
+		}
+
+		b := board.NewBoard(g.StartFEN)
+		for i, move := range g.Moves {
+			if i >= skipPlies && !b.InCheck {
+				positions = append(positions, position{fen: b.ExportFEN(), result: result})
+			}
+			if _, ok := b.MoveUCI(move); !ok {
+				break
+			}
+		}
+	}
+	return positions, nil
+}
@@ -2,7 +2,7 @@ module github.com/likeawizard/tofiks
 
 go 1.26
 
-require gopkg.in/yaml.v3 v3.0.1 // indirect
+require gopkg.in/yaml.v3 v3.0.1
 
 require (
 	4d63.com/gocheckcompilerdirectives v1.3.0 // indirect
@@ -163,6 +163,17 @@ func (b *Board) IsAttacked(sq int, side int8, occ BBoard) bool {
 		GetRookAttacks(sq, occ)&(b.Pieces[side^1][Rooks]|b.Pieces[side^1][Queens]) != 0
 }
 
+// Checkers returns a bitboard of the enemy pieces giving check to the side to move.
+func (b *Board) Checkers() BBoard {
+	side := b.Side
+	sq := b.Pieces[side][Kings].LS1B()
+	occ := b.Occupancy[Both]
+	return PawnAttacks[side][sq]&b.Pieces[side^1][Pawns] |
+		KnightAttacks[sq]&b.Pieces[side^1][Knights] |
+		GetBishopAttacks(sq, occ)&(b.Pieces[side^1][Bishops]|b.Pieces[side^1][Queens]) |
+		GetRookAttacks(sq, occ)&(b.Pieces[side^1][Rooks]|b.Pieces[side^1][Queens])
+}
+
 // Determine if the king for the given side is in check.
 func (b *Board) IsChecked(side int8) bool {
 	return b.IsAttacked(b.Pieces[side][Kings].LS1B(), side, b.Occupancy[Both])
@@ -1,5 +1,7 @@
 package board
 
+import "strings"
+
 func NewBoard(position string) *Board {
 	b := Board{}
 	switch position {
@@ -15,6 +17,8 @@ func NewBoard(position string) *Board {
 	return &b
 }
 
+// Flip mirrors the position vertically and swaps colors, so the resulting
+// position is the same game seen from the other side of the board.
 func (b *Board) Flip() {
 	b.Side ^= 1
 	for pieces := Pawns; pieces <= Kings; pieces++ {
@@ -22,7 +26,42 @@ func (b *Board) Flip() {
 	}
 	b.Occupancy[White], b.Occupancy[Black] = b.Occupancy[Black].Flip(), b.Occupancy[White].Flip()
 	b.Occupancy[Both] = b.Occupancy[White] | b.Occupancy[Black]
+
+	b.CastlingRights = (b.CastlingRights&(WOO|WOOO))<<2 | (b.CastlingRights&(BOO|BOOO))>>2
+	if b.EnPassantTarget != -1 {
+		b.EnPassantTarget ^= 56
+	}
+
+	b.Hash = b.SeedHash()
 	b.PawnHash = b.SeedPawnHash()
+	b.InCheck = b.IsChecked(b.Side)
+}
+
+// String renders the board as an ASCII diagram from white's point of view.
+func (b *Board) String() string {
+	const separator = "  +---+---+---+---+---+---+---+---+\n"
+	symbols := [2][6]byte{{'P', 'B', 'N', 'R', 'Q', 'K'}, {'p', 'b', 'n', 'r', 'q', 'k'}}
+	var s strings.Builder
+	s.WriteString(separator)
+	for rank := range 8 {
+		s.WriteString("  |")
+		for file := range 8 {
+			sq := rank*8 + file
+			symbol := byte(' ')
+			for color := White; color <= Black; color++ {
+				for piece := Pawns; piece <= Kings; piece++ {
+					if b.Pieces[color][piece]&SquareBitboards[sq] != 0 {
+						symbol = symbols[color][piece]
+					}
+				}
+			}
+			s.WriteString(" " + string(symbol) + " |")
+		}
+		s.WriteString(" " + string(byte('8'-rank)) + "\n")
+		s.WriteString(separator)
+	}
+	s.WriteString("    a   b   c   d   e   f   g   h\n")
+	return s.String()
 }
 
 func (b *Board) Copy() *Board {
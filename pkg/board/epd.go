@@ -0,0 +1,167 @@
+package board
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// epdOrder is the order ExportEPD writes the common opcodes in. Others follow
+// sorted by name.
+var epdOrder = []string{
+	"id", "bm", "am", "dm", "ce", "acd", "pv",
+	"c0", "c1", "c2", "c3", "c4", "c5", "c6", "c7", "c8", "c9",
+}
+
+// ParseEPD reads an EPD record: the four position fields of a FEN and
+// "opcode operand...;" operations. Halfmove and fullmove counters may follow
+// the position fields as in a FEN, or be given by hmvc and fmvn. Operands are
+// returned without quotes and escapes.
+func ParseEPD(epd string) (*Board, map[string][]string, error) {
+	fields := strings.Fields(epd)
+	if len(fields) < 4 {
+		return nil, nil, fmt.Errorf("EPD must contain four fields - '%s'", epd)
+	}
+	half, full := "0", "1"
+	skip := 4
+	if rest := fields[4:]; len(rest) >= 2 && isCounter(rest[0]) && isCounter(rest[1]) {
+		half, full = rest[0], rest[1]
+		skip = 6
+	}
+
+	// Operations may contain quoted spaces, so they are parsed from the raw
+	// record rather than the fields.
+	raw := epd
+	for range skip {
+		raw = strings.TrimLeft(raw, " \t")
+		raw = raw[strings.IndexAny(raw+" ", " \t"):]
+	}
+	ops, err := parseOps(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	if v := ops["hmvc"]; len(v) == 1 {
+		half = v[0]
+	}
+	if v := ops["fmvn"]; len(v) == 1 {
+		full = v[0]
+	}
+
+	b := &Board{}
+	if err := b.ImportFEN(strings.Join(append(fields[:4:4], half, full), " ")); err != nil {
+		return nil, nil, err
+	}
+	return b, ops, nil
+}
+
+func isCounter(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+// parseOps reads "opcode operand...;" operations. Quoted operands may contain
+// spaces, semicolons and backslash escapes.
+func parseOps(s string) (map[string][]string, error) {
+	ops := make(map[string][]string)
+	var opcode string
+	var operands []string
+	flush := func() {
+		if opcode != "" {
+			ops[opcode] = operands
+		}
+		opcode, operands = "", nil
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == ';':
+			flush()
+			i++
+		case c == '"':
+			if opcode == "" {
+				return nil, fmt.Errorf("operand without opcode in '%s'", s)
+			}
+			var sb strings.Builder
+			i++
+			for i < len(s) && s[i] != '"' {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				sb.WriteByte(s[i])
+				i++
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("unterminated string in '%s'", s)
+			}
+			i++
+			operands = append(operands, sb.String())
+		default:
+			j := i
+			for j < len(s) && s[j] != ' ' && s[j] != '\t' && s[j] != ';' {
+				j++
+			}
+			if opcode == "" {
+				opcode = s[i:j]
+			} else {
+				operands = append(operands, s[i:j])
+			}
+			i = j
+		}
+	}
+	flush()
+	return ops, nil
+}
+
+// ExportEPD writes the position as an EPD record with the given operations.
+// Counters that differ from a fresh game are written as hmvc and fmvn, which
+// take the place of any given in ops.
+func (b *Board) ExportEPD(ops map[string][]string) string {
+	var sb strings.Builder
+	fen := strings.Fields(b.ExportFEN())
+	sb.WriteString(strings.Join(fen[:4], " "))
+
+	writeOp := func(opcode string, operands []string) {
+		sb.WriteString(" " + opcode)
+		for _, operand := range operands {
+			sb.WriteString(" " + quoteOperand(opcode, operand))
+		}
+		sb.WriteByte(';')
+	}
+	for _, opcode := range epdOrder {
+		if operands, ok := ops[opcode]; ok {
+			writeOp(opcode, operands)
+		}
+	}
+	var rest []string
+	for opcode := range ops {
+		if !slices.Contains(epdOrder, opcode) && opcode != "hmvc" && opcode != "fmvn" {
+			rest = append(rest, opcode)
+		}
+	}
+	slices.Sort(rest)
+	for _, opcode := range rest {
+		writeOp(opcode, ops[opcode])
+	}
+	if b.HalfMoveCounter != 0 {
+		writeOp("hmvc", []string{strconv.Itoa(int(b.HalfMoveCounter))})
+	}
+	if b.FullMoveCounter > 1 {
+		writeOp("fmvn", []string{strconv.Itoa(int(b.FullMoveCounter))})
+	}
+	return sb.String()
+}
+
+// quoteOperand quotes string operands - id and the comments c0 to c9 - and
+// any operand that would not read back as a single token.
+func quoteOperand(opcode, operand string) string {
+	isString := opcode == "id" || len(opcode) == 2 && opcode[0] == 'c' && opcode[1] >= '0' && opcode[1] <= '9'
+	if !isString && operand != "" && !strings.ContainsAny(operand, " \t;\"\\") {
+		return operand
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(operand) + `"`
+}
@@ -306,3 +306,17 @@ func (b *Board) PseudoCaptureAndQueenPromoGen() []Move {
 
 	return moves
 }
+
+// LegalMoves returns all strictly legal moves in the position.
+func (b *Board) LegalMoves() []Move {
+	all := b.PseudoMoveGen()
+	legal := make([]Move, 0, len(all))
+	for _, move := range all {
+		umove := b.MakeMove(move)
+		if !b.IsChecked(b.Side ^ 1) {
+			legal = append(legal, move)
+		}
+		umove()
+	}
+	return legal
+}
@@ -0,0 +1,224 @@
+package board
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// PackedSize is the size of a packed position.
+const PackedSize = 32
+
+// PackedFormat is the layout of a packed position, as read by NNUE trainers.
+// Both layouts are little endian, count squares from a1 and start with
+//
+//	uint64    occupancy
+//	[16]byte  a nibble per occupied square in occupancy order, low nibble
+//	          first: the piece type (pawn, knight, bishop, rook, queen, king)
+//	          with bit 3 set for black
+//
+// Marlinformat continues with
+//
+//	uint8     side to move in bit 7, en passant square or 64 below
+//	uint8     halfmove clock
+//	uint16    fullmove number
+//	int16     score from white's point of view
+//	uint8     WDL from white's point of view
+//	uint8     unused
+//
+// and gives rooks with castling rights piece type 6. Bulletformat stores the
+// position from the point of view of the side to move, flipping the board
+// when black is to move so that "black" above is the opponent, and continues
+// with
+//
+//	int16     score for the side to move
+//	uint8     WDL for the side to move
+//	uint8     king square of the side to move
+//	uint8     king square of the opponent, flipped vertically
+//	[3]byte   unused
+type PackedFormat int
+
+const (
+	Marlinformat PackedFormat = iota
+	Bulletformat
+)
+
+const (
+	packedUnmovedRook = 6
+	packedNoSquare    = 64
+)
+
+// packedPieces maps board piece types to packed piece types and back.
+var packedPieces = [6]uint8{
+	Pawns:   0,
+	Knights: 1,
+	Bishops: 2,
+	Rooks:   3,
+	Queens:  4,
+	Kings:   5,
+}
+
+// castlingRooks are the squares of the rooks of each castling right.
+var castlingRooks = []struct {
+	right CastlingRights
+	color int
+	sq    int
+}{
+	{WOO, White, H1},
+	{WOOO, White, A1},
+	{BOO, Black, H8},
+	{BOOO, Black, A8},
+}
+
+// PackedPosition is a training position: a position with its search score in
+// centipawns and the result of its game as WDL, 0 for a loss, 1 for a draw
+// and 2 for a win, both from white's point of view.
+type PackedPosition struct {
+	Board *Board
+	Score int16
+	WDL   uint8
+}
+
+// WDLFromResult converts a game result for white, from 0 to 1, to a WDL.
+func WDLFromResult(result float64) uint8 {
+	switch {
+	case result > 0.5:
+		return 2
+	case result < 0.5:
+		return 0
+	}
+	return 1
+}
+
+// Result returns the game result for white: 1, 0.5 or 0.
+func (p *PackedPosition) Result() float64 {
+	return float64(p.WDL) / 2
+}
+
+// Marshal packs the position in format f.
+func (p *PackedPosition) Marshal(f PackedFormat) [PackedSize]byte {
+	b, score, wdl := p.Board, p.Score, p.WDL
+	if f == Bulletformat && b.Side == Black {
+		b = b.Copy()
+		b.Flip()
+		score, wdl = -score, 2-wdl
+	}
+
+	// Squares are counted from a1 in packed positions and from a8 on the
+	// board: sq^56 converts either way.
+	var codes [64]uint8
+	var occupancy BBoard
+	for color := White; color <= Black; color++ {
+		for piece := Pawns; piece <= Kings; piece++ {
+			pieces := b.Pieces[color][piece]
+			for pieces > 0 {
+				sq := pieces.PopLS1B() ^ 56
+				occupancy |= 1 << sq
+				codes[sq] = packedPieces[piece] | uint8(color)<<3
+			}
+		}
+	}
+	if f == Marlinformat {
+		for _, r := range castlingRooks {
+			if b.CastlingRights&r.right != 0 {
+				codes[r.sq^56] = codes[r.sq^56]&8 | packedUnmovedRook
+			}
+		}
+	}
+
+	var rec [PackedSize]byte
+	binary.LittleEndian.PutUint64(rec[0:], uint64(occupancy))
+	for n, occ := 0, occupancy; occ > 0; n++ {
+		rec[8+n/2] |= codes[occ.PopLS1B()] << (4 * (n % 2))
+	}
+	switch f {
+	case Marlinformat:
+		ep := uint8(packedNoSquare)
+		if b.EnPassantTarget != -1 {
+			ep = uint8(b.EnPassantTarget ^ 56)
+		}
+		rec[24] = ep | uint8(b.Side)<<7
+		rec[25] = b.HalfMoveCounter
+		binary.LittleEndian.PutUint16(rec[26:], uint16(b.FullMoveCounter))
+		binary.LittleEndian.PutUint16(rec[28:], uint16(score))
+		rec[30] = wdl
+	case Bulletformat:
+		binary.LittleEndian.PutUint16(rec[24:], uint16(score))
+		rec[26] = wdl
+		rec[27] = uint8(b.Pieces[White][Kings].LS1B() ^ 56)
+		rec[28] = uint8(b.Pieces[Black][Kings].LS1B())
+	}
+	return rec
+}
+
+// UnmarshalPacked unpacks a position in format f. Bulletformat positions come
+// back with the side to move as white, without castling rights, en passant
+// square and move counters.
+func UnmarshalPacked(f PackedFormat, data []byte) (PackedPosition, error) {
+	if len(data) < PackedSize {
+		return PackedPosition{}, fmt.Errorf("packed position of %d bytes", len(data))
+	}
+	occupancy := BBoard(binary.LittleEndian.Uint64(data))
+	if occupancy.Count() > 32 {
+		return PackedPosition{}, fmt.Errorf("packed position with %d pieces", occupancy.Count())
+	}
+
+	b := &Board{EnPassantTarget: -1, FullMoveCounter: 1}
+	for n, occ := 0, occupancy; occ > 0; n++ {
+		sq := occ.PopLS1B() ^ 56
+		code := data[8+n/2] >> (4 * (n % 2)) & 0xf
+		color, piece := int(code>>3), code&7
+		if piece == packedUnmovedRook && f == Marlinformat {
+			piece = packedPieces[Rooks]
+			right := CastlingRights(0)
+			for _, r := range castlingRooks {
+				if r.sq == sq && r.color == color {
+					right = r.right
+				}
+			}
+			if right == 0 {
+				return PackedPosition{}, fmt.Errorf("castling rook on %v", Square(sq))
+			}
+			b.CastlingRights |= right
+		}
+		if piece > packedPieces[Kings] {
+			return PackedPosition{}, fmt.Errorf("invalid piece %d on %v", code, Square(sq))
+		}
+		b.Pieces[color][packedPieces[piece]] |= 1 << sq
+	}
+	if b.Pieces[White][Kings].Count() != 1 || b.Pieces[Black][Kings].Count() != 1 {
+		return PackedPosition{}, fmt.Errorf("packed position without one king per side")
+	}
+
+	p := PackedPosition{Board: b}
+	switch f {
+	case Marlinformat:
+		b.Side = int8(data[24] >> 7)
+		switch ep := data[24] & 0x7f; {
+		case ep < 64:
+			b.EnPassantTarget = Square(ep ^ 56)
+		case ep != packedNoSquare:
+			return PackedPosition{}, fmt.Errorf("invalid en passant square %d", ep)
+		}
+		b.HalfMoveCounter = data[25]
+		b.FullMoveCounter = uint8(binary.LittleEndian.Uint16(data[26:]))
+		p.Score = int16(binary.LittleEndian.Uint16(data[28:]))
+		p.WDL = data[30]
+	case Bulletformat:
+		p.Score = int16(binary.LittleEndian.Uint16(data[24:]))
+		p.WDL = data[26]
+	}
+	if p.WDL > 2 {
+		return PackedPosition{}, fmt.Errorf("invalid WDL %d", p.WDL)
+	}
+
+	for color := White; color <= Black; color++ {
+		for piece := Pawns; piece <= Kings; piece++ {
+			b.Occupancy[color] |= b.Pieces[color][piece]
+		}
+	}
+	b.Occupancy[Both] = b.Occupancy[White] | b.Occupancy[Black]
+	b.Hash = b.SeedHash()
+	b.PawnHash = b.SeedPawnHash()
+	b.InCheck = b.IsChecked(b.Side)
+	return p, nil
+}
@@ -2,6 +2,7 @@ package board
 
 import (
 	"fmt"
+	"io"
 	"time"
 )
 
@@ -16,6 +17,11 @@ func Perft(fen string, depth int) (int64, time.Duration) {
 	return leafs, time.Since(start)
 }
 
+// Perft counts the leaf nodes of the legal move tree from the current position.
+func (b *Board) Perft(depth int) int64 {
+	return traverse(b, depth)
+}
+
 func traverse(b *Board, depth int) int64 {
 	num := int64(0)
 	if depth == 0 {
@@ -46,7 +52,8 @@ func traverse(b *Board, depth int) int64 {
 	return num
 }
 
-func (b *Board) PerftDebug(depth int) {
+// PerftDebug writes the leaf node count per root move, the total and the speed to w.
+func (b *Board) PerftDebug(w io.Writer, depth int) {
 	all := b.PseudoMoveGen()
 	start := time.Now()
 	nodesSearched := int64(0)
@@ -58,8 +65,8 @@ func (b *Board) PerftDebug(depth int) {
 		}
 		nodes := traverse(b, depth-1)
 		nodesSearched += nodes
-		fmt.Printf("%s: %d\n", move, nodes)
+		fmt.Fprintf(w, "%s: %d\n", move, nodes)
 		umove()
 	}
-	fmt.Printf("\nNodes searched: %d (nps %d)\n", nodesSearched, (1000000*nodesSearched)/time.Since(start).Microseconds())
+	fmt.Fprintf(w, "\nNodes searched: %d (nps %d)\n", nodesSearched, (1000000*nodesSearched)/time.Since(start).Microseconds())
 }
@@ -0,0 +1,171 @@
+package board
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sanPieces maps piece constants to their SAN letters. Pawns have none.
+var sanPieces = [6]string{"", "B", "N", "R", "Q", "K"}
+
+// sanPiece returns the piece constant for a SAN piece letter.
+func sanPiece(c byte) (uint8, bool) {
+	for piece, letter := range sanPieces {
+		if letter != "" && letter[0] == c {
+			return uint8(piece), true
+		}
+	}
+	return 0, false
+}
+
+// ParseSAN returns the legal move written in standard algebraic notation.
+// Check and annotation suffixes are ignored and castling may be written with
+// O or 0.
+func (b *Board) ParseSAN(san string) (Move, error) {
+	s := strings.TrimRight(san, "+#!?")
+	legal := b.LegalMoves()
+
+	switch s {
+	case "O-O", "0-0", "O-O-O", "0-0-0":
+		file := Square(6)
+		if len(s) == 5 {
+			file = 2
+		}
+		for _, m := range legal {
+			if m.IsCastling() && m.To()%8 == file {
+				return m, nil
+			}
+		}
+		return 0, fmt.Errorf("illegal move %q", san)
+	}
+
+	s = strings.NewReplacer("x", "", "-", "", "=", "", ":", "").Replace(s)
+	if len(s) < 2 {
+		return 0, fmt.Errorf("invalid move %q", san)
+	}
+
+	piece := uint8(Pawns)
+	if p, ok := sanPiece(s[0]); ok {
+		piece = p
+		s = s[1:]
+	}
+	var promo uint8
+	if piece == Pawns && len(s) > 2 {
+		if p, ok := sanPiece(strings.ToUpper(s[len(s)-1:])[0]); ok && p != Kings {
+			promo = p
+			s = s[:len(s)-1]
+		}
+	}
+	if len(s) < 2 || len(s) > 4 {
+		return 0, fmt.Errorf("invalid move %q", san)
+	}
+	target := s[len(s)-2:]
+	if target[0] < 'a' || target[0] > 'h' || target[1] < '1' || target[1] > '8' {
+		return 0, fmt.Errorf("invalid move %q", san)
+	}
+	to := SquareFromString(target)
+	disambiguation := s[:len(s)-2]
+
+	var found Move
+	matches := 0
+	for _, m := range legal {
+		if m.Piece() != piece || m.To() != to || m.Promotion() != promo {
+			continue
+		}
+		from := m.From().String()
+		if !matchesDisambiguation(from, disambiguation) {
+			continue
+		}
+		found = m
+		matches++
+	}
+	switch matches {
+	case 0:
+		return 0, fmt.Errorf("illegal move %q", san)
+	case 1:
+		return found, nil
+	default:
+		return 0, fmt.Errorf("ambiguous move %q", san)
+	}
+}
+
+// matchesDisambiguation reports whether the from square fits the file and
+// rank hints given in a SAN move.
+func matchesDisambiguation(from, hint string) bool {
+	for i := 0; i < len(hint); i++ {
+		c := hint[i]
+		switch {
+		case c >= 'a' && c <= 'h':
+			if from[0] != c {
+				return false
+			}
+		case c >= '1' && c <= '8':
+			if from[1] != c {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// SAN writes a legal move in standard algebraic notation, with a + or #
+// suffix when it gives check or mate.
+func (b *Board) SAN(m Move) string {
+	var sb strings.Builder
+	from, to := m.From().String(), m.To().String()
+
+	switch {
+	case m.IsCastling():
+		if m.To()%8 == 6 {
+			sb.WriteString("O-O")
+		} else {
+			sb.WriteString("O-O-O")
+		}
+	case m.Piece() == Pawns:
+		if m.IsCapture() {
+			sb.WriteString(from[:1] + "x")
+		}
+		sb.WriteString(to)
+		if promo := m.Promotion(); promo != 0 {
+			sb.WriteString("=" + sanPieces[promo])
+		}
+	default:
+		sb.WriteString(sanPieces[m.Piece()])
+		sameFile, sameRank, ambiguous := false, false, false
+		for _, other := range b.LegalMoves() {
+			if other == m || other.Piece() != m.Piece() || other.To() != m.To() {
+				continue
+			}
+			ambiguous = true
+			otherFrom := other.From().String()
+			sameFile = sameFile || otherFrom[0] == from[0]
+			sameRank = sameRank || otherFrom[1] == from[1]
+		}
+		switch {
+		case !ambiguous:
+		case !sameFile:
+			sb.WriteByte(from[0])
+		case !sameRank:
+			sb.WriteByte(from[1])
+		default:
+			sb.WriteString(from)
+		}
+		if m.IsCapture() {
+			sb.WriteByte('x')
+		}
+		sb.WriteString(to)
+	}
+
+	umove := b.MakeMove(m)
+	if b.IsChecked(b.Side) {
+		if len(b.LegalMoves()) == 0 {
+			sb.WriteByte('#')
+		} else {
+			sb.WriteByte('+')
+		}
+	}
+	umove()
+	return sb.String()
+}
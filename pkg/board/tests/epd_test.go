@@ -0,0 +1,70 @@
+package board
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+)
+
+func TestParseEPD(t *testing.T) {
+	tests := []struct {
+		epd string
+		fen string
+		ops map[string][]string
+	}{
+		{
+			epd: `rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 bm e5 c5; id "start \"1\"";`,
+			fen: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+			ops: map[string][]string{"bm": {"e5", "c5"}, "id": {`start "1"`}},
+		},
+		{
+			epd: `6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 4 31 ce 32765; acd 12; pv Rd8#; c0 "a; b";`,
+			fen: "6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 4 31",
+			ops: map[string][]string{"ce": {"32765"}, "acd": {"12"}, "pv": {"Rd8#"}, "c0": {"a; b"}},
+		},
+		{
+			epd: "6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - hmvc 4; fmvn 31;",
+			fen: "6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 4 31",
+			ops: map[string][]string{"hmvc": {"4"}, "fmvn": {"31"}},
+		},
+		{
+			epd: "6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - -",
+			fen: "6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 0 1",
+			ops: map[string][]string{},
+		},
+	}
+	for _, tt := range tests {
+		b, ops, err := board.ParseEPD(tt.epd)
+		assert.NoError(t, err, tt.epd)
+		assert.Equal(t, tt.fen, b.ExportFEN())
+		assert.Equal(t, tt.ops, ops)
+	}
+
+	for _, epd := range []string{"8/8/8 w", `6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - id "open;`, `6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - "x";`} {
+		_, _, err := board.ParseEPD(epd)
+		assert.Error(t, err, epd)
+	}
+}
+
+func TestExportEPD(t *testing.T) {
+	b := board.NewBoard("6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 4 31")
+	ops := map[string][]string{
+		"bm":  {"Rd8#"},
+		"id":  {`back "rank"`},
+		"c0":  {`a\b`},
+		"zz":  {"x y"},
+		"acd": {"12"},
+	}
+	epd := b.ExportEPD(ops)
+	assert.Equal(t, `6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - id "back \"rank\""; bm Rd8#; acd 12; c0 "a\\b"; zz "x y"; hmvc 4; fmvn 31;`, epd)
+
+	parsed, parsedOps, err := board.ParseEPD(epd)
+	assert.NoError(t, err)
+	assert.Equal(t, b.ExportFEN(), parsed.ExportFEN())
+	ops["hmvc"], ops["fmvn"] = []string{"4"}, []string{"31"}
+	assert.Equal(t, ops, parsedOps)
+
+	assert.Equal(t, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -", board.NewBoard(board.StartingFEN).ExportEPD(nil))
+}
@@ -0,0 +1,74 @@
+package board
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+)
+
+func TestMarshalMarlinformat(t *testing.T) {
+	p := board.PackedPosition{Board: board.NewBoard(board.StartPos), Score: 25, WDL: 2}
+	rec := p.Marshal(board.Marlinformat)
+	assert.Equal(t, []byte{0xff, 0xff, 0, 0, 0, 0, 0xff, 0xff}, rec[:8])
+	// a1 rook with castling rights, b1 knight, c1 bishop, d1 queen, e1 king.
+	assert.Equal(t, []byte{0x16, 0x42, 0x25, 0x61}, rec[8:12])
+	assert.Equal(t, []byte{0, 0, 0, 0}, rec[12:16])
+	assert.Equal(t, []byte{0x88, 0x88, 0x88, 0x88}, rec[16:20])
+	assert.Equal(t, []byte{0x9e, 0xca, 0xad, 0xe9}, rec[20:24])
+	assert.Equal(t, []byte{64, 0, 1, 0, 25, 0, 2, 0}, rec[24:])
+
+	p = board.PackedPosition{Board: board.NewBoard("4k3/8/8/3pP3/8/8/8/4K2R w K d6 3 40"), Score: -300, WDL: 1}
+	rec = p.Marshal(board.Marlinformat)
+	assert.Equal(t, byte(43), rec[24])
+	assert.Equal(t, []byte{3, 40, 0, 0xd4, 0xfe, 1}, rec[25:31])
+}
+
+func TestMarshalBulletformat(t *testing.T) {
+	fen := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"
+	p := board.PackedPosition{Board: board.NewBoard(fen), Score: -30, WDL: 2}
+	rec := p.Marshal(board.Bulletformat)
+	// Black is to move, so the board is stored flipped: the pawn on e4 is an
+	// opponent pawn on e5.
+	assert.Equal(t, []byte{0xff, 0xff, 0, 0, 0x10, 0, 0xef, 0xff}, rec[:8])
+	assert.Equal(t, []byte{0x13, 0x42, 0x25, 0x31}, rec[8:12])
+	assert.Equal(t, []byte{30, 0, 0, 4, 4, 0, 0, 0}, rec[24:])
+
+	q, err := board.UnmarshalPacked(board.Bulletformat, rec[:])
+	assert.NoError(t, err)
+	assert.Equal(t, "rnbqkbnr/pppp1ppp/8/4p3/8/8/PPPPPPPP/RNBQKBNR w - - 0 1", q.Board.ExportFEN())
+	assert.Equal(t, int16(30), q.Score)
+	assert.Equal(t, uint8(0), q.WDL)
+}
+
+func TestPackedRoundTrip(t *testing.T) {
+	tests := []struct {
+		format board.PackedFormat
+		fen    string
+	}{
+		{board.Marlinformat, board.StartingFEN},
+		{board.Marlinformat, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R b Kq - 3 17"},
+		{board.Marlinformat, "4k3/8/8/3pP3/8/8/8/4K2R w K d6 0 40"},
+		{board.Marlinformat, "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1"},
+		{board.Bulletformat, "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1"},
+		{board.Bulletformat, "r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 1"},
+	}
+	for _, tt := range tests {
+		b := board.NewBoard(tt.fen)
+		p := board.PackedPosition{Board: b, Score: -123, WDL: 1}
+		rec := p.Marshal(tt.format)
+		q, err := board.UnmarshalPacked(tt.format, rec[:])
+		assert.NoError(t, err, tt.fen)
+		assert.Equal(t, tt.fen, q.Board.ExportFEN())
+		assert.Equal(t, b.Hash, q.Board.Hash, tt.fen)
+		assert.Equal(t, p.Score, q.Score, tt.fen)
+		assert.Equal(t, p.WDL, q.WDL, tt.fen)
+	}
+
+	var rec [board.PackedSize]byte
+	_, err := board.UnmarshalPacked(board.Marlinformat, rec[:])
+	assert.Error(t, err)
+	_, err = board.UnmarshalPacked(board.Marlinformat, rec[:8])
+	assert.Error(t, err)
+}
@@ -0,0 +1,56 @@
+package board
+
+import (
+	"testing"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+)
+
+func TestSAN(t *testing.T) {
+	tests := []struct {
+		fen string
+		uci string
+		san string
+	}{
+		{board.StartingFEN, "e2e4", "e4"},
+		{board.StartingFEN, "g1f3", "Nf3"},
+		{"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", "f3e5", "Nxe5"},
+		{"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2", "e4d5", "exd5"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", "O-O"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8", "O-O-O"},
+		{"7k/P7/8/8/8/8/8/K7 w - - 0 1", "a7a8q", "a8=Q+"},
+		{"7k/P7/8/8/8/8/8/K7 w - - 0 1", "a7a8n", "a8=N"},
+		{"6k1/5ppp/8/8/8/8/8/K3R3 w - - 0 1", "e1e8", "Re8#"},
+		// Knights on b1 and f3 both reach d2: file disambiguation.
+		{"4k3/8/8/8/8/5N2/8/1N2K3 w - - 0 1", "b1d2", "Nbd2"},
+		// Rooks on a1 and a5 both reach a3: rank disambiguation.
+		{"4k3/8/8/R7/8/8/8/R3K3 w - - 0 1", "a1a3", "R1a3"},
+		// Queens on a1, a3 and c1 all reach b2: full square.
+		{"4k3/8/8/8/8/Q7/8/Q1Q1K3 w - - 0 1", "a1b2", "Qa1b2"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", "exd6"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.san, func(t *testing.T) {
+			b := board.NewBoard(tt.fen)
+			move, err := b.ParseSAN(tt.san)
+			if err != nil {
+				t.Fatalf("ParseSAN(%q): %v", tt.san, err)
+			}
+			if move.String() != tt.uci {
+				t.Fatalf("ParseSAN(%q) = %s, want %s", tt.san, move, tt.uci)
+			}
+			if got := b.SAN(move); got != tt.san {
+				t.Fatalf("SAN(%s) = %q, want %q", tt.uci, got, tt.san)
+			}
+		})
+	}
+}
+
+func TestParseSANErrors(t *testing.T) {
+	b := board.NewBoard("4k3/8/8/8/8/5N2/8/1N2K3 w - - 0 1")
+	for _, san := range []string{"Nd2", "e5", "Qd4", "O-O", "x", "Nz9"} {
+		if _, err := b.ParseSAN(san); err == nil {
+			t.Errorf("ParseSAN(%q) succeeded, want error", san)
+		}
+	}
+}
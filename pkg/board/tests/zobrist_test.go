@@ -56,3 +56,24 @@ func TestTempoLoss(t *testing.T) {
 		}
 	})
 }
+
+// Flipping twice must restore the original position and the flipped hash must
+// match a hash computed from scratch.
+func TestFlipHash(t *testing.T) {
+	b := board.NewBoard("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K1R1 b Qkq - 0 1")
+	b.PlayMovesUCI("c7c5")
+	fen := b.ExportFEN()
+
+	b.Flip()
+	if b.Hash != b.SeedHash() {
+		t.Fatalf("flipped hash does not match seeded hash")
+	}
+	if got := b.ExportFEN(); got != "r3k1r1/pppbbppp/2n2q1P/1P2p3/2Ppn3/BN2PNP1/P2PQPB1/R3K2R b KQq c3 0 2" {
+		t.Fatalf("unexpected flipped FEN %s", got)
+	}
+
+	b.Flip()
+	if got := b.ExportFEN(); got != fen {
+		t.Fatalf("double flip: want %s, got %s", fen, got)
+	}
+}
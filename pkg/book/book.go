@@ -0,0 +1,234 @@
+package book
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sort"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+)
+
+// Mode selects how a move is picked among the book moves of a position.
+type Mode int
+
+const (
+	// Best plays the move with the highest weight.
+	Best Mode = iota
+	// Weighted picks a move at random in proportion to its weight.
+	Weighted
+	// Uniform picks any book move with equal chance.
+	Uniform
+	// MinWeight drops the moves weighted below MinWeight percent of the best
+	// move and picks among the rest by weight.
+	MinWeight
+)
+
+// DefaultMinWeight is the MinWeight a new book starts with.
+const DefaultMinWeight = 10
+
+// Modes names the modes in the order of their constants.
+var Modes = []string{"Best", "Weighted", "Uniform", "MinWeight"}
+
+// Entry is a legal book move with its weight.
+type Entry struct {
+	Move   board.Move
+	Weight uint16
+}
+
+// Book is a PolyGlot opening book. PolyGlot files are sorted by key, so
+// positions are found by binary search on disk and books of any size can be
+// used without loading them into memory. A Book is not safe for concurrent
+// use; every engine has its own.
+type Book struct {
+	file    *os.File
+	rng     *rand.Rand
+	path    string
+	played  []played
+	entries int64
+	// Mode selects the move played among the book moves.
+	Mode Mode
+	// MinWeight is the percentage of the best move's weight a move needs in
+	// MinWeight mode.
+	MinWeight int
+	// Depth is the last full move the book is used for, 0 for no limit.
+	Depth int
+	// Learning records the moves played from the book so that Learn can
+	// adjust them by the result of the game.
+	Learning bool
+}
+
+// New returns a book with no file open that picks moves by weight.
+func New() *Book {
+	return &Book{
+		rng:       rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+		Mode:      Weighted,
+		MinWeight: DefaultMinWeight,
+	}
+}
+
+// Seed makes the random move choice repeatable.
+func (bk *Book) Seed(seed uint64) {
+	bk.rng = rand.New(rand.NewPCG(seed, seed))
+}
+
+// Open switches the book to the PolyGlot file at path. The previous file
+// stays open if the new one cannot be used.
+func (bk *Book) Open(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if info.Size() == 0 || info.Size()%entrySize != 0 {
+		f.Close()
+		return fmt.Errorf("%s: %d bytes is not a PolyGlot book", path, info.Size())
+	}
+
+	bk.Close()
+	bk.file, bk.path, bk.entries = f, path, info.Size()/entrySize
+	return nil
+}
+
+// Close closes the book file. The book has no moves until opened again.
+func (bk *Book) Close() error {
+	if bk.file == nil {
+		return nil
+	}
+	err := bk.file.Close()
+	bk.file, bk.path, bk.entries = nil, "", 0
+	return err
+}
+
+// Path returns the path of the open book file, empty if none.
+func (bk *Book) Path() string {
+	return bk.path
+}
+
+// Len returns the number of entries in the book file.
+func (bk *Book) Len() int64 {
+	return bk.entries
+}
+
+// Entries returns the legal book moves of the position in file order.
+func (bk *Book) Entries(b *board.Board) ([]Entry, error) {
+	_, stored, err := bk.lookup(PolyZobrist(b))
+	if err != nil || len(stored) == 0 {
+		return nil, err
+	}
+
+	moves := b.PseudoMoveGen()
+	var entries []Entry
+	for _, raw := range stored {
+		uci := convertPolyToUCI(b, polyMoveToUCI(raw.Move))
+		for _, move := range moves {
+			if move.String() != uci {
+				continue
+			}
+			umove := b.MakeMove(move)
+			legal := !b.IsChecked(b.Side ^ 1)
+			umove()
+			if legal {
+				entries = append(entries, Entry{Move: move, Weight: raw.Weight})
+			}
+			break
+		}
+	}
+	return entries, nil
+}
+
+// Move picks a book move for the position according to Mode. ok is false when
+// the position is past Depth or the book has no legal move for it.
+func (bk *Book) Move(b *board.Board) (move board.Move, ok bool) {
+	if bk.file == nil || (bk.Depth > 0 && int(b.FullMoveCounter) > bk.Depth) {
+		return 0, false
+	}
+	entries, err := bk.Entries(b)
+	if err != nil || len(entries) == 0 {
+		return 0, false
+	}
+	move = bk.pick(entries)
+	bk.record(b, move)
+	return move, true
+}
+
+// pick selects one of entries according to Mode.
+func (bk *Book) pick(entries []Entry) board.Move {
+	best := entries[0]
+	for _, e := range entries[1:] {
+		if e.Weight > best.Weight {
+			best = e
+		}
+	}
+
+	switch bk.Mode {
+	case Best:
+		return best.Move
+	case Uniform:
+		return entries[bk.rng.IntN(len(entries))].Move
+	case MinWeight:
+		threshold := int(best.Weight) * bk.MinWeight / 100
+		kept := entries[:0:0]
+		for _, e := range entries {
+			if int(e.Weight) >= threshold {
+				kept = append(kept, e)
+			}
+		}
+		entries = kept
+	}
+
+	total := 0
+	for _, e := range entries {
+		total += int(e.Weight)
+	}
+	if total == 0 {
+		return entries[bk.rng.IntN(len(entries))].Move
+	}
+	r := bk.rng.IntN(total)
+	for _, e := range entries {
+		r -= int(e.Weight)
+		if r < 0 {
+			return e.Move
+		}
+	}
+	return best.Move
+}
+
+// lookup binary searches the file for the entries stored under key and
+// returns them with the index of the first one.
+func (bk *Book) lookup(key uint64) (int64, []RawEntry, error) {
+	if bk.file == nil {
+		return 0, nil, nil
+	}
+
+	var err error
+	var buf [entrySize]byte
+	first := int64(sort.Search(int(bk.entries), func(i int) bool {
+		if err != nil {
+			return true
+		}
+		_, err = bk.file.ReadAt(buf[:8], int64(i)*entrySize)
+		return binary.BigEndian.Uint64(buf[:8]) >= key
+	}))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var entries []RawEntry
+	for i := first; i < bk.entries; i++ {
+		if _, err := bk.file.ReadAt(buf[:], i*entrySize); err != nil {
+			return 0, nil, err
+		}
+		e := decodeEntry(buf[:])
+		if e.Key != key {
+			break
+		}
+		entries = append(entries, e)
+	}
+	return first, entries, nil
+}
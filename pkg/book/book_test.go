@@ -0,0 +1,144 @@
+package book
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+)
+
+func writeBook(t *testing.T, entries []RawEntry) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "book.bin")
+	if err := WriteFile(path, entries); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func entry(key uint64, move string, weight uint16) RawEntry {
+	return RawEntry{Key: key, Move: EncodeMove(board.MoveFromString(move)), Weight: weight}
+}
+
+func testBook(t *testing.T) (*Book, *board.Board) {
+	t.Helper()
+	b := board.NewBoard(board.StartPos)
+	start := PolyZobrist(b)
+	entries := []RawEntry{
+		entry(start, "d2d4", 10),
+		entry(start, "e2e4", 30),
+		entry(start, "g1f3", 2),
+		// Illegal in the start position, must be skipped.
+		entry(start, "e2e5", 100),
+	}
+	// Neighbouring keys so that the binary search has to find the range.
+	for i := range uint64(50) {
+		entries = append(entries, entry(start-1000-i, "a2a3", 1), entry(start+1000+i, "h2h3", 1))
+	}
+
+	bk := New()
+	bk.Seed(1)
+	if err := bk.Open(writeBook(t, entries)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bk.Close() })
+	return bk, b
+}
+
+func TestPolyZobristStartPos(t *testing.T) {
+	// Reference key from the PolyGlot book format specification.
+	assert.Equal(t, uint64(0x463b96181691fc9c), PolyZobrist(board.NewBoard(board.StartPos)))
+}
+
+func TestEntries(t *testing.T) {
+	bk, b := testBook(t)
+	entries, err := bk.Entries(b)
+	assert.NoError(t, err)
+
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Move.String())
+	}
+	// Written in PolyGlot order, by descending weight.
+	assert.Equal(t, []string{"e2e4", "d2d4", "g1f3"}, got)
+}
+
+func TestModes(t *testing.T) {
+	bk, b := testBook(t)
+	count := func() map[string]int {
+		counts := make(map[string]int)
+		for range 2000 {
+			move, ok := bk.Move(b)
+			assert.True(t, ok)
+			counts[move.String()]++
+		}
+		return counts
+	}
+
+	bk.Mode = Best
+	assert.Equal(t, map[string]int{"e2e4": 2000}, count())
+
+	bk.Mode = Uniform
+	assert.Len(t, count(), 3)
+
+	bk.Mode = Weighted
+	counts := count()
+	assert.Greater(t, counts["e2e4"], counts["d2d4"])
+	assert.Greater(t, counts["d2d4"], counts["g1f3"])
+
+	// g1f3 has 2/30 of the best weight, below the 10% threshold.
+	bk.Mode = MinWeight
+	counts = count()
+	assert.Zero(t, counts["g1f3"])
+	assert.Len(t, counts, 2)
+}
+
+func TestDepth(t *testing.T) {
+	bk, b := testBook(t)
+	bk.Depth = 1
+	_, ok := bk.Move(b)
+	assert.True(t, ok)
+
+	b.FullMoveCounter = 2
+	_, ok = bk.Move(b)
+	assert.False(t, ok)
+}
+
+func TestOpenInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "short.bin")
+	assert.NoError(t, os.WriteFile(path, make([]byte, entrySize+1), 0o600))
+	bk := New()
+	assert.Error(t, bk.Open(path))
+	assert.Error(t, bk.Open(filepath.Join(t.TempDir(), "missing.bin")))
+
+	_, ok := bk.Move(board.NewBoard(board.StartPos))
+	assert.False(t, ok)
+}
+
+func TestEncodeMove(t *testing.T) {
+	tests := []struct {
+		fen  string
+		move string
+		poly string
+	}{
+		{board.StartingFEN, "g1f3", "g1f3"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", "e1h1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8", "e8a8"},
+		{"7k/P7/8/8/8/8/8/K7 w - - 0 1", "a7a8n", "a7a8n"},
+		{"7k/P7/8/8/8/8/8/K7 w - - 0 1", "a7a8q", "a7a8q"},
+	}
+	for _, tt := range tests {
+		b := board.NewBoard(tt.fen)
+		for _, m := range b.LegalMoves() {
+			if m.String() != tt.move {
+				continue
+			}
+			poly := polyMoveToUCI(EncodeMove(m))
+			assert.Equal(t, tt.poly, poly)
+			assert.Equal(t, tt.move, convertPolyToUCI(b, poly))
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package book
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+)
+
+// RawEntry is a PolyGlot entry as stored on disk.
+type RawEntry struct {
+	Key    uint64
+	Move   uint16
+	Weight uint16
+	Learn  uint32
+}
+
+// polyPromotions maps promotion pieces to their PolyGlot codes.
+var polyPromotions = map[uint8]uint16{
+	board.Knights: 1,
+	board.Bishops: 2,
+	board.Rooks:   3,
+	board.Queens:  4,
+}
+
+// EncodeMove packs a move the way PolyGlot stores it. Castling is written as
+// the king taking its own rook.
+func EncodeMove(m board.Move) uint16 {
+	from, to := m.From(), m.To()
+	if m.IsCastling() {
+		if to%8 == 6 {
+			to++
+		} else {
+			to -= 2
+		}
+	}
+	row := func(sq board.Square) uint16 { return uint16(7 - sq/8) }
+	file := func(sq board.Square) uint16 { return uint16(sq % 8) }
+	return polyPromotions[m.Promotion()]<<12 | row(from)<<9 | file(from)<<6 | row(to)<<3 | file(to)
+}
+
+func decodeEntry(buf []byte) RawEntry {
+	return RawEntry{
+		Key:    binary.BigEndian.Uint64(buf[:8]),
+		Move:   binary.BigEndian.Uint16(buf[8:10]),
+		Weight: binary.BigEndian.Uint16(buf[10:12]),
+		Learn:  binary.BigEndian.Uint32(buf[12:16]),
+	}
+}
+
+// ReadFile reads every entry of a PolyGlot file.
+func ReadFile(path string) ([]RawEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []RawEntry
+	r := bufio.NewReader(f)
+	var buf [entrySize]byte
+	for {
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			if err == io.EOF {
+				return entries, nil
+			}
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		entries = append(entries, decodeEntry(buf[:]))
+	}
+}
+
+// SortEntries puts entries in PolyGlot order: by key, then by descending
+// weight.
+func SortEntries(entries []RawEntry) {
+	slices.SortStableFunc(entries, func(a, b RawEntry) int {
+		if c := cmp.Compare(a.Key, b.Key); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.Weight, a.Weight)
+	})
+}
+
+// WriteFile sorts the entries and writes them as a PolyGlot file.
+func WriteFile(path string, entries []RawEntry) error {
+	SortEntries(entries)
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	var buf [entrySize]byte
+	for _, e := range entries {
+		binary.BigEndian.PutUint64(buf[:8], e.Key)
+		binary.BigEndian.PutUint16(buf[8:10], e.Move)
+		binary.BigEndian.PutUint16(buf[10:12], e.Weight)
+		binary.BigEndian.PutUint32(buf[12:16], e.Learn)
+		if _, err := w.Write(buf[:]); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,124 @@
+package book
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+)
+
+// played is a book move the engine played in the current game.
+type played struct {
+	key  uint64
+	move uint16
+	side int8
+}
+
+// record remembers a book move for learning.
+func (bk *Book) record(b *board.Board, move board.Move) {
+	if bk.Learning {
+		bk.played = append(bk.played, played{key: PolyZobrist(b), move: EncodeMove(move), side: b.Side})
+	}
+}
+
+// learnWeight adjusts a weight for a move that won (score 1) or lost
+// (score -1). Winning moves gain a quarter, losing moves lose half, so a line
+// that keeps losing soon drops out of weighted play.
+func learnWeight(weight uint16, score int) uint16 {
+	const maxWeight = 1<<16 - 1
+	switch {
+	case score > 0:
+		return uint16(min(int(weight)+int(weight)/4+1, maxWeight))
+	case score < 0:
+		return weight / 2
+	}
+	return weight
+}
+
+// Learn adjusts the book moves played since the last call by the game result
+// from white's point of view: 1, 0 or -1. The learn field of every entry
+// played counts the games learned from. The book is written back to a
+// temporary file next to it that then replaces it, so an interrupted write
+// leaves the old book intact.
+func (bk *Book) Learn(result int) error {
+	games := bk.played
+	bk.played = nil
+	if bk.file == nil || len(games) == 0 {
+		return nil
+	}
+
+	patches := make(map[int64]RawEntry)
+	for _, p := range games {
+		first, entries, err := bk.lookup(p.key)
+		if err != nil {
+			return err
+		}
+		for i, e := range entries {
+			if e.Move != p.move {
+				continue
+			}
+			offset := (first + int64(i)) * entrySize
+			if patched, ok := patches[offset]; ok {
+				e = patched
+			}
+			score := result
+			if p.side == board.Black {
+				score = -score
+			}
+			e.Weight = learnWeight(e.Weight, score)
+			e.Learn++
+			patches[offset] = e
+			break
+		}
+	}
+	if len(patches) == 0 {
+		return nil
+	}
+	return bk.rewrite(patches)
+}
+
+// rewrite copies the book with the patched entries to a temporary file,
+// renames it over the book and reopens it.
+func (bk *Book) rewrite(patches map[int64]RawEntry) error {
+	path := bk.path
+	info, err := bk.file.Stat()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, io.NewSectionReader(bk.file, 0, bk.entries*entrySize)); err != nil {
+		tmp.Close()
+		return err
+	}
+	var buf [8]byte
+	for offset, e := range patches {
+		binary.BigEndian.PutUint16(buf[:2], e.Weight)
+		binary.BigEndian.PutUint32(buf[2:6], e.Learn)
+		if _, err := tmp.WriteAt(buf[:6], offset+10); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Chmod(info.Mode()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return bk.Open(path)
+}
@@ -0,0 +1,67 @@
+package book
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+)
+
+func TestLearn(t *testing.T) {
+	bk, b := testBook(t)
+	bk.Mode = Best
+	bk.Learning = true
+	weight := func(move string) uint16 {
+		entries, err := bk.Entries(b)
+		assert.NoError(t, err)
+		for _, e := range entries {
+			if e.Move.String() == move {
+				return e.Weight
+			}
+		}
+		return 0
+	}
+
+	// A win for white raises e2e4 by a quarter.
+	move, ok := bk.Move(b)
+	assert.True(t, ok)
+	assert.Equal(t, "e2e4", move.String())
+	assert.NoError(t, bk.Learn(1))
+	assert.Equal(t, uint16(38), weight("e2e4"))
+
+	assert.NoError(t, bk.Learn(-1), "nothing recorded since the last call")
+	assert.Equal(t, uint16(38), weight("e2e4"))
+
+	// A win for black is a loss for white's move, twice halving it. The learn
+	// field counts the games.
+	for range 2 {
+		bk.Move(b)
+		assert.NoError(t, bk.Learn(-1))
+	}
+	assert.Equal(t, uint16(9), weight("e2e4"))
+	entries, err := ReadFile(bk.Path())
+	assert.NoError(t, err)
+	for _, e := range entries {
+		if e.Key == PolyZobrist(b) && e.Move == EncodeMove(board.MoveFromString("e2e4")) {
+			assert.Equal(t, uint32(3), e.Learn)
+		}
+	}
+
+	// The rewrite leaves no temporary files behind.
+	files, err := os.ReadDir(filepath.Dir(bk.Path()))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+}
+
+func TestLearnDisabled(t *testing.T) {
+	bk, b := testBook(t)
+	bk.Mode = Best
+	bk.Move(b)
+	assert.NoError(t, bk.Learn(1))
+	entries, err := bk.Entries(b)
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(30), entries[0].Weight)
+}
@@ -1,11 +1,7 @@
 package book
 
 import (
-	"bufio"
-	"encoding/binary"
 	"fmt"
-	"math/rand"
-	"os"
 
 	"github.com/likeawizard/tofiks/pkg/board"
 )
@@ -26,19 +22,10 @@ const (
 
 	entrySize = 16
 	sideHash  = 780
-)
-
-var BookMoves map[uint64][]polyEntry
 
-type polyEntry struct {
-	move   string
-	weight uint16
-}
-
-type engineMove struct {
-	move   board.Move
-	weight uint16
-}
+	// DefaultFile is the book loaded when no other book file is set.
+	DefaultFile = "book.bin"
+)
 
 func getPieceIdx(piece, row, file int) int {
 	return 64*piece + 8*row + file
@@ -67,12 +54,6 @@ func squareToRowAndFile(sq int) (int, int) {
 	return 7 - (sq / 8), sq % 8
 }
 
-// Check if current position is in book.
-func InBook(b *board.Board) bool {
-	_, ok := BookMoves[PolyZobrist(b)]
-	return ok
-}
-
 // Convert polyglot castling moves to UCI else return unchanged.
 func convertPolyToUCI(b *board.Board, polyMove string) string {
 	switch polyMove {
@@ -97,100 +78,6 @@ func convertPolyToUCI(b *board.Board, polyMove string) string {
 	return polyMove
 }
 
-// Get best scoring book move.
-func GetBest(b *board.Board) board.Move {
-	moves := getBookMoves(b)
-	return moves[0].move
-}
-
-// Get weighted random.
-func GetWeighted(b *board.Board) board.Move {
-	moves := getBookMoves(b)
-	type bin struct {
-		min  int
-		max  int
-		move board.Move
-	}
-	moveBins := make([]bin, len(moves))
-	counter := 0
-	for idx, move := range moves {
-		moveBins[idx] = bin{min: counter, max: counter + int(move.weight), move: move.move}
-		counter += int(move.weight)
-	}
-
-	r := rand.Intn(counter)
-	for _, bin := range moveBins {
-		if r >= bin.min && r < bin.max {
-			return bin.move
-		}
-	}
-	return moves[0].move
-}
-
-func getBookMoves(b *board.Board) []engineMove {
-	polyMoves := BookMoves[PolyZobrist(b)]
-	moves := b.PseudoMoveGen()
-	engineMoves := make([]engineMove, 0)
-	for _, pMove := range polyMoves {
-		pMove.move = convertPolyToUCI(b, pMove.move)
-		for _, move := range moves {
-			if pMove.move == move.String() {
-				engineMoves = append(engineMoves, engineMove{move: move, weight: pMove.weight})
-			}
-		}
-	}
-	return engineMoves
-}
-
-func PrintBookMoves(b *board.Board) {
-	polyMoves := getBookMoves(b)
-
-	if len(polyMoves) == 0 {
-		fmt.Println("Out of book.")
-	}
-	totalWeight := float32(0)
-	for _, pMove := range polyMoves {
-		totalWeight += float32(pMove.weight)
-	}
-	for _, pMove := range polyMoves {
-		fmt.Printf(" %s (%.1f%%)", pMove.move, 100*float32(pMove.weight)/totalWeight)
-	}
-	fmt.Println()
-}
-
-func LoadBook(path string) int {
-	file, err := os.Open(path)
-	if err != nil {
-		return 0
-	}
-	BookMoves = make(map[uint64][]polyEntry)
-	buffer := make([]byte, entrySize)
-	reader := bufio.NewReader(file)
-
-	lines := 0
-	for numBytes, err := reader.Read(buffer); err == nil && numBytes == 16; numBytes, err = reader.Read(buffer) {
-		if len(buffer) != 16 {
-			continue
-		}
-		key, entry := decodeBookEntry(buffer)
-		// if _, ok := BookMoves[key]; !ok {
-		// 	BookMoves[key] = make([]polyEntry, 0)
-		// }
-		BookMoves[key] = append(BookMoves[key], entry)
-		lines++
-	}
-
-	return lines
-}
-
-func decodeBookEntry(bytes []byte) (uint64, polyEntry) {
-	key := binary.BigEndian.Uint64(bytes[:8])
-	move := binary.BigEndian.Uint16(bytes[8:10])
-	weight := binary.BigEndian.Uint16(bytes[10:12])
-
-	return key, polyEntry{move: polyMoveToUCI(move), weight: weight}
-}
-
 func polyMoveToUCI(move uint16) string {
 	files := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
 	promoPiece := []string{"", "n", "b", "r", "q"}
@@ -0,0 +1,293 @@
+// Package datagen generates training data from self-play: games of an
+// in-process engine at a fixed node count, from random or book openings, with
+// every quiet position written alongside its search score and the result of
+// the game.
+package datagen
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+	"github.com/likeawizard/tofiks/pkg/match"
+	"github.com/likeawizard/tofiks/pkg/search"
+	"github.com/likeawizard/tofiks/pkg/texel"
+)
+
+// maxScore bounds the scores of written positions. Mates score beyond it.
+const maxScore = 10000
+
+// textFormat is the format of the text output: EPD records with the result in
+// c9 and the search score in ce.
+const textFormat = texel.FormatEPD
+
+// Config describes a data generation run.
+type Config struct {
+	// Games is the number of games to play over all workers.
+	Games   int
+	Workers int
+	// Nodes is the node limit of each search and Hash the transposition
+	// table size of each worker in MB.
+	Nodes int
+	Hash  int
+	// Seed determines the openings. Worker w draws those of its n-th game
+	// from a generator seeded with Seed, w and n, so a run is reproducible
+	// and a resumed run continues where it stopped.
+	Seed uint64
+	// Openings are picked at random when set. Otherwise games start from the
+	// start position. RandomPlies random moves are played after the opening.
+	Openings    []match.Opening
+	RandomPlies int
+	// Configure, when set, sets up each worker's engine.
+	Configure    func(*search.Engine) error
+	Adjudication match.Adjudication
+	// Packed is the format of the packed output, texel.FormatMarlin or
+	// texel.FormatBullet.
+	Packed texel.Format
+}
+
+// Stats is the progress of a run. Games and Positions include those of the
+// run resumed, the other fields cover this run only.
+type Stats struct {
+	Games, Positions    int
+	Wins, Draws, Losses int
+	Elapsed             time.Duration
+	// Rate is the number of positions written per second.
+	Rate float64
+}
+
+// Positions returns the quiet positions of a game: those not in check where
+// the engine played neither a capture nor a promotion and did not see a mate.
+// The positions of the opening are left out.
+func Positions(g *match.Game) ([]texel.Sample, error) {
+	b := board.NewBoard(g.Opening.FEN)
+	for _, uci := range g.Opening.Moves {
+		if _, ok := b.MoveUCI(uci); !ok {
+			return nil, fmt.Errorf("illegal opening move %s", uci)
+		}
+	}
+	result := g.Score()
+	var positions []texel.Sample
+	for i, uci := range g.Moves {
+		move, ok := legalMove(b, uci)
+		if !ok {
+			return nil, fmt.Errorf("illegal move %s", uci)
+		}
+		score := g.Scores[i]
+		if b.Side == board.Black {
+			score = -score
+		}
+		if !b.InCheck && !move.IsCapture() && move.Promotion() == 0 && score > -maxScore && score < maxScore {
+			positions = append(positions, texel.Sample{Board: b.Copy(), Score: int16(score), HasScore: true, Result: result})
+		}
+		b.MakeMove(move)
+	}
+	return positions, nil
+}
+
+func legalMove(b *board.Board, uci string) (board.Move, bool) {
+	for _, m := range b.LegalMoves() {
+		if m.String() == uci {
+			return m, true
+		}
+	}
+	return 0, false
+}
+
+// gameData is the output of one game of a worker.
+type gameData struct {
+	worker    int
+	text      []byte
+	records   []byte
+	positions int
+	result    float64
+}
+
+// Run plays the games of cfg and appends the positions with their scores and
+// results to out+".epd" as EPD records and to a packed file, out+".marlin" or
+// out+".bullet". The progress is kept in out+".progress" after every game; a
+// run with that file present resumes it. progress, when set, is called after
+// every game.
+func Run(cfg Config, out string, progress func(Stats)) (Stats, error) {
+	if cfg.Workers < 1 || cfg.Games < 1 || cfg.Nodes < 1 {
+		return Stats{}, errors.New("games, workers and nodes must be positive")
+	}
+	if _, ok := cfg.Packed.Packed(); !ok {
+		return Stats{}, fmt.Errorf("%v is not a packed format", cfg.Packed)
+	}
+	st, err := openState(out, cfg)
+	if err != nil {
+		return Stats{}, err
+	}
+	files, err := openOutputs(out, st, cfg.Packed)
+	if err != nil {
+		return Stats{}, err
+	}
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	if err := st.save(out); err != nil {
+		return Stats{}, err
+	}
+	text, bin := bufio.NewWriter(files[0]), bufio.NewWriter(files[1])
+
+	gameCh := make(chan gameData, cfg.Workers)
+	errCh := make(chan error, cfg.Workers)
+	var wg sync.WaitGroup
+	for w := range cfg.Workers {
+		games := cfg.Games / cfg.Workers
+		if w < cfg.Games%cfg.Workers {
+			games++
+		}
+		if st.Games[w] >= games {
+			continue
+		}
+		wg.Go(func() {
+			if err := runWorker(cfg, w, st.Games[w], games, gameCh); err != nil {
+				errCh <- err
+			}
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(gameCh)
+	}()
+
+	stats := Stats{Games: st.total(), Positions: st.Positions}
+	start, written := time.Now(), 0
+	for gd := range gameCh {
+		if _, err := text.Write(gd.text); err != nil {
+			return stats, err
+		}
+		if _, err := bin.Write(gd.records); err != nil {
+			return stats, err
+		}
+		if err := text.Flush(); err != nil {
+			return stats, err
+		}
+		if err := bin.Flush(); err != nil {
+			return stats, err
+		}
+		st.Text += int64(len(gd.text))
+		st.Binary += int64(len(gd.records))
+		st.Games[gd.worker]++
+		st.Positions += gd.positions
+		if err := st.save(out); err != nil {
+			return stats, err
+		}
+
+		stats.Games++
+		stats.Positions += gd.positions
+		switch gd.result {
+		case 1:
+			stats.Wins++
+		case 0:
+			stats.Losses++
+		default:
+			stats.Draws++
+		}
+		written += gd.positions
+		stats.Elapsed = time.Since(start)
+		stats.Rate = float64(written) / max(stats.Elapsed.Seconds(), 1e-3)
+		if progress != nil {
+			progress(stats)
+		}
+	}
+	select {
+	case err := <-errCh:
+		return stats, err
+	default:
+	}
+	return stats, nil
+}
+
+// runWorker plays the games from..to-1 of worker w.
+func runWorker(cfg Config, w, from, to int, out chan<- gameData) error {
+	p, err := match.NewEnginePlayer("tofiks", func(e *search.Engine) error {
+		if cfg.Hash > 0 {
+			e.TTable = search.NewTTable(cfg.Hash)
+		}
+		if cfg.Configure != nil {
+			return cfg.Configure(e)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	tc := match.TimeControl{Nodes: cfg.Nodes}
+	for n := from; n < to; n++ {
+		rng := rand.New(rand.NewPCG(cfg.Seed, uint64(w)<<32|uint64(n)))
+		opening, err := randomOpening(rng, cfg.Openings, cfg.RandomPlies)
+		if err != nil {
+			return err
+		}
+		g, err := match.Play(p, p, opening, tc, cfg.Adjudication)
+		if err != nil {
+			return err
+		}
+		positions, err := Positions(g)
+		if err != nil {
+			return err
+		}
+		gd := gameData{worker: w, positions: len(positions), result: g.Score()}
+		if gd.text, err = encode(positions, textFormat); err != nil {
+			return err
+		}
+		if gd.records, err = encode(positions, cfg.Packed); err != nil {
+			return err
+		}
+		out <- gd
+	}
+	return nil
+}
+
+// encode returns the positions in format f.
+func encode(positions []texel.Sample, f texel.Format) ([]byte, error) {
+	var buf bytes.Buffer
+	w := texel.NewDataWriter(&buf, f)
+	for _, pos := range positions {
+		if err := w.Write(pos); err != nil {
+			return nil, err
+		}
+	}
+	err := w.Flush()
+	return buf.Bytes(), err
+}
+
+// randomOpening picks an opening and plays plies random moves from it,
+// retrying until the game is not over.
+func randomOpening(rng *rand.Rand, openings []match.Opening, plies int) (match.Opening, error) {
+	for range 100 {
+		opening := match.Opening{FEN: board.StartingFEN}
+		if len(openings) > 0 {
+			opening = openings[rng.IntN(len(openings))]
+		}
+		b := board.NewBoard(opening.FEN)
+		if !b.PlayMovesUCI(strings.Join(opening.Moves, " ")) {
+			return match.Opening{}, fmt.Errorf("illegal opening %s %v", opening.FEN, opening.Moves)
+		}
+		moves := append([]string{}, opening.Moves...)
+		for range plies {
+			legal := b.LegalMoves()
+			if len(legal) == 0 {
+				break
+			}
+			move := legal[rng.IntN(len(legal))]
+			b.MakeMove(move)
+			moves = append(moves, move.String())
+		}
+		if len(b.LegalMoves()) > 0 {
+			return match.Opening{FEN: opening.FEN, Moves: moves}, nil
+		}
+	}
+	return match.Opening{}, errors.New("no playable random opening")
+}
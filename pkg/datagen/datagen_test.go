@@ -0,0 +1,64 @@
+package datagen
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+	"github.com/likeawizard/tofiks/pkg/match"
+	"github.com/likeawizard/tofiks/pkg/texel"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPositions(t *testing.T) {
+	g := &match.Game{
+		Opening: match.Opening{FEN: board.StartPos, Moves: []string{"e2e4"}},
+		Moves:   []string{"d7d5", "e4d5", "d8d5", "b1c3"},
+		Scores:  []int{-20, 30, -10, 40},
+		Result:  "1-0",
+	}
+	positions, err := Positions(g)
+	assert.NoError(t, err)
+	// The captures e4d5 and d8d5 are left out.
+	assert.Len(t, positions, 2)
+	assert.Equal(t, int16(20), positions[0].Score)
+	assert.Equal(t, int16(40), positions[1].Score)
+	assert.Equal(t, 1.0, positions[0].Result)
+}
+
+func TestRunResume(t *testing.T) {
+	if testing.Short() {
+		t.Skip("plays games")
+	}
+	out := filepath.Join(t.TempDir(), "data")
+	cfg := Config{Games: 2, Workers: 2, Nodes: 300, Hash: 1, Seed: 7, RandomPlies: 8,
+		Adjudication: match.Adjudication{ResignMoveCount: 3, ResignScore: 500}, Packed: texel.FormatMarlin}
+	stats, err := Run(cfg, out, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, stats.Games)
+
+	cfg.Games = 3
+	stats, err = Run(cfg, out, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, stats.Games)
+
+	lines := 0
+	f, err := os.Open(out + ".epd")
+	assert.NoError(t, err)
+	defer f.Close()
+	for s := bufio.NewScanner(f); s.Scan(); {
+		sample, ok := texel.ParseSample(s.Text())
+		assert.True(t, ok && sample.HasScore, "unscored line %q", s.Text())
+		lines++
+	}
+	assert.Equal(t, stats.Positions, lines)
+	info, err := os.Stat(out + ".marlin")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(board.PackedSize*stats.Positions), info.Size())
+
+	cfg.Seed = 8
+	_, err = Run(cfg, out, nil)
+	assert.Error(t, err)
+}
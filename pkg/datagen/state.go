@@ -0,0 +1,106 @@
+package datagen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/likeawizard/tofiks/pkg/texel"
+)
+
+// state is the progress of a run as saved in its progress file.
+type state struct {
+	Seed    uint64 `json:"seed"`
+	Workers int    `json:"workers"`
+	Packed  string `json:"packed"`
+	// Games counts the games written per worker. Text and Binary are the
+	// sizes of the text and packed outputs after the last of them.
+	Games     []int `json:"games"`
+	Positions int   `json:"positions"`
+	Text      int64 `json:"text"`
+	Binary    int64 `json:"binary"`
+}
+
+func (st *state) total() int {
+	n := 0
+	for _, games := range st.Games {
+		n += games
+	}
+	return n
+}
+
+// openState loads the progress of an earlier run with the same seed and
+// workers, or starts a new run if there is none.
+func openState(out string, cfg Config) (*state, error) {
+	data, err := os.ReadFile(out + ".progress")
+	if errors.Is(err, fs.ErrNotExist) {
+		for _, ext := range []string{textFormat.Ext(), cfg.Packed.Ext()} {
+			if _, err := os.Stat(out + ext); err == nil {
+				return nil, fmt.Errorf("%s exists without a progress file", out+ext)
+			}
+		}
+		return &state{Seed: cfg.Seed, Workers: cfg.Workers, Packed: cfg.Packed.String(), Games: make([]int, cfg.Workers)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("%s.progress: %w", out, err)
+	}
+	if st.Seed != cfg.Seed || st.Workers != cfg.Workers || st.Packed != cfg.Packed.String() || len(st.Games) != st.Workers {
+		return nil, fmt.Errorf("%s.progress is of a run with seed %d, %d workers and %s output", out, st.Seed, st.Workers, st.Packed)
+	}
+	return &st, nil
+}
+
+// openOutputs opens the text and packed outputs for appending. Data written
+// after the last saved game of an interrupted run is cut off.
+func openOutputs(out string, st *state, packed texel.Format) ([2]*os.File, error) {
+	var files [2]*os.File
+	for i, f := range []struct {
+		ext  string
+		size int64
+	}{{textFormat.Ext(), st.Text}, {packed.Ext(), st.Binary}} {
+		file, err := os.OpenFile(out+f.ext, os.O_CREATE|os.O_WRONLY, 0o644)
+		if err == nil {
+			var info os.FileInfo
+			if info, err = file.Stat(); err == nil && info.Size() < f.size {
+				err = fmt.Errorf("%s is shorter than its progress file records", out+f.ext)
+			}
+			if err == nil {
+				err = file.Truncate(f.size)
+			}
+			if err == nil {
+				_, err = file.Seek(f.size, 0)
+			}
+			if err != nil {
+				file.Close()
+			}
+		}
+		if err != nil {
+			if i > 0 {
+				files[0].Close()
+			}
+			return files, err
+		}
+		files[i] = file
+	}
+	return files, nil
+}
+
+// save writes the progress file, replacing the old one only once the new one
+// is complete.
+func (st *state) save(out string) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	tmp := out + ".progress.tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, out+".progress")
+}
@@ -0,0 +1,89 @@
+// Package epd reads EPD test suites and runs the engine on them.
+package epd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+)
+
+// Position is one EPD record: a position and its operations. Operands are
+// stored without quotes.
+type Position struct {
+	Ops map[string][]string
+	FEN string
+}
+
+// ParseFile reads every record of an EPD file. Blank lines and lines starting
+// with # are skipped.
+func ParseFile(path string) ([]Position, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var positions []Position
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		p, err := Parse(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		positions = append(positions, p)
+	}
+	return positions, s.Err()
+}
+
+// Parse reads one EPD record.
+func Parse(line string) (Position, error) {
+	b, ops, err := board.ParseEPD(line)
+	if err != nil {
+		return Position{}, err
+	}
+	return Position{FEN: b.ExportFEN(), Ops: ops}, nil
+}
+
+// ID returns the id operand, or "" when missing.
+func (p *Position) ID() string {
+	if v := p.Ops["id"]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// MateIn returns the dm operand: the side to move mates in this many moves.
+func (p *Position) MateIn() int {
+	if v := p.Ops["dm"]; len(v) > 0 {
+		n, _ := strconv.Atoi(v[0])
+		return n
+	}
+	return 0
+}
+
+// Points parses STS-style move scores from c0, as in "f4=10, Be5=3". ok is
+// false when c0 holds no scores.
+func (p *Position) Points() (points map[string]int, ok bool) {
+	v := p.Ops["c0"]
+	if len(v) == 0 {
+		return nil, false
+	}
+	points = make(map[string]int)
+	for pair := range strings.SplitSeq(strings.Join(v, " "), ",") {
+		move, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		n, err := strconv.Atoi(value)
+		if !found || err != nil {
+			return nil, false
+		}
+		points[move] = n
+	}
+	return points, true
+}
@@ -0,0 +1,78 @@
+package epd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/likeawizard/tofiks/pkg/search"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		line string
+		fen  string
+		ops  map[string][]string
+	}{
+		{
+			line: `6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - bm Rd8#; id "back rank";`,
+			fen:  "6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 0 1",
+			ops:  map[string][]string{"bm": {"Rd8#"}, "id": {"back rank"}},
+		},
+		{
+			line: `6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 3 40 am Rd7 Kf1; c0 "say \"hi\"; bye";`,
+			fen:  "6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 3 40",
+			ops:  map[string][]string{"am": {"Rd7", "Kf1"}, "c0": {`say "hi"; bye`}},
+		},
+		{
+			line: "6k1/5ppp/8/8/8/8/5PPP/3R2K1 b - - hmvc 7; fmvn 21;",
+			fen:  "6k1/5ppp/8/8/8/8/5PPP/3R2K1 b - - 7 21",
+			ops:  map[string][]string{"hmvc": {"7"}, "fmvn": {"21"}},
+		},
+	}
+	for _, tt := range tests {
+		p, err := Parse(tt.line)
+		assert.NoError(t, err, tt.line)
+		assert.Equal(t, tt.fen, p.FEN)
+		assert.Equal(t, tt.ops, p.Ops)
+	}
+
+	for _, line := range []string{"8/8/8 w", `6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - id "open`} {
+		_, err := Parse(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func TestPoints(t *testing.T) {
+	p, err := Parse(`6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - bm Rd8#; c0 "Rd8=10, Rd7=3";`)
+	assert.NoError(t, err)
+	points, ok := p.Points()
+	assert.True(t, ok)
+	assert.Equal(t, map[string]int{"Rd8": 10, "Rd7": 3}, points)
+
+	p.Ops["c0"] = []string{"a comment"}
+	_, ok = p.Points()
+	assert.False(t, ok)
+}
+
+func TestRun(t *testing.T) {
+	var positions []Position
+	for _, line := range []string{
+		`6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - bm Rd8#; id "bm"; c0 "Rd8=10, Rd7=2";`,
+		`r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - dm 1; id "dm";`,
+		`6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - am Rd8#; id "am";`,
+	} {
+		p, err := Parse(line)
+		assert.NoError(t, err)
+		positions = append(positions, p)
+	}
+
+	summary := Run(search.NewEngine(), positions, Limits{Depth: 4}, nil)
+	assert.Equal(t, 3, summary.Total)
+	assert.Equal(t, 2, summary.Solved)
+	assert.Equal(t, 10, summary.Points)
+	assert.Equal(t, 10, summary.MaxPoints)
+	assert.Equal(t, "Rd8#", summary.Results[0].Move)
+	assert.Equal(t, "Qxf7#", summary.Results[1].Move)
+	assert.False(t, summary.Results[2].Solved)
+}
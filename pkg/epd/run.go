@@ -0,0 +1,165 @@
+package epd
+
+import (
+	"slices"
+	"time"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+	"github.com/likeawizard/tofiks/pkg/pgn"
+	"github.com/likeawizard/tofiks/pkg/search"
+)
+
+// Limits bound the search of every position. Zero values are unlimited, but
+// at least one should be set.
+type Limits struct {
+	Depth    int `json:"depth,omitempty"`
+	Nodes    int `json:"nodes,omitempty"`
+	MoveTime int `json:"movetimeMs,omitempty"`
+}
+
+// Result is the outcome of one position.
+type Result struct {
+	ID     string   `json:"id,omitempty"`
+	FEN    string   `json:"fen"`
+	Move   string   `json:"move"`
+	Best   []string `json:"bm,omitempty"`
+	Avoid  []string `json:"am,omitempty"`
+	MateIn int      `json:"dm,omitempty"`
+	Score  string   `json:"score"`
+	// SolvedMs is the time at which the engine settled on a solving move, if
+	// it kept one until the end.
+	SolvedMs  int64 `json:"solvedMs"`
+	Depth     int   `json:"depth"`
+	Nodes     int   `json:"nodes"`
+	Points    int   `json:"points"`
+	MaxPoints int   `json:"maxPoints"`
+	Solved    bool  `json:"solved"`
+}
+
+// Summary aggregates the results of a suite.
+type Summary struct {
+	Results   []Result `json:"results"`
+	Limits    Limits   `json:"limits"`
+	Total     int      `json:"total"`
+	Solved    int      `json:"solved"`
+	Points    int      `json:"points"`
+	MaxPoints int      `json:"maxPoints"`
+}
+
+// task is a position with its solution criteria resolved to moves.
+type task struct {
+	points map[board.Move]int
+	best   []board.Move
+	avoid  []board.Move
+	mateIn int
+}
+
+func newTask(b *board.Board, p *Position) task {
+	t := task{mateIn: p.MateIn()}
+	parse := func(tokens []string) []board.Move {
+		var moves []board.Move
+		for _, tok := range tokens {
+			if m, err := pgn.ParseMove(b, tok); err == nil {
+				moves = append(moves, m)
+			}
+		}
+		return moves
+	}
+	t.best = parse(p.Ops["bm"])
+	t.avoid = parse(p.Ops["am"])
+	if points, ok := p.Points(); ok {
+		t.points = make(map[board.Move]int)
+		for tok, n := range points {
+			if m, err := pgn.ParseMove(b, tok); err == nil {
+				t.points[m] = n
+			}
+		}
+	}
+	return t
+}
+
+// solves reports whether a move and score fulfil every criterion given.
+func (t *task) solves(move board.Move, score int16) bool {
+	if len(t.best) > 0 && !slices.Contains(t.best, move) {
+		return false
+	}
+	if slices.Contains(t.avoid, move) {
+		return false
+	}
+	if t.mateIn > 0 {
+		if dist, ok := search.MateDistance(score); !ok || dist <= 0 || dist > t.mateIn {
+			return false
+		}
+	}
+	return len(t.best) > 0 || len(t.avoid) > 0 || t.mateIn > 0
+}
+
+// Run searches every position with a fresh game state and scores the result.
+// progress, when set, is called after each position.
+func Run(e *search.Engine, positions []Position, limits Limits, progress func(Result)) Summary {
+	summary := Summary{Limits: limits, Total: len(positions)}
+	onInfo := e.OnInfo
+	defer func() { e.OnInfo = onInfo }()
+
+	for i := range positions {
+		r := runPosition(e, &positions[i], limits)
+		summary.Results = append(summary.Results, r)
+		if r.Solved {
+			summary.Solved++
+		}
+		summary.Points += r.Points
+		summary.MaxPoints += r.MaxPoints
+		if progress != nil {
+			progress(r)
+		}
+	}
+	return summary
+}
+
+func runPosition(e *search.Engine, p *Position, limits Limits) Result {
+	e.NewGame()
+	e.Board = board.NewBoard(p.FEN)
+	e.Ply = 0
+	t := newTask(e.Board, p)
+	r := Result{ID: p.ID(), FEN: p.FEN, Best: p.Ops["bm"], Avoid: p.Ops["am"], MateIn: t.mateIn}
+
+	var last search.Info
+	nodes := 0
+	solvedAt := time.Duration(-1)
+	e.OnInfo = func(info search.Info) {
+		last = info
+		nodes += info.Nodes
+		switch {
+		case len(info.PV) == 0 || !t.solves(info.PV[0], info.Score):
+			solvedAt = -1
+		case solvedAt < 0:
+			solvedAt = info.Time
+		}
+	}
+
+	e.Clock = search.Clock{Movetime: limits.MoveTime, Infinite: limits.MoveTime == 0}
+	e.TC = e.Clock.NewTimeControl(int(e.Board.FullMoveCounter), e.Board.Side)
+	e.NodeLimit = limits.Nodes
+	depth := limits.Depth
+	if depth == 0 {
+		depth = 100
+	}
+	move, _, _ := e.IDSearch(depth, true)
+	e.TC.Stop()
+	e.NodeLimit = 0
+
+	b := board.NewBoard(p.FEN)
+	r.Move = b.SAN(move)
+	r.Score = e.ConvertEvalToScore(last.Score)
+	r.Depth = last.Depth
+	r.Nodes = nodes
+	r.Solved = t.solves(move, last.Score)
+	if r.Solved && solvedAt >= 0 {
+		r.SolvedMs = solvedAt.Milliseconds()
+	}
+	for _, n := range t.points {
+		r.MaxPoints = max(r.MaxPoints, n)
+	}
+	r.Points = t.points[move]
+	return r
+}
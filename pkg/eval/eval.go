@@ -24,8 +24,8 @@ var (
 	// KnightPawnSlope and RookPawnSlope are L. Kaufman's piece-value adjustments
 	// Tuner persistently finds both opposite-sign from Kaufman's theory — likely
 	// a structural interaction with other eval terms rather than a data artifact.
-	KnightPawnSlope = -1
-	RookPawnSlope   = 2
+	KnightPawnSlope = S(-1, -1)
+	RookPawnSlope   = S(2, 2)
 
 	dist = [64]int{
 		4, 3, 3, 3, 3, 3, 3, 4,
@@ -39,34 +39,36 @@ var (
 	}
 )
 
+// Scalar terms are (middlegame, endgame) pairs blended by the game phase. The
+// king terms and passed-pawn king distances below apply to one stage only.
 var (
-	QueenMobility  = 2
-	RookMobility   = 3
-	BishopMobility = 7
-	KnightMobility = 0
-
-	QueenThreat  = 15
-	RookThreat   = 4
-	BishopThreat = 4
-	KnightThreat = 1
-
-	PawnProtected       = 18
-	PawnDoubled         = -16
-	PawnIsolated        = -10
-	PawnBackwardDeep    = -15
-	PawnBackwardMid     = -12
-	PawnBackwardOpen    = 8
-	PawnBlocked         = -5
-	PawnConnectedPasser = 8
-	PawnCandidate       = 8
-	PawnBreak           = 1
-
-	RookOpenFile     = 27
-	RookSemiOpenFile = 28
-
-	BishopPair = 21
-
-	BadBishop = -10
+	QueenMobility  = S(2, 2)
+	RookMobility   = S(3, 3)
+	BishopMobility = S(7, 7)
+	KnightMobility = S(0, 0)
+
+	QueenThreat  = S(15, 15)
+	RookThreat   = S(4, 4)
+	BishopThreat = S(4, 4)
+	KnightThreat = S(1, 1)
+
+	PawnProtected       = S(18, 18)
+	PawnDoubled         = S(-16, -16)
+	PawnIsolated        = S(-10, -10)
+	PawnBackwardDeep    = S(-15, -15)
+	PawnBackwardMid     = S(-12, -12)
+	PawnBackwardOpen    = S(8, 8)
+	PawnBlocked         = S(-5, -5)
+	PawnConnectedPasser = S(8, 8)
+	PawnCandidate       = S(8, 8)
+	PawnBreak           = S(1, 1)
+
+	RookOpenFile     = S(27, 27)
+	RookSemiOpenFile = S(28, 28)
+
+	BishopPair = S(21, 21)
+
+	BadBishop = S(-10, -10)
 
 	KingSafetyDistCenter = -13
 	KingSafetyPawnShield = 29
@@ -75,7 +77,7 @@ var (
 	KingActivityDistCenter  = -24
 	KingActivityDistSquares = -1
 
-	PassedPawnBonus = [8]int{0, -27, -37, -22, 14, 60, 205, 0}
+	PassedPawnBonus = [8]Score{S(0, 0), S(-27, -27), S(-37, -37), S(-22, -22), S(14, 14), S(60, 60), S(205, 205), S(0, 0)}
 
 	// PasserEnemyKingDist / PasserFriendlyKingDist scale rank × Manhattan
 	// distance to each king and are applied EG-only. Enemy-king-far is good,
@@ -84,14 +86,14 @@ var (
 	PasserFriendlyKingDist = -2
 
 	// Tempo is a flat bonus for the side to move.
-	Tempo = 29
+	Tempo = S(29, 29)
 
 	// Victim-aware threats.
-	ThreatPawnOnMinor  = 127
-	ThreatPawnOnMajor  = 96
-	ThreatMinorOnRook  = 76
-	ThreatMinorOnQueen = 13
-	ThreatRookOnQueen  = 57
+	ThreatPawnOnMinor  = S(127, 127)
+	ThreatPawnOnMajor  = S(96, 96)
+	ThreatMinorOnRook  = S(76, 76)
+	ThreatMinorOnQueen = S(13, 13)
+	ThreatRookOnQueen  = S(57, 57)
 )
 
 // Piece protected a pawn.
@@ -120,7 +122,7 @@ func (e *Eval) GetEvaluation(b *board.Board) int {
 	b.Phase = b.GetGamePhase()
 
 	// Pawn structure evaluation via hash table.
-	var pawnScore int16
+	var pawnScore Score
 	if cached, ok := e.PawnTable.Probe(b.PawnHash); ok {
 		pawnScore = cached
 	} else {
@@ -129,7 +131,8 @@ func (e *Eval) GetEvaluation(b *board.Board) int {
 	}
 
 	var (
-		eval     = int(pawnScore)
+		eval     int
+		score    = pawnScore
 		side     = -1
 		numPawns int
 		pieces   board.BBoard
@@ -148,23 +151,21 @@ func (e *Eval) GetEvaluation(b *board.Board) int {
 			pieces = b.Pieces[color][pieceType]
 			for pieces > 0 {
 				piece = pieces.PopLS1B()
-				var pieceEval int
 				switch pieceType {
 				case board.Pawns:
 					// Structure already evaluated via pawn table.
 				case board.Bishops:
-					pieceEval = bishopEval(b, piece, color, oppKing)
+					score += Score(side) * bishopEval(b, piece, color, oppKing)
 				case board.Knights:
-					pieceEval = knightEval(b, piece, color, oppKing, numPawns)
+					score += Score(side) * knightEval(b, piece, color, oppKing, numPawns)
 				case board.Rooks:
-					pieceEval = rookEval(b, piece, color, oppKing, numPawns)
+					score += Score(side) * rookEval(b, piece, color, oppKing, numPawns)
 				case board.Queens:
-					pieceEval = queenEval(b, piece, oppKing)
+					score += Score(side) * queenEval(b, piece, oppKing)
 				case board.Kings:
-					pieceEval = kingEval(b, piece, color, oppKing)
+					eval += side * kingEval(b, piece, color, oppKing)
 				}
 				eval += side * (PieceWeights[pieceType] +
-					pieceEval +
 					(PST[0][color][pieceType][piece]*(256-b.Phase)+
 						PST[1][color][pieceType][piece]*b.Phase)/256)
 			}
@@ -181,8 +182,8 @@ func (e *Eval) GetEvaluation(b *board.Board) int {
 		}
 		enemyMinors := b.Pieces[color^1][board.Knights] | b.Pieces[color^1][board.Bishops]
 		enemyMajors := b.Pieces[color^1][board.Rooks] | b.Pieces[color^1][board.Queens]
-		eval += side * ((pawnAttackBB&enemyMinors).Count()*ThreatPawnOnMinor +
-			(pawnAttackBB&enemyMajors).Count()*ThreatPawnOnMajor)
+		score += Score(side) * (Score((pawnAttackBB&enemyMinors).Count())*ThreatPawnOnMinor +
+			Score((pawnAttackBB&enemyMajors).Count())*ThreatPawnOnMajor)
 
 		// Passed-pawn king proximity (EG-only). Kept outside the pawn-hash
 		// cache because the score depends on king squares, not pawn structure.
@@ -201,20 +202,20 @@ func (e *Eval) GetEvaluation(b *board.Board) int {
 		}
 	}
 
-	eval += evaluatePawnBreaks(b)
+	score += evaluatePawnBreaks(b)
 
 	// Tempo bonus for side to move (from White's perspective).
 	if b.Side == board.White {
-		eval += Tempo
+		score += Tempo
 	} else {
-		eval -= Tempo
+		score -= Tempo
 	}
 
-	return eval
+	return eval + score.Taper(b.Phase)
 }
 
 // evaluatePawnBreaks counts pawn pushes whose destination is empty and attacks an enemy pawn.
-func evaluatePawnBreaks(b *board.Board) int {
+func evaluatePawnBreaks(b *board.Board) Score {
 	empty := ^b.Occupancy[board.Both]
 
 	wPawns := b.Pieces[board.White][board.Pawns]
@@ -231,7 +232,7 @@ func evaluatePawnBreaks(b *board.Board) int {
 	wBreaks := ((wSingle | wDouble) & wBreakTarget).Count()
 	bBreaks := ((bSingle | bDouble) & bBreakTarget).Count()
 
-	return (wBreaks - bBreaks) * PawnBreak
+	return Score(wBreaks-bBreaks) * PawnBreak
 }
 
 func DistCenter(sq int) int {
@@ -264,45 +265,48 @@ func getKingActivity(b *board.Board, king int, side int) (kingActivity int) {
 	return kingActivity
 }
 
-func knightEval(b *board.Board, sq int, side int, oppKing board.BBoard, numPawns int) int {
-	var eval int
+// Outposts are not tapered: they enter the Score as equal halves.
+func knightEval(b *board.Board, sq int, side int, oppKing board.BBoard, numPawns int) Score {
+	var eval Score
 	moves := board.KnightAttacks[sq] & ^b.Occupancy[side]
 	if board.Outposts[side][sq]&b.Pieces[side^1][board.Pawns] == 0 &&
 		board.PawnAttacks[side^1][sq]&b.Pieces[side][board.Pawns] != 0 {
-		eval = OutpostsScores[side][board.Knights][sq]
+		outpost := OutpostsScores[side][board.Knights][sq]
+		eval = S(outpost, outpost)
 	}
-	return eval + moves.Count()*KnightMobility +
-		(moves&oppKing).Count()*KnightThreat +
-		(moves&b.Pieces[side^1][board.Rooks]).Count()*ThreatMinorOnRook +
-		(moves&b.Pieces[side^1][board.Queens]).Count()*ThreatMinorOnQueen +
-		KnightPawnSlope*(numPawns-5)
+	return eval + Score(moves.Count())*KnightMobility +
+		Score((moves&oppKing).Count())*KnightThreat +
+		Score((moves&b.Pieces[side^1][board.Rooks]).Count())*ThreatMinorOnRook +
+		Score((moves&b.Pieces[side^1][board.Queens]).Count())*ThreatMinorOnQueen +
+		KnightPawnSlope*Score(numPawns-5)
 }
 
-func bishopEval(b *board.Board, sq int, side int, oppKing board.BBoard) int {
-	var eval int
+func bishopEval(b *board.Board, sq int, side int, oppKing board.BBoard) Score {
+	var eval Score
 	moves := board.GetBishopAttacks(sq, b.Occupancy[board.Both])
 
 	if board.Outposts[side][sq]&b.Pieces[side^1][board.Pawns] == 0 &&
 		board.PawnAttacks[side^1][sq]&b.Pieces[side][board.Pawns] != 0 {
-		eval = OutpostsScores[side][board.Bishops][sq]
+		outpost := OutpostsScores[side][board.Bishops][sq]
+		eval = S(outpost, outpost)
 	}
 	if b.Pieces[side][board.Bishops].Count() > 1 {
 		eval += BishopPair
 	}
-	eval += BadBishop * (b.Pieces[side][board.Pawns] & board.SquareColorMask[sq]).Count()
-	return eval + moves.Count()*BishopMobility +
-		(moves&oppKing).Count()*BishopThreat +
-		(moves&b.Pieces[side^1][board.Rooks]).Count()*ThreatMinorOnRook +
-		(moves&b.Pieces[side^1][board.Queens]).Count()*ThreatMinorOnQueen
+	eval += BadBishop * Score((b.Pieces[side][board.Pawns] & board.SquareColorMask[sq]).Count())
+	return eval + Score(moves.Count())*BishopMobility +
+		Score((moves&oppKing).Count())*BishopThreat +
+		Score((moves&b.Pieces[side^1][board.Rooks]).Count())*ThreatMinorOnRook +
+		Score((moves&b.Pieces[side^1][board.Queens]).Count())*ThreatMinorOnQueen
 }
 
 // Evaluation for rooks - mobility, captures, king threats, and (semi)open files.
-func rookEval(b *board.Board, sq int, side int, oppKing board.BBoard, numPawns int) int {
+func rookEval(b *board.Board, sq int, side int, oppKing board.BBoard, numPawns int) Score {
 	moves := board.GetRookAttacks(sq, b.Occupancy[board.Both])
-	eval := moves.Count()*RookMobility +
-		(moves&oppKing).Count()*RookThreat +
-		(moves&b.Pieces[side^1][board.Queens]).Count()*ThreatRookOnQueen +
-		RookPawnSlope*(numPawns-5)
+	eval := Score(moves.Count())*RookMobility +
+		Score((moves&oppKing).Count())*RookThreat +
+		Score((moves&b.Pieces[side^1][board.Queens]).Count())*ThreatRookOnQueen +
+		RookPawnSlope*Score(numPawns-5)
 
 	file := board.FileMasks[sq%8]
 	if file&b.Pieces[side][board.Pawns] == 0 {
@@ -316,10 +320,10 @@ func rookEval(b *board.Board, sq int, side int, oppKing board.BBoard, numPawns i
 	return eval
 }
 
-func queenEval(b *board.Board, sq int, oppKing board.BBoard) int {
+func queenEval(b *board.Board, sq int, oppKing board.BBoard) Score {
 	moves := board.GetQueenAttacks(sq, b.Occupancy[board.Both])
-	return moves.Count()*QueenMobility +
-		(moves&oppKing).Count()*QueenThreat
+	return Score(moves.Count())*QueenMobility +
+		Score((moves&oppKing).Count())*QueenThreat
 }
 
 func kingEval(b *board.Board, king int, side int, _ board.BBoard) int {
@@ -0,0 +1,138 @@
+package eval
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ParamsVersion is the version of the parameter file format.
+const ParamsVersion = 1
+
+// ParamFile is a set of evaluation weights as stored in a JSON parameter file.
+// Each entry holds the tuned values of one of the Params, as Param.Get returns
+// them; the halves of a Score are listed middlegame first.
+type ParamFile struct {
+	Version int          `json:"version"`
+	Params  []ParamValue `json:"params"`
+}
+
+// ParamValue holds the tuned values of the parameter Name. An entry without
+// values leaves them as they are.
+//
+// The other fields constrain further tuning by cmd/texel and are ignored by
+// the engine: Freeze keeps the values, Min and Max bound them, and Monotone 1
+// (-1) keeps the values of an array from decreasing (increasing).
+type ParamValue struct {
+	Name     string `json:"name"`
+	Values   []int  `json:"values,omitempty"`
+	Freeze   bool   `json:"freeze,omitempty"`
+	Min      *int   `json:"min,omitempty"`
+	Max      *int   `json:"max,omitempty"`
+	Monotone int    `json:"monotone,omitempty"`
+}
+
+// CurrentParams returns the weights the evaluation uses now.
+func CurrentParams() *ParamFile {
+	f := &ParamFile{Version: ParamsVersion}
+	for _, p := range Params {
+		pv := ParamValue{Name: p.Name, Values: make([]int, p.Size)}
+		for i := range p.Size {
+			pv.Values[i] = p.Get(i)
+		}
+		f.Params = append(f.Params, pv)
+	}
+	return f
+}
+
+// Apply sets the weights of the file. Params the file leaves out or lists
+// without values keep their values. Nothing is set unless every entry names
+// a known parameter and holds the right number of values within its bounds.
+func (f *ParamFile) Apply() error {
+	if f.Version != ParamsVersion {
+		return fmt.Errorf("parameter file version %d, want %d", f.Version, ParamsVersion)
+	}
+	for _, pv := range f.Params {
+		p := ParamByName(pv.Name)
+		if p == nil {
+			return fmt.Errorf("unknown parameter %s", pv.Name)
+		}
+		if pv.Values != nil && len(pv.Values) != p.Size {
+			return fmt.Errorf("%s has %d values, want %d", pv.Name, len(pv.Values), p.Size)
+		}
+		for i, v := range pv.Values {
+			if v < p.Min || v > p.Max {
+				return fmt.Errorf("%s[%d] = %d is not within [%d, %d]", pv.Name, i, v, p.Min, p.Max)
+			}
+		}
+	}
+	for _, pv := range f.Params {
+		p := ParamByName(pv.Name)
+		for i, v := range pv.Values {
+			p.Set(i, v)
+		}
+	}
+	InitPSTs()
+	return nil
+}
+
+// Write writes the file as JSON with one parameter per line.
+func (f *ParamFile) Write(w io.Writer) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "{\n  \"version\": %d,\n  \"params\": [\n", f.Version)
+	for i, pv := range f.Params {
+		line, err := json.Marshal(pv)
+		if err != nil {
+			return err
+		}
+		buf.WriteString("    ")
+		buf.Write(line)
+		if i < len(f.Params)-1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("  ]\n}\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// ReadParamFile reads a parameter file.
+func ReadParamFile(r io.Reader) (*ParamFile, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var f ParamFile
+	if err := dec.Decode(&f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// LoadParams applies the parameter file at path.
+func LoadParams(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	f, err := ReadParamFile(file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := f.Apply(); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// ResetParams restores the weights the engine was built with.
+func ResetParams() {
+	for _, p := range Params {
+		for i, v := range p.Default {
+			p.Set(i, v)
+		}
+	}
+	InitPSTs()
+}
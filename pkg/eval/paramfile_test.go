@@ -0,0 +1,60 @@
+package eval
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestParamFileRoundTrip(t *testing.T) {
+	f := CurrentParams()
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	g, err := ReadParamFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(f, g) {
+		t.Errorf("read back %+v, want %+v", g, f)
+	}
+}
+
+func TestParamFileApply(t *testing.T) {
+	defer ResetParams()
+
+	f := &ParamFile{Version: ParamsVersion, Params: []ParamValue{
+		{Name: "BishopPair", Values: []int{30, 50}},
+		{Name: "PassedPawnBonus", Values: []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}},
+		{Name: "Tempo", Freeze: true},
+	}}
+	if err := f.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if BishopPair != S(30, 50) || PassedPawnBonus[1] != S(1, 2) || PassedPawnBonus[6] != S(11, 12) || PassedPawnBonus[7] != S(0, 0) {
+		t.Errorf("BishopPair = (%d, %d), PassedPawnBonus = %v", BishopPair.MG(), BishopPair.EG(), PassedPawnBonus)
+	}
+	if Tempo != S(29, 29) {
+		t.Errorf("Tempo = (%d, %d) after an entry without values", Tempo.MG(), Tempo.EG())
+	}
+
+	for _, bad := range []*ParamFile{
+		{Version: ParamsVersion + 1},
+		{Version: ParamsVersion, Params: []ParamValue{{Name: "Tempo", Values: []int{1, 1}}, {Name: "NoSuchParam", Values: []int{1}}}},
+		{Version: ParamsVersion, Params: []ParamValue{{Name: "Tempo", Values: []int{1}}}},
+		{Version: ParamsVersion, Params: []ParamValue{{Name: "Tempo", Values: []int{1, 99999}}}},
+	} {
+		if err := bad.Apply(); err == nil {
+			t.Errorf("Apply(%+v) succeeded", bad)
+		}
+	}
+	if Tempo != S(29, 29) {
+		t.Errorf("Tempo = (%d, %d) after failed applies", Tempo.MG(), Tempo.EG())
+	}
+
+	ResetParams()
+	if BishopPair != S(21, 21) {
+		t.Errorf("BishopPair = (%d, %d) after reset", BishopPair.MG(), BishopPair.EG())
+	}
+}
@@ -0,0 +1,225 @@
+package eval
+
+import "github.com/likeawizard/tofiks/pkg/board"
+
+// Taper tells in which stage of the game a parameter applies.
+type Taper int
+
+const (
+	// Untapered parameters apply in full at every phase.
+	Untapered Taper = iota
+	// Middlegame parameters are scaled by 256-phase.
+	Middlegame
+	// Endgame parameters are scaled by phase.
+	Endgame
+	// Tapered parameters are Scores: each value is a middlegame and an
+	// endgame weight, in that order.
+	Tapered
+)
+
+// paramBound is the default limit of a tuned value in either direction.
+const paramBound = 4000
+
+// Param describes a tunable evaluation variable. The values of all Params
+// form a flat weight vector in registry order: a Param holds the values at
+// Offset through Offset+Size-1. Arrays may hold values that are not tuned,
+// such as the king weight; only the Size values from element From on are.
+// The elements of a Tapered variable are the halves of its Scores.
+type Param struct {
+	// Name is the Go variable holding the values.
+	Name string
+	// Group is the evaluation concept the parameter belongs to.
+	Group string
+	Taper Taper
+	// Dims are the dimensions of the variable, nil for a scalar.
+	Dims []int
+	From int
+	Size int
+	// Min and Max bound each tuned value.
+	Min, Max int
+	// Default holds the tuned values the engine was built with.
+	Default []int
+	Offset  int
+
+	elements []*int
+	scores   []*Score
+}
+
+// Get returns the i-th tuned value.
+func (p *Param) Get(i int) int {
+	return p.element(p.From + i)
+}
+
+func (p *Param) element(e int) int {
+	if p.scores == nil {
+		return *p.elements[e]
+	}
+	s := *p.scores[e/2]
+	if e%2 == 0 {
+		return s.MG()
+	}
+	return s.EG()
+}
+
+// Set sets the i-th tuned value. Call InitPSTs after setting tables so the
+// black side is rebuilt.
+func (p *Param) Set(i, v int) {
+	e := p.From + i
+	if p.scores == nil {
+		*p.elements[e] = v
+		return
+	}
+	s := p.scores[e/2]
+	if e%2 == 0 {
+		*s = S(v, s.EG())
+	} else {
+		*s = S(s.MG(), v)
+	}
+}
+
+// Elements returns every element of the variable, tuned or not.
+func (p *Param) Elements() []int {
+	values := make([]int, len(p.elements)+2*len(p.scores))
+	for e := range values {
+		values[e] = p.element(e)
+	}
+	return values
+}
+
+func (p *Param) bounds(lo, hi int) *Param {
+	p.Min, p.Max = lo, hi
+	return p
+}
+
+func scalar(name, group string, taper Taper, v *int) *Param {
+	return &Param{Name: name, Group: group, Taper: taper, Size: 1, elements: []*int{v}}
+}
+
+func score(name, group string, s *Score) *Param {
+	return &Param{Name: name, Group: group, Taper: Tapered, Size: 2, scores: []*Score{s}}
+}
+
+// scores registers the Scores from through to-1 of values as tuned.
+func scores(name, group string, values []Score, from, to int) *Param {
+	p := &Param{Name: name, Group: group, Taper: Tapered, Dims: []int{len(values)}, From: 2 * from, Size: 2 * (to - from)}
+	for i := range values {
+		p.scores = append(p.scores, &values[i])
+	}
+	return p
+}
+
+// array registers the elements from through to-1 of values as tuned.
+func array(name, group string, taper Taper, values []int, from, to int) *Param {
+	p := &Param{Name: name, Group: group, Taper: taper, Dims: []int{len(values)}, From: from, Size: to - from}
+	for i := range values {
+		p.elements = append(p.elements, &values[i])
+	}
+	return p
+}
+
+// Params is the registry of tunable evaluation parameters.
+var Params = registerParams()
+
+var paramsByName = func() map[string]*Param {
+	m := make(map[string]*Param, len(Params))
+	for _, p := range Params {
+		m[p.Name] = p
+	}
+	return m
+}()
+
+// ParamByName returns the parameter of a Go variable, or nil.
+func ParamByName(name string) *Param {
+	return paramsByName[name]
+}
+
+// NumWeights returns the length of the weight vector of all Params.
+func NumWeights() int {
+	last := Params[len(Params)-1]
+	return last.Offset + last.Size
+}
+
+func registerParams() []*Param {
+	var params []*Param
+	pieceNames := [6]string{"pawn", "bishop", "knight", "rook", "queen", "king"}
+	for stage, suffix := range [2]string{"PST", "EGPST"} {
+		taper := Middlegame + Taper(stage)
+		for piece := board.Pawns; piece <= board.Kings; piece++ {
+			params = append(params, array(pieceNames[piece]+suffix, "PST", taper, PST[stage][board.White][piece][:], 0, 64))
+		}
+	}
+
+	params = append(params,
+		array("PieceWeights", "Material", Untapered, PieceWeights[:], board.Pawns, board.Kings).bounds(0, paramBound),
+
+		score("QueenMobility", "Mobility", &QueenMobility),
+		score("RookMobility", "Mobility", &RookMobility),
+		score("BishopMobility", "Mobility", &BishopMobility),
+		score("KnightMobility", "Mobility", &KnightMobility),
+
+		score("QueenThreat", "King attack", &QueenThreat),
+		score("RookThreat", "King attack", &RookThreat),
+		score("BishopThreat", "King attack", &BishopThreat),
+		score("KnightThreat", "King attack", &KnightThreat),
+
+		score("PawnProtected", "Pawn structure", &PawnProtected),
+		score("PawnDoubled", "Pawn structure", &PawnDoubled),
+		score("PawnIsolated", "Pawn structure", &PawnIsolated),
+		score("PawnBackwardDeep", "Pawn structure", &PawnBackwardDeep),
+		score("PawnBackwardMid", "Pawn structure", &PawnBackwardMid),
+		score("PawnBackwardOpen", "Pawn structure", &PawnBackwardOpen),
+		score("PawnBlocked", "Pawn structure", &PawnBlocked),
+		score("PawnConnectedPasser", "Pawn structure", &PawnConnectedPasser),
+		score("PawnCandidate", "Pawn structure", &PawnCandidate),
+
+		// Passed pawns never stand on the first or last rank.
+		scores("PassedPawnBonus", "Passed pawns", PassedPawnBonus[:], 1, 7),
+
+		score("RookOpenFile", "Rook files", &RookOpenFile),
+		score("RookSemiOpenFile", "Rook files", &RookSemiOpenFile),
+
+		score("BishopPair", "Bishop pair", &BishopPair),
+
+		scalar("KingSafetyDistCenter", "King safety", Middlegame, &KingSafetyDistCenter),
+		scalar("KingSafetyPawnShield", "King safety", Middlegame, &KingSafetyPawnShield),
+		scalar("KingSafetyFriendly", "King safety", Middlegame, &KingSafetyFriendly),
+
+		scalar("KingActivityDistCenter", "King activity", Endgame, &KingActivityDistCenter),
+		scalar("KingActivityDistSquares", "King activity", Endgame, &KingActivityDistSquares),
+
+		array("knightOutposts", "Outposts", Untapered, knightOutposts[:], 0, 64),
+		array("bishopOutposts", "Outposts", Untapered, bishopOutposts[:], 0, 64),
+
+		score("KnightPawnSlope", "Pawn slopes", &KnightPawnSlope),
+		score("RookPawnSlope", "Pawn slopes", &RookPawnSlope),
+
+		scalar("PasserEnemyKingDist", "Passer king dist", Endgame, &PasserEnemyKingDist),
+		scalar("PasserFriendlyKingDist", "Passer king dist", Endgame, &PasserFriendlyKingDist),
+
+		score("Tempo", "Tempo", &Tempo),
+
+		score("ThreatPawnOnMinor", "Threats", &ThreatPawnOnMinor),
+		score("ThreatPawnOnMajor", "Threats", &ThreatPawnOnMajor),
+		score("ThreatMinorOnRook", "Threats", &ThreatMinorOnRook),
+		score("ThreatMinorOnQueen", "Threats", &ThreatMinorOnQueen),
+		score("ThreatRookOnQueen", "Threats", &ThreatRookOnQueen),
+
+		score("BadBishop", "Bad bishop", &BadBishop),
+
+		score("PawnBreak", "Pawn breaks", &PawnBreak),
+	)
+
+	offset := 0
+	for _, p := range params {
+		if p.Min == 0 && p.Max == 0 {
+			p.bounds(-paramBound, paramBound)
+		}
+		p.Offset = offset
+		offset += p.Size
+		p.Default = make([]int, p.Size)
+		for i := range p.Size {
+			p.Default[i] = p.Get(i)
+		}
+	}
+	return params
+}
@@ -5,7 +5,7 @@ import "github.com/likeawizard/tofiks/pkg/board"
 // PawnEntry stores cached pawn structure evaluation.
 type PawnEntry struct {
 	key   uint64
-	score int16
+	score Score
 }
 
 // PawnTable is a fixed-size hash table for pawn structure evaluation.
@@ -31,7 +31,7 @@ func NewPawnTable() *PawnTable {
 	}
 }
 
-func (pt *PawnTable) Probe(key uint64) (int16, bool) {
+func (pt *PawnTable) Probe(key uint64) (Score, bool) {
 	pt.Stats.recordProbe()
 	e := &pt.entries[key&pt.mask]
 	if e.key == key {
@@ -41,7 +41,7 @@ func (pt *PawnTable) Probe(key uint64) (int16, bool) {
 	return 0, false
 }
 
-func (pt *PawnTable) Store(key uint64, score int16) {
+func (pt *PawnTable) Store(key uint64, score Score) {
 	e := &pt.entries[key&pt.mask]
 	e.key = key
 	e.score = score
@@ -54,8 +54,8 @@ func (pt *PawnTable) Clear() {
 }
 
 // evaluatePawns computes the full pawn structure score for both sides.
-func evaluatePawns(b *board.Board) int16 {
-	var score int
+func evaluatePawns(b *board.Board) Score {
+	var score Score
 	for color := board.White; color <= board.Black; color++ {
 		side := 1
 		if color == board.Black {
@@ -70,7 +70,7 @@ func evaluatePawns(b *board.Board) int16 {
 			sq := board.Square(piece)
 			s := int(sq)
 			file := s % 8
-			var value int
+			var value Score
 
 			if IsProtected(b, sq, color) {
 				value = PawnProtected
@@ -156,8 +156,8 @@ func evaluatePawns(b *board.Board) int16 {
 				}
 			}
 
-			score += side * value
+			score += Score(side) * value
 		}
 	}
-	return int16(score)
+	return score
 }
@@ -0,0 +1,28 @@
+package eval
+
+// Score packs a middlegame and an endgame value into one int64, the endgame
+// value in the upper half. Scores add and multiply by ints like plain values,
+// so both halves of a term are summed at once and blended by the game phase
+// only at the end of the evaluation.
+type Score int64
+
+// S returns the Score of a middlegame and an endgame value.
+func S(mg, eg int) Score {
+	return Score(int64(eg)<<32 + int64(mg))
+}
+
+// MG returns the middlegame value.
+func (s Score) MG() int {
+	return int(int32(uint32(s)))
+}
+
+// EG returns the endgame value.
+func (s Score) EG() int {
+	return int(int32(uint64(s+1<<31) >> 32))
+}
+
+// Taper blends the two values by phase, from 0 (middlegame) to 256
+// (endgame).
+func (s Score) Taper(phase int) int {
+	return (s.MG()*(256-phase) + s.EG()*phase) / 256
+}
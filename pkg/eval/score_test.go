@@ -0,0 +1,21 @@
+package eval
+
+import "testing"
+
+func TestScore(t *testing.T) {
+	for _, tt := range []struct{ mg, eg int }{{0, 0}, {1, -1}, {-27, 205}, {-40000, -3}, {123456, -654321}} {
+		s := S(tt.mg, tt.eg)
+		if s.MG() != tt.mg || s.EG() != tt.eg {
+			t.Errorf("S(%d, %d) = (%d, %d)", tt.mg, tt.eg, s.MG(), s.EG())
+		}
+		if sum := s*3 - S(1, 1); sum.MG() != 3*tt.mg-1 || sum.EG() != 3*tt.eg-1 {
+			t.Errorf("3*S(%d, %d)-S(1, 1) = (%d, %d)", tt.mg, tt.eg, sum.MG(), sum.EG())
+		}
+	}
+	if got := S(100, 200).Taper(64); got != 125 {
+		t.Errorf("Taper = %d, want 125", got)
+	}
+	if got := S(-7, -7).Taper(100); got != -7 {
+		t.Errorf("Taper of equal halves = %d, want -7", got)
+	}
+}
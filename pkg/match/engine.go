@@ -0,0 +1,91 @@
+package match
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+	"github.com/likeawizard/tofiks/pkg/search"
+)
+
+// maxDepth bounds the depth of in-process searches, which end on the clock or
+// the node limit.
+const maxDepth = 100
+
+// EnginePlayer plays with an in-process search engine.
+type EnginePlayer struct {
+	e    *search.Engine
+	name string
+}
+
+// NewEnginePlayer returns a player with a new engine. configure, when set,
+// sets up the engine, for example from a config file.
+func NewEnginePlayer(name string, configure func(*search.Engine) error) (*EnginePlayer, error) {
+	e := search.NewEngine()
+	if configure != nil {
+		if err := configure(e); err != nil {
+			return nil, err
+		}
+	}
+	return &EnginePlayer{e: e, name: name}, nil
+}
+
+func (p *EnginePlayer) Name() string {
+	return p.name
+}
+
+// Engine returns the engine of the player.
+func (p *EnginePlayer) Engine() *search.Engine {
+	return p.e
+}
+
+func (p *EnginePlayer) NewGame() error {
+	p.e.NewGame()
+	return nil
+}
+
+func (p *EnginePlayer) Go(req *Request) (Reply, error) {
+	e := p.e
+	e.Board = &board.Board{}
+	fen := req.FEN
+	if fen == "" || fen == board.StartPos {
+		fen = board.StartingFEN
+	}
+	if err := e.Board.ImportFEN(fen); err != nil {
+		return Reply{}, err
+	}
+	if !e.PlayMovesUCI(strings.Join(req.Moves, " ")) {
+		return Reply{}, errors.New("illegal move in game")
+	}
+
+	var score int16
+	e.OnInfo = func(info search.Info) { score = info.Score }
+	e.Clock.Wtime = int(req.WTime.Milliseconds())
+	e.Clock.Btime = int(req.BTime.Milliseconds())
+	e.Clock.Winc = int(req.WInc.Milliseconds())
+	e.Clock.Binc = int(req.BInc.Milliseconds())
+	e.Clock.Movestogo, e.Clock.Movetime = 0, 0
+	e.Clock.Infinite = req.Nodes > 0
+	e.Analysis = false
+	e.NodeLimit = req.Nodes
+	e.TC = e.Clock.NewTimeControl(int(e.Board.FullMoveCounter), e.Board.Side)
+	move, _ := e.FindMove(maxDepth, false)
+	e.TC.Stop()
+
+	return Reply{Move: move.String(), Score: replyScore(score)}, nil
+}
+
+// replyScore converts an engine score to the Reply scale.
+func replyScore(eval int16) int {
+	if dist, ok := search.MateDistance(eval); ok {
+		if dist < 0 {
+			return -MateScore - dist
+		}
+		return MateScore - dist
+	}
+	return int(eval)
+}
+
+func (p *EnginePlayer) Close() error {
+	return nil
+}
@@ -0,0 +1,269 @@
+package match
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+	"github.com/likeawizard/tofiks/pkg/pgn"
+)
+
+// PGN Termination values.
+const (
+	TerminationNormal      = "normal"
+	TerminationAdjudicated = "adjudication"
+	TerminationTime        = "time forfeit"
+	TerminationIllegal     = "rules infraction"
+	TerminationAbandoned   = "abandoned"
+)
+
+// TimeControl is the clock of a game, or a node limit per move when Nodes is
+// set.
+type TimeControl struct {
+	Base, Inc time.Duration
+	Nodes     int
+	// Margin is how far an engine may overrun its clock before it loses on
+	// time.
+	Margin time.Duration
+}
+
+// String formats the time control as the PGN TimeControl tag, in seconds.
+func (tc TimeControl) String() string {
+	if tc.Nodes > 0 {
+		return "-"
+	}
+	return strconv.FormatFloat(tc.Base.Seconds(), 'f', -1, 64) + "+" + strconv.FormatFloat(tc.Inc.Seconds(), 'f', -1, 64)
+}
+
+// Adjudication ends games that are decided early. Zero counts disable a rule.
+type Adjudication struct {
+	// From move DrawMoveNumber on a game is drawn once both engines scored
+	// within DrawScore of zero for their last DrawMoveCount moves.
+	DrawMoveNumber int
+	DrawMoveCount  int
+	DrawScore      int
+	// An engine resigns once it scored -ResignScore or worse for its last
+	// ResignMoveCount moves.
+	ResignMoveCount int
+	ResignScore     int
+}
+
+// Opening is the start of a game: a position and moves in UCI notation played
+// from it.
+type Opening struct {
+	FEN   string
+	Moves []string
+}
+
+// Game is a finished game.
+type Game struct {
+	White, Black string
+	Opening      Opening
+	// Moves are the moves the engines played after the opening, Scores the
+	// score each move was played with from the point of view of its side.
+	Moves  []string
+	Scores []int
+	Result string
+	// Termination is the PGN Termination tag and Reason a description of how
+	// the game ended, such as "White mates".
+	Termination string
+	Reason      string
+}
+
+// Score returns the result from white's point of view: 1, 0.5 or 0.
+func (g *Game) Score() float64 {
+	switch g.Result {
+	case "1-0":
+		return 1
+	case "0-1":
+		return 0
+	}
+	return 0.5
+}
+
+var sideNames = [2]string{"White", "Black"}
+
+// Play plays a game from an opening. Engine errors and illegal moves lose the
+// game. An error is returned only for an invalid opening.
+func Play(white, black Player, opening Opening, tc TimeControl, adj Adjudication) (*Game, error) {
+	b, err := openingBoard(opening)
+	if err != nil {
+		return nil, err
+	}
+	g := &Game{White: white.Name(), Black: black.Name(), Opening: opening}
+	seen := make(map[uint64]int)
+	seen[b.Hash]++
+
+	players := [2]Player{white, black}
+	for side, p := range players {
+		if err := p.NewGame(); err != nil {
+			g.end(loss(int8(side)), TerminationAbandoned, fmt.Sprintf("%s fails to start: %v", sideNames[side], err))
+			return g, nil
+		}
+	}
+
+	clocks := [2]time.Duration{tc.Base, tc.Base}
+	req := &Request{FEN: opening.FEN, Nodes: tc.Nodes, WInc: tc.Inc, BInc: tc.Inc}
+	req.Moves = append(req.Moves, opening.Moves...)
+	for !g.over(b, seen, adj) {
+		side := b.Side
+		req.WTime, req.BTime = max(clocks[board.White], time.Millisecond), max(clocks[board.Black], time.Millisecond)
+
+		start := time.Now()
+		reply, err := players[side].Go(req)
+		elapsed := time.Since(start)
+		if tc.Nodes == 0 {
+			clocks[side] -= elapsed
+			if clocks[side] < -tc.Margin || errors.Is(err, ErrTimeout) {
+				g.end(loss(side), TerminationTime, sideNames[side]+" loses on time")
+				return g, nil
+			}
+			clocks[side] += tc.Inc
+		}
+		if err != nil {
+			g.end(loss(side), TerminationAbandoned, fmt.Sprintf("%s disconnects: %v", sideNames[side], err))
+			return g, nil
+		}
+
+		move, ok := legalMove(b, reply.Move)
+		if !ok {
+			g.end(loss(side), TerminationIllegal, fmt.Sprintf("%s makes an illegal move: %s", sideNames[side], reply.Move))
+			return g, nil
+		}
+		b.MakeMove(move)
+		seen[b.Hash]++
+		req.Moves = append(req.Moves, reply.Move)
+		g.Moves = append(g.Moves, reply.Move)
+		g.Scores = append(g.Scores, reply.Score)
+	}
+	return g, nil
+}
+
+// openingBoard returns the position after an opening.
+func openingBoard(opening Opening) (*board.Board, error) {
+	b := &board.Board{}
+	fen := opening.FEN
+	if fen == "" || fen == board.StartPos {
+		fen = board.StartingFEN
+	}
+	if err := b.ImportFEN(fen); err != nil {
+		return nil, err
+	}
+	for _, uci := range opening.Moves {
+		move, ok := legalMove(b, uci)
+		if !ok {
+			return nil, fmt.Errorf("illegal opening move %s in %s", uci, fen)
+		}
+		b.MakeMove(move)
+	}
+	return b, nil
+}
+
+func legalMove(b *board.Board, uci string) (board.Move, bool) {
+	for _, m := range b.LegalMoves() {
+		if m.String() == uci {
+			return m, true
+		}
+	}
+	return 0, false
+}
+
+// loss returns the result of a game lost by side.
+func loss(side int8) string {
+	if side == board.White {
+		return "0-1"
+	}
+	return "1-0"
+}
+
+func (g *Game) end(result, termination, reason string) {
+	g.Result, g.Termination, g.Reason = result, termination, reason
+}
+
+// over reports whether the game ended by the rules or by adjudication and
+// sets the result if it did.
+func (g *Game) over(b *board.Board, seen map[uint64]int, adj Adjudication) bool {
+	switch {
+	case len(b.LegalMoves()) == 0 && b.InCheck:
+		g.end(loss(b.Side), TerminationNormal, sideNames[b.Side^1]+" mates")
+	case len(b.LegalMoves()) == 0:
+		g.end("1/2-1/2", TerminationNormal, "Draw by stalemate")
+	case seen[b.Hash] >= 3:
+		g.end("1/2-1/2", TerminationNormal, "Draw by threefold repetition")
+	case b.HalfMoveCounter >= 100:
+		g.end("1/2-1/2", TerminationNormal, "Draw by fifty-move rule")
+	case b.InsufficientMaterial():
+		g.end("1/2-1/2", TerminationNormal, "Draw by insufficient material")
+	case g.resigns(adj):
+		// The side that just moved resigns.
+		g.end(loss(b.Side^1), TerminationAdjudicated, sideNames[b.Side^1]+" resigns")
+	case g.drawn(adj, int(b.FullMoveCounter)):
+		g.end("1/2-1/2", TerminationAdjudicated, "Draw by adjudication")
+	default:
+		return false
+	}
+	return true
+}
+
+// resigns reports whether the engine that played the last move scored
+// -ResignScore or worse with each of its last ResignMoveCount moves.
+func (g *Game) resigns(adj Adjudication) bool {
+	n := adj.ResignMoveCount
+	if n == 0 || len(g.Scores) < 2*n-1 {
+		return false
+	}
+	for i := len(g.Scores) - 1; i >= len(g.Scores)-2*n+1; i -= 2 {
+		if g.Scores[i] > -adj.ResignScore {
+			return false
+		}
+	}
+	return true
+}
+
+// drawn reports whether both engines scored within DrawScore of zero for
+// their last DrawMoveCount moves from move DrawMoveNumber on.
+func (g *Game) drawn(adj Adjudication, moveNumber int) bool {
+	n := 2 * adj.DrawMoveCount
+	if n == 0 || moveNumber < adj.DrawMoveNumber || len(g.Scores) < n {
+		return false
+	}
+	for _, score := range g.Scores[len(g.Scores)-n:] {
+		if score > adj.DrawScore || score < -adj.DrawScore {
+			return false
+		}
+	}
+	return true
+}
+
+// PGN returns the game for writing, with the moves in SAN and the White,
+// Black, Result, Termination and PlyCount tags set.
+func (g *Game) PGN() *pgn.Game {
+	b, _ := openingBoard(Opening{FEN: g.Opening.FEN})
+	var moves []string
+	for _, uci := range append(append([]string{}, g.Opening.Moves...), g.Moves...) {
+		move, ok := legalMove(b, uci)
+		if !ok {
+			break
+		}
+		moves = append(moves, b.SAN(move))
+		b.MakeMove(move)
+	}
+	startFEN := g.Opening.FEN
+	if startFEN == "" {
+		startFEN = board.StartPos
+	}
+	return &pgn.Game{
+		Headers: map[string]string{
+			"White":       g.White,
+			"Black":       g.Black,
+			"Result":      g.Result,
+			"Termination": g.Termination,
+			"PlyCount":    strconv.Itoa(len(moves)),
+		},
+		StartFEN: startFEN,
+		Result:   g.Result,
+		Moves:    moves,
+	}
+}
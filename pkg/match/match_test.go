@@ -0,0 +1,130 @@
+package match
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// scripted plays fixed moves with fixed scores.
+type scripted struct {
+	name   string
+	moves  []string
+	scores []int
+	n      int
+}
+
+func (p *scripted) Name() string   { return p.name }
+func (p *scripted) NewGame() error { p.n = 0; return nil }
+func (p *scripted) Close() error   { return nil }
+
+func (p *scripted) Go(_ *Request) (Reply, error) {
+	r := Reply{Move: p.moves[p.n]}
+	if p.n < len(p.scores) {
+		r.Score = p.scores[p.n]
+	}
+	p.n++
+	return r, nil
+}
+
+func TestPlayRules(t *testing.T) {
+	tests := []struct {
+		name        string
+		opening     Opening
+		white       []string
+		black       []string
+		result      string
+		termination string
+	}{
+		{
+			name:        "fool's mate",
+			opening:     Opening{FEN: "startpos", Moves: []string{"f2f3"}},
+			white:       []string{"g2g4"},
+			black:       []string{"e7e5", "d8h4"},
+			result:      "0-1",
+			termination: TerminationNormal,
+		},
+		{
+			name:        "repetition",
+			opening:     Opening{FEN: "startpos"},
+			white:       []string{"g1f3", "f3g1", "g1f3", "f3g1"},
+			black:       []string{"g8f6", "f6g8", "g8f6", "f6g8"},
+			result:      "1/2-1/2",
+			termination: TerminationNormal,
+		},
+		{
+			name:        "illegal move",
+			opening:     Opening{FEN: "startpos"},
+			white:       []string{"e2e5"},
+			result:      "0-1",
+			termination: TerminationIllegal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			white := &scripted{name: "w", moves: tt.white}
+			black := &scripted{name: "b", moves: tt.black}
+			g, err := Play(white, black, tt.opening, TimeControl{Nodes: 1}, Adjudication{})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.result, g.Result, g.Reason)
+			assert.Equal(t, tt.termination, g.Termination)
+		})
+	}
+}
+
+func TestPlayAdjudication(t *testing.T) {
+	moves := []string{"g1f3", "f3g1", "b1c3", "c3b1", "g1f3", "f3g1"}
+	replies := []string{"g8f6", "f6g8", "b8c6", "c6b8", "g8f6", "f6g8"}
+
+	white := &scripted{name: "w", moves: moves, scores: []int{-700, -700, -700}}
+	black := &scripted{name: "b", moves: replies, scores: []int{700, 700, 700}}
+	g, err := Play(white, black, Opening{}, TimeControl{Nodes: 1}, Adjudication{ResignMoveCount: 3, ResignScore: 600})
+	assert.NoError(t, err)
+	assert.Equal(t, "0-1", g.Result)
+	assert.Equal(t, TerminationAdjudicated, g.Termination)
+	assert.Len(t, g.Moves, 5)
+
+	white = &scripted{name: "w", moves: moves, scores: []int{5, 0, -5}}
+	black = &scripted{name: "b", moves: replies, scores: []int{0, 3, 10}}
+	g, err = Play(white, black, Opening{}, TimeControl{Nodes: 1}, Adjudication{DrawMoveNumber: 1, DrawMoveCount: 2, DrawScore: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, "1/2-1/2", g.Result)
+	assert.Equal(t, TerminationAdjudicated, g.Termination)
+	assert.Len(t, g.Moves, 4)
+}
+
+func TestPlayEngines(t *testing.T) {
+	white, err := NewEnginePlayer("white", nil)
+	assert.NoError(t, err)
+	black, err := NewEnginePlayer("black", nil)
+	assert.NoError(t, err)
+
+	// White mates in one.
+	opening := Opening{FEN: "6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 0 1"}
+	g, err := Play(white, black, opening, TimeControl{Base: time.Second, Inc: 10 * time.Millisecond, Margin: time.Second}, Adjudication{})
+	assert.NoError(t, err)
+	assert.Equal(t, "1-0", g.Result)
+	assert.Equal(t, []string{"d1d8"}, g.Moves)
+	assert.Equal(t, MateScore-1, g.Scores[0])
+	assert.Equal(t, []string{"Rd8#"}, g.PGN().Moves)
+}
+
+func TestParseScore(t *testing.T) {
+	tests := []struct {
+		info  string
+		score int
+		ok    bool
+	}{
+		{"info depth 5 score cp -35 nodes 100 pv e2e4", -35, true},
+		{"info depth 9 score mate 3 pv d1d8", MateScore - 3, true},
+		{"info depth 9 score mate -2 pv g8h8", -MateScore + 2, true},
+		{"info string hello", 0, false},
+	}
+	for _, tt := range tests {
+		score, ok := parseScore(strings.Fields(tt.info))
+		assert.Equal(t, tt.ok, ok, tt.info)
+		assert.Equal(t, tt.score, score, tt.info)
+	}
+}
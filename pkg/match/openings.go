@@ -0,0 +1,72 @@
+package match
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+	"github.com/likeawizard/tofiks/pkg/pgn"
+)
+
+// LoadOpenings reads openings from a PGN file, where each game is an opening
+// line, or from a file of EPD or FEN positions, one per line. Files ending in
+// .pgn are read as PGN.
+func LoadOpenings(path string) ([]Opening, error) {
+	if strings.HasSuffix(strings.ToLower(path), ".pgn") {
+		return loadPGNOpenings(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var openings []Opening
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		b, _, err := board.ParseEPD(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		openings = append(openings, Opening{FEN: b.ExportFEN()})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(openings) == 0 {
+		return nil, fmt.Errorf("no openings in %s", path)
+	}
+	return openings, nil
+}
+
+func loadPGNOpenings(path string) ([]Opening, error) {
+	games, err := pgn.ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+	openings := make([]Opening, 0, len(games))
+	for i, g := range games {
+		b := board.NewBoard(g.StartFEN)
+		opening := Opening{FEN: b.ExportFEN()}
+		for _, token := range g.Moves {
+			move, err := pgn.ParseMove(b, token)
+			if err != nil {
+				return nil, fmt.Errorf("%s: game %d: %w", path, i+1, err)
+			}
+			opening.Moves = append(opening.Moves, move.String())
+			b.MakeMove(move)
+		}
+		openings = append(openings, opening)
+	}
+	if len(openings) == 0 {
+		return nil, fmt.Errorf("no openings in %s", path)
+	}
+	return openings, nil
+}
@@ -0,0 +1,92 @@
+// Package match plays games between engines: UCI engines running as
+// subprocesses or in-process search engines.
+package match
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MateScore is the score of a mate on the board. A mate in n moves scores
+// MateScore - n.
+const MateScore = 100000
+
+// Request is one move to search: the game so far and the search limits.
+type Request struct {
+	// FEN is the start position of the game, Moves the moves played since in
+	// UCI notation.
+	FEN   string
+	Moves []string
+	// Nodes limits the search when set. Otherwise the remaining clock times
+	// and increments apply.
+	Nodes        int
+	WTime, BTime time.Duration
+	WInc, BInc   time.Duration
+}
+
+// Reply is the result of a search.
+type Reply struct {
+	Move string
+	// Score is the last reported score in centipawns from the point of view
+	// of the side to move, with mates as ±(MateScore - moves).
+	Score int
+}
+
+// Player is an engine taking part in a match. A player plays one game at a
+// time.
+type Player interface {
+	Name() string
+	// NewGame resets the player before a game.
+	NewGame() error
+	// Go searches a position and returns the move to play.
+	Go(req *Request) (Reply, error)
+	Close() error
+}
+
+// parseScore reads a UCI "score cp N" or "score mate N" from info fields.
+func parseScore(fields []string) (int, bool) {
+	for i := 0; i+2 < len(fields); i++ {
+		if fields[i] != "score" {
+			continue
+		}
+		n, err := strconv.Atoi(fields[i+2])
+		if err != nil {
+			return 0, false
+		}
+		switch fields[i+1] {
+		case "cp":
+			return n, true
+		case "mate":
+			if n < 0 {
+				return -MateScore - n, true
+			}
+			return MateScore - n, true
+		}
+	}
+	return 0, false
+}
+
+// positionCommand returns the UCI position command for a request.
+func positionCommand(req *Request) string {
+	var sb strings.Builder
+	if req.FEN == "" || req.FEN == "startpos" {
+		sb.WriteString("position startpos")
+	} else {
+		sb.WriteString("position fen " + req.FEN)
+	}
+	if len(req.Moves) > 0 {
+		sb.WriteString(" moves " + strings.Join(req.Moves, " "))
+	}
+	return sb.String()
+}
+
+// goCommand returns the UCI go command for a request.
+func goCommand(req *Request) string {
+	if req.Nodes > 0 {
+		return fmt.Sprintf("go nodes %d", req.Nodes)
+	}
+	return fmt.Sprintf("go wtime %d btime %d winc %d binc %d",
+		req.WTime.Milliseconds(), req.BTime.Milliseconds(), req.WInc.Milliseconds(), req.BInc.Milliseconds())
+}
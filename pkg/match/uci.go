@@ -0,0 +1,231 @@
+package match
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrTimeout is returned when an engine does not answer in time.
+var ErrTimeout = errors.New("engine timed out")
+
+const (
+	// initTimeout bounds the uci and isready handshakes.
+	initTimeout = 10 * time.Second
+	// goGrace is how long a UCI engine may overrun its clock before it is
+	// given up on. The game is lost on time long before.
+	goGrace = 5 * time.Second
+	// nodesTimeout bounds a node-limited search.
+	nodesTimeout = 5 * time.Minute
+)
+
+// Option is a UCI option set on an engine before it plays.
+type Option struct {
+	Name, Value string
+}
+
+// UCIPlayer runs a UCI engine as a subprocess. An engine that crashes or
+// hangs is restarted at the next NewGame.
+type UCIPlayer struct {
+	cmd     *exec.Cmd
+	in      io.WriteCloser
+	lines   chan string
+	name    string
+	path    string
+	options []Option
+}
+
+// NewUCIPlayer starts the engine at path and sets its options. The name
+// defaults to the file name of the engine.
+func NewUCIPlayer(name, path string, options []Option) (*UCIPlayer, error) {
+	if name == "" {
+		name = filepath.Base(path)
+	}
+	p := &UCIPlayer{name: name, path: path, options: options}
+	if err := p.start(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *UCIPlayer) Name() string {
+	return p.name
+}
+
+func (p *UCIPlayer) start() error {
+	cmd := exec.Command(p.path)
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	p.cmd, p.in = cmd, in
+	p.lines = make(chan string, 64)
+	go func(lines chan<- string) {
+		s := bufio.NewScanner(out)
+		for s.Scan() {
+			lines <- s.Text()
+		}
+		close(lines)
+	}(p.lines)
+
+	if err := p.handshake(); err != nil {
+		p.kill()
+		return err
+	}
+	return nil
+}
+
+// handshake initializes the engine and sets its options.
+func (p *UCIPlayer) handshake() error {
+	if err := p.send("uci"); err != nil {
+		return err
+	}
+	if _, err := p.waitFor("uciok", initTimeout); err != nil {
+		return err
+	}
+	for _, o := range p.options {
+		if err := p.send(fmt.Sprintf("setoption name %s value %s", o.Name, o.Value)); err != nil {
+			return err
+		}
+	}
+	return p.ready()
+}
+
+func (p *UCIPlayer) send(command string) error {
+	_, err := io.WriteString(p.in, command+"\n")
+	return err
+}
+
+func (p *UCIPlayer) ready() error {
+	if err := p.send("isready"); err != nil {
+		return err
+	}
+	_, err := p.waitFor("readyok", initTimeout)
+	return err
+}
+
+// waitFor reads lines until one starting with prefix and returns it along
+// with the info lines read before.
+func (p *UCIPlayer) waitFor(prefix string, timeout time.Duration) ([]string, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	var lines []string
+	for {
+		select {
+		case line, ok := <-p.lines:
+			if !ok {
+				return lines, fmt.Errorf("%s exited", p.name)
+			}
+			if strings.HasPrefix(line, prefix) {
+				return append(lines, line), nil
+			}
+			lines = append(lines, line)
+		case <-timer.C:
+			return lines, ErrTimeout
+		}
+	}
+}
+
+func (p *UCIPlayer) NewGame() error {
+	if p.cmd == nil {
+		if err := p.start(); err != nil {
+			return err
+		}
+	}
+	if err := p.send("ucinewgame"); err != nil {
+		p.kill()
+		return err
+	}
+	if err := p.ready(); err != nil {
+		p.kill()
+		return err
+	}
+	return nil
+}
+
+func (p *UCIPlayer) Go(req *Request) (Reply, error) {
+	if p.cmd == nil {
+		return Reply{}, fmt.Errorf("%s is not running", p.name)
+	}
+	timeout := nodesTimeout
+	if req.Nodes == 0 {
+		timeout = req.WTime + req.WInc + goGrace
+		if req.blackToMove() {
+			timeout = req.BTime + req.BInc + goGrace
+		}
+	}
+	if err := p.send(positionCommand(req)); err != nil {
+		p.kill()
+		return Reply{}, err
+	}
+	if err := p.send(goCommand(req)); err != nil {
+		p.kill()
+		return Reply{}, err
+	}
+	lines, err := p.waitFor("bestmove", timeout)
+	if err != nil {
+		p.kill()
+		return Reply{}, err
+	}
+
+	var reply Reply
+	for _, line := range lines[:len(lines)-1] {
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] == "info" {
+			if score, ok := parseScore(fields); ok {
+				reply.Score = score
+			}
+		}
+	}
+	if fields := strings.Fields(lines[len(lines)-1]); len(fields) > 1 {
+		reply.Move = fields[1]
+	}
+	return reply, nil
+}
+
+// blackToMove reports whether black is to move after the moves of a request.
+func (req *Request) blackToMove() bool {
+	fields := strings.Fields(req.FEN)
+	blackFirst := len(fields) > 1 && fields[1] == "b"
+	return blackFirst != (len(req.Moves)%2 == 1)
+}
+
+// kill stops an engine that misbehaved so that NewGame restarts it.
+func (p *UCIPlayer) kill() {
+	if p.cmd == nil {
+		return
+	}
+	_ = p.cmd.Process.Kill()
+	_ = p.cmd.Wait()
+	p.cmd = nil
+}
+
+func (p *UCIPlayer) Close() error {
+	if p.cmd == nil {
+		return nil
+	}
+	_ = p.send("quit")
+	done := make(chan error, 1)
+	go func() { done <- p.cmd.Wait() }()
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(initTimeout):
+		_ = p.cmd.Process.Kill()
+		<-done
+		err = ErrTimeout
+	}
+	p.cmd = nil
+	return err
+}
@@ -0,0 +1,145 @@
+// Package pgn reads games from PGN files. Moves are returned as written in
+// the movetext, so they may be in SAN or in UCI notation; ParseMove reads
+// either.
+package pgn
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+)
+
+// Game is one game: its tag pairs, starting position, result and moves.
+type Game struct {
+	Headers  map[string]string
+	StartFEN string
+	Result   string
+	Moves    []string
+}
+
+// ParseFile reads all games from a PGN file.
+func ParseFile(path string) ([]Game, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads all games from r. Comments, variations, NAGs, move numbers and
+// result tokens are dropped from the moves.
+func Parse(r io.Reader) ([]Game, error) {
+	var games []Game
+	headers := make(map[string]string)
+	var movetext strings.Builder
+
+	flush := func() {
+		if movetext.Len() == 0 {
+			return
+		}
+		startFEN := board.StartPos
+		if fen, ok := headers["FEN"]; ok {
+			startFEN = fen
+		}
+		games = append(games, Game{
+			Headers:  headers,
+			StartFEN: startFEN,
+			Result:   headers["Result"],
+			Moves:    parseMoves(movetext.String()),
+		})
+		headers = make(map[string]string)
+		movetext.Reset()
+	}
+
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			flush()
+			parts := strings.SplitN(line[1:len(line)-1], " ", 2)
+			if len(parts) == 2 {
+				headers[parts[0]] = strings.Trim(parts[1], "\"")
+			}
+		} else if line != "" {
+			movetext.WriteString(line)
+			movetext.WriteByte('\n')
+		}
+	}
+	flush()
+
+	return games, s.Err()
+}
+
+// parseMoves extracts the mainline moves from PGN movetext.
+func parseMoves(text string) []string {
+	var clean strings.Builder
+	comment, variation := false, 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case comment:
+			comment = c != '}'
+		case c == '{':
+			comment = true
+		case c == ';':
+			// Rest of line comment.
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		case c == '(':
+			variation++
+		case c == ')':
+			variation--
+		case variation == 0:
+			clean.WriteByte(c)
+			continue
+		}
+		clean.WriteByte(' ')
+	}
+
+	var moves []string
+	for tok := range strings.FieldsSeq(clean.String()) {
+		// Drop move numbers, also when glued to the move as in "12.Nf3".
+		if i := strings.LastIndexByte(tok, '.'); i >= 0 {
+			tok = tok[i+1:]
+		}
+		switch {
+		case tok == "", tok[0] == '$':
+		case tok == "1-0", tok == "0-1", tok == "1/2-1/2", tok == "*":
+		default:
+			moves = append(moves, tok)
+		}
+	}
+	return moves
+}
+
+// ParseMove returns the legal move for a movetext token in SAN or UCI
+// notation.
+func ParseMove(b *board.Board, token string) (board.Move, error) {
+	uci := strings.TrimRight(token, "+#!?")
+	for _, m := range b.LegalMoves() {
+		if m.String() == uci {
+			return m, nil
+		}
+	}
+	return b.ParseSAN(token)
+}
+
+// Score converts a game result token to the result from white's point of
+// view: 1, 0 or -1. ok is false for unfinished games.
+func Score(result string) (score int, ok bool) {
+	switch result {
+	case "1-0":
+		return 1, true
+	case "0-1":
+		return -1, true
+	case "1/2-1/2":
+		return 0, true
+	}
+	return 0, false
+}
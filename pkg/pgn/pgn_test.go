@@ -0,0 +1,86 @@
+package pgn
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+)
+
+const games = `[Event "Test"]
+[Result "1-0"]
+
+1. e2e4 {+0.35/12 0.5s} e7e5 2. g1f3 (2. f2f4 e5f4) 2... b8c6 $1
+3.f1b5 ; Ruy Lopez
+a7a6 1-0
+
+[Event "Test"]
+[FEN "7k/5Q2/6K1/8/8/8/8/8 w - - 0 1"]
+[Result "1-0"]
+
+1. f7h7# 1-0
+`
+
+func TestParse(t *testing.T) {
+	parsed, err := Parse(strings.NewReader(games))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Len(t, parsed, 2) {
+		return
+	}
+
+	assert.Equal(t, "startpos", parsed[0].StartFEN)
+	assert.Equal(t, "1-0", parsed[0].Result)
+	assert.Equal(t, []string{"e2e4", "e7e5", "g1f3", "b8c6", "f1b5", "a7a6"}, parsed[0].Moves)
+
+	assert.Equal(t, "7k/5Q2/6K1/8/8/8/8/8 w - - 0 1", parsed[1].StartFEN)
+	assert.Equal(t, []string{"f7h7#"}, parsed[1].Moves)
+}
+
+func TestParseMove(t *testing.T) {
+	b := board.NewBoard("7k/5Q2/6K1/8/8/8/8/8 w - - 0 1")
+	for _, token := range []string{"f7h7#", "Qh7#", "Qfh7", "Qh7"} {
+		move, err := ParseMove(b, token)
+		if assert.NoError(t, err, token) {
+			assert.Equal(t, "f7h7", move.String(), token)
+		}
+	}
+	_, err := ParseMove(b, "Qh9")
+	assert.Error(t, err)
+}
+
+func TestWrite(t *testing.T) {
+	games := []*Game{
+		{
+			Headers:  map[string]string{"White": `say "hi"`, "Termination": "normal"},
+			StartFEN: board.StartPos,
+			Result:   "1-0",
+			Moves:    []string{"e4", "e5", "Nf3", "Nc6", "Bb5", "a6"},
+		},
+		{
+			Headers:  map[string]string{},
+			StartFEN: "7k/5Q2/6K1/8/8/8/8/8 b - - 0 40",
+			Result:   "1-0",
+			Moves:    []string{"Kg8", "Qg7#"},
+		},
+	}
+	var sb strings.Builder
+	for _, g := range games {
+		assert.NoError(t, Write(&sb, g))
+	}
+	assert.Contains(t, sb.String(), "[Event \"?\"]\n[Site \"?\"]\n[Date \"?\"]\n[Round \"?\"]\n[White \"say \\\"hi\\\"\"]\n")
+	assert.Contains(t, sb.String(), "1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 1-0\n")
+	assert.Contains(t, sb.String(), "[SetUp \"1\"]\n")
+	assert.Contains(t, sb.String(), "40... Kg8 41. Qg7# 1-0\n")
+
+	parsed, err := Parse(strings.NewReader(sb.String()))
+	assert.NoError(t, err)
+	if assert.Len(t, parsed, 2) {
+		assert.Equal(t, games[0].Moves, parsed[0].Moves)
+		assert.Equal(t, games[1].StartFEN, parsed[1].StartFEN)
+		assert.Equal(t, games[1].Moves, parsed[1].Moves)
+	}
+}
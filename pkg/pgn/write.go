@@ -0,0 +1,96 @@
+package pgn
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+)
+
+// rosterTags are the seven tags every PGN game starts with, in order.
+var rosterTags = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+// lineWidth is the maximum length of a movetext line.
+const lineWidth = 79
+
+// Write writes a game in PGN export format: the seven tag roster, FEN and
+// SetUp for games not starting from the initial position, the other tags in
+// name order, then the moves as given, with move numbers.
+func Write(w io.Writer, g *Game) error {
+	tags := make(map[string]string, len(g.Headers)+3)
+	for name, value := range g.Headers {
+		tags[name] = value
+	}
+	for _, name := range rosterTags {
+		if tags[name] == "" {
+			tags[name] = "?"
+		}
+	}
+	result := g.Result
+	if result == "" {
+		result = "*"
+	}
+	tags["Result"] = result
+
+	b := board.NewBoard(g.StartFEN)
+	if g.StartFEN != board.StartPos && g.StartFEN != "" && g.StartFEN != board.StartingFEN {
+		tags["FEN"] = g.StartFEN
+		tags["SetUp"] = "1"
+	}
+
+	var sb strings.Builder
+	writeTag := func(name string) {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(tags[name])
+		fmt.Fprintf(&sb, "[%s \"%s\"]\n", name, value)
+	}
+	for _, name := range rosterTags {
+		writeTag(name)
+	}
+	var rest []string
+	for name := range tags {
+		if !slices.Contains(rosterTags, name) {
+			rest = append(rest, name)
+		}
+	}
+	slices.Sort(rest)
+	for _, name := range rest {
+		writeTag(name)
+	}
+	sb.WriteByte('\n')
+
+	side, number := b.Side, int(b.FullMoveCounter)
+	lineLen := 0
+	writeToken := func(tok string) {
+		if lineLen > 0 && lineLen+1+len(tok) > lineWidth {
+			sb.WriteByte('\n')
+			lineLen = 0
+		}
+		if lineLen > 0 {
+			sb.WriteByte(' ')
+			lineLen++
+		}
+		sb.WriteString(tok)
+		lineLen += len(tok)
+	}
+	for i, move := range g.Moves {
+		switch {
+		case side == board.White:
+			writeToken(fmt.Sprintf("%d. %s", number, move))
+		case i == 0:
+			writeToken(fmt.Sprintf("%d... %s", number, move))
+		default:
+			writeToken(move)
+		}
+		if side == board.Black {
+			number++
+		}
+		side ^= 1
+	}
+	writeToken(result)
+	sb.WriteString("\n\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
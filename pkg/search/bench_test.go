@@ -0,0 +1,46 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// benchSignature is the node count of the default bench. Any change to the
+// search or evaluation that alters it is a functional change: update it
+// together with the change, as OpenBench tests expect.
+const benchSignature = 4570223
+
+func TestBenchSignature(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping bench signature in short mode")
+	}
+	nodes, _, err := Bench(BenchPositions, DefaultBenchDepth, DefaultBenchHash)
+	assert.NoError(t, err)
+	total := 0
+	for _, n := range nodes {
+		total += n
+	}
+	assert.Equal(t, benchSignature, total)
+}
+
+func TestBenchDeterministic(t *testing.T) {
+	positions := BenchPositions[:4]
+	first, _, err := Bench(positions, 5, 1)
+	assert.NoError(t, err)
+	second, _, err := Bench(positions, 5, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+}
+
+func TestReadBenchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bench.epd")
+	data := "# positions\n" + BenchPositions[0] + "\n\n8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - bm Rxb1;\n"
+	assert.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+
+	positions, err := ReadBenchFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{BenchPositions[0], "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1"}, positions)
+}
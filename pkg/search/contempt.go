@@ -0,0 +1,58 @@
+package search
+
+import "github.com/likeawizard/tofiks/pkg/board"
+
+// Analysis contempt modes select how Contempt applies to infinite analysis.
+const (
+	// AnalysisContemptOff scores draws as 0 in analysis.
+	AnalysisContemptOff = iota
+	// AnalysisContemptWhite applies contempt from white's point of view.
+	AnalysisContemptWhite
+	// AnalysisContemptBlack applies contempt from black's point of view.
+	AnalysisContemptBlack
+	// AnalysisContemptBoth applies contempt from the side to move at the root, as in play.
+	AnalysisContemptBoth
+)
+
+// AnalysisContemptModes names the modes in the order of their constants.
+var AnalysisContemptModes = []string{"Off", "White", "Black", "Both"}
+
+// setContempt fixes the draw score for each side to move for the coming
+// search. A positive contempt makes the side it favours avoid draws.
+//
+// Draw scores reach the transposition table through the values backed up from
+// them, so entries are only valid under the draw score they were searched
+// with. Rather than clearing the table when it changes, which in analysis with
+// AnalysisContemptBoth happens whenever the side to move does, entries are
+// keyed by the draw score as well (see ttKey).
+func (e *Engine) setContempt() {
+	contempt := int16(e.Contempt)
+	side := e.Board.Side
+	if e.Analysis {
+		switch e.AnalysisContempt {
+		case AnalysisContemptOff:
+			contempt = 0
+		case AnalysisContemptWhite:
+			side = board.White
+		case AnalysisContemptBlack:
+			side = board.Black
+		}
+	}
+
+	e.drawScore[side] = -contempt
+	e.drawScore[side^1] = contempt
+	// The white draw score determines both. Without contempt the key is the
+	// position hash alone.
+	e.ttSalt = uint64(uint16(e.drawScore[board.White])) * 0x9e3779b97f4a7c15
+}
+
+// ttKey returns the transposition table key of the position under the draw
+// score of the search.
+func (e *Engine) ttKey() uint64 {
+	return e.Board.Hash ^ e.ttSalt
+}
+
+// DrawScore returns the score of a draw for the side to move.
+func (e *Engine) DrawScore() int16 {
+	return e.drawScore[e.Board.Side]
+}
@@ -0,0 +1,89 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+)
+
+func TestContemptStalemate(t *testing.T) {
+	// Black to move is stalemated.
+	const fen = "k7/8/1Q6/8/8/8/8/2K5 b - - 0 1"
+	tests := []struct {
+		name     string
+		mode     int
+		analysis bool
+		want     int16
+	}{
+		{"play", AnalysisContemptOff, false, -20},
+		{"analysis off", AnalysisContemptOff, true, 0},
+		{"analysis white", AnalysisContemptWhite, true, 20},
+		{"analysis black", AnalysisContemptBlack, true, -20},
+		{"analysis both", AnalysisContemptBoth, true, -20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEngine()
+			e.TTable = NewTTable(1)
+			e.Board = board.NewBoard(fen)
+			e.Contempt = 20
+			e.AnalysisContempt = tt.mode
+			e.Analysis = tt.analysis
+			e.setContempt()
+
+			var line []board.Move
+			if got := e.PVS(nil, &line, 1, 0, -Inf, Inf, false, -1); got != tt.want {
+				t.Fatalf("stalemate score = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContemptRepetition(t *testing.T) {
+	e := NewEngine()
+	e.TTable = NewTTable(1)
+	e.Contempt = 30
+	e.setContempt()
+	e.AddPly()
+	for _, move := range []string{"g1f3", "g8f6", "f3g1", "f6g8"} {
+		e.Board.MoveUCI(move)
+		e.AddPly()
+	}
+
+	// White to move at the root sees the repeated start position as a draw
+	// it wants to avoid.
+	var line []board.Move
+	if got := e.PVS(nil, &line, 1, 1, -Inf, Inf, false, 1); got != -30 {
+		t.Fatalf("repetition score = %d, want -30", got)
+	}
+}
+
+// TestContemptKeepsTable checks that changing the draw score, as analysis
+// with contempt from the side to move does on every position, keeps the
+// transposition table entries of either draw score.
+func TestContemptKeepsTable(t *testing.T) {
+	e := NewEngine()
+	e.TTable = NewTTable(1)
+	e.Contempt = 20
+	e.Analysis = true
+	e.AnalysisContempt = AnalysisContemptBoth
+	e.setContempt()
+	e.TTable.Store(e.ttKey(), Exact, 35, 5, 0, 0)
+	white := e.ttKey()
+
+	unmake, _ := e.Board.MoveUCI("e2e4")
+	e.setContempt()
+	if e.DrawScore() != -20 {
+		t.Fatalf("draw score = %d, want -20", e.DrawScore())
+	}
+	unmake()
+	if e.ttKey() == white {
+		t.Fatal("same table key under another draw score")
+	}
+	if _, ok := e.TTable.Probe(white); !ok {
+		t.Fatal("entry lost when the draw score changed")
+	}
+	if _, ok := e.TTable.Probe(e.ttKey()); ok {
+		t.Fatal("entry found under another draw score")
+	}
+}
@@ -2,7 +2,9 @@ package search
 
 import (
 	"fmt"
+	"io"
 	"math"
+	"os"
 	"strings"
 	"sync"
 
@@ -11,6 +13,9 @@ import (
 	"github.com/likeawizard/tofiks/pkg/eval"
 )
 
+// Name is the engine name and version the UCI and xboard protocols report.
+const Name = "Tofiks v1.5.0"
+
 // LmrTable[depth][moveNum] gives the late-move reduction in plies.
 // Computed once at init using a log * log formula.
 var LmrTable [64][64]int8
@@ -55,6 +60,7 @@ type Engine struct {
 	Board        *board.Board
 	TTable       *TTable
 	Eval         *eval.Eval
+	Book         *book.Book
 	TC           *TimeControl
 	Stats        Stats
 	History      HistoryHeuristic
@@ -67,9 +73,42 @@ type Engine struct {
 	PrevMove     [100]board.Move
 	ExcludedMove [100]board.Move
 	StaticEvals  [100]int16
-	MateFound    bool
-	OwnBook      bool
-	Ponder       bool
+	// Out receives the protocol output. When nil it goes to os.Stdout.
+	Out io.Writer
+	// OnInfo receives a summary of every completed search iteration. When nil
+	// the iteration is printed as a UCI info line.
+	OnInfo    func(Info)
+	MateFound bool
+	OwnBook   bool
+	Ponder    bool
+	// Debug enables extra info string diagnostics (UCI debug on).
+	Debug bool
+	// ShowWDL appends win/draw/loss per mille to info lines.
+	ShowWDL bool
+	// NormalizeScore reports cp scores so that 100 is a 50% chance to win.
+	NormalizeScore bool
+	// Contempt is the penalty in centipawns for a draw from the root side's
+	// point of view. Negative values make the engine seek draws.
+	Contempt int
+	// AnalysisContempt is one of the AnalysisContempt modes and applies when
+	// Analysis is set.
+	AnalysisContempt int
+	// Analysis marks an infinite search on behalf of the user, not pondering.
+	Analysis bool
+	// Params are the search tunables.
+	Params Params
+	// NodeLimit stops the search after this many nodes, 0 for no limit.
+	NodeLimit int
+	// nodeBudget is what is left of NodeLimit for the running iteration.
+	nodeBudget int
+	// drawScore is the score of a draw indexed by the side to move.
+	drawScore [2]int16
+	// ttSalt is mixed into transposition table keys to tell apart entries
+	// searched under different draw scores.
+	ttSalt uint64
+	// lastScore is the score of the last completed iteration from white's
+	// point of view.
+	lastScore int16
 }
 
 var mvvlva = [7][6]int{
@@ -89,23 +128,88 @@ func NewEngine() *Engine {
 		TTable: NewTTable(64),
 		Eval:   eval.New(),
 		TC:     &TimeControl{},
+		Book:   book.New(),
+		Params: DefaultParams(),
 	}
 }
 
 // Returns the best move and best opponent response - ponder.
 func (e *Engine) GetMove(depth int, infinite bool) (board.Move, board.Move) {
+	e.TC = e.Clock.NewTimeControl(int(e.Board.FullMoveCounter), e.Board.Side)
+	return e.FindMove(depth, infinite)
+}
+
+// FindMove is GetMove with the time control already armed in e.TC. Callers
+// that search on another goroutine set e.TC up front so that an abort issued
+// right after the hand-off cannot be lost.
+func (e *Engine) FindMove(depth int, infinite bool) (board.Move, board.Move) {
 	var best, ponder board.Move
-	if e.OwnBook && book.InBook(e.Board) {
-		move := book.GetWeighted(e.Board)
-		return move, 0
+	if e.OwnBook {
+		if move, ok := e.Book.Move(e.Board); ok {
+			e.Debugf("book move %v", move)
+			return move, 0
+		}
 	}
+	e.Debugf("time budget %v hard limit %v", e.TC.budget, e.TC.hardLimit)
 
-	e.TC = e.Clock.NewTimeControl(int(e.Board.FullMoveCounter), e.Board.Side)
 	best, ponder, _ = e.IDSearch(depth, infinite)
 
 	return best, ponder
 }
 
+// Output returns the writer protocol output goes to.
+func (e *Engine) Output() io.Writer {
+	if e.Out == nil {
+		return os.Stdout
+	}
+	return e.Out
+}
+
+// Debugf prints an info string when debug mode is on.
+func (e *Engine) Debugf(format string, args ...any) {
+	if !e.Debug {
+		return
+	}
+	fmt.Fprintf(e.Output(), "info string "+format+"\n", args...)
+}
+
+// NewGame clears all state carried over between games: hash tables and the
+// move ordering heuristics. Book learning of the previous game uses the
+// result expected from the last search unless EndGame already applied one.
+func (e *Engine) NewGame() {
+	e.EndGame(e.ExpectedResult())
+	e.lastScore = 0
+	e.TTable.Clear()
+	e.Eval.PawnTable.Clear()
+	e.KillerMoves = [100][2]board.Move{}
+	e.Plys = [512]uint64{}
+	e.History = HistoryHeuristic{}
+}
+
+// learnMargin is the score in centipawns beyond which the last search of a
+// game counts as a win or a loss.
+const learnMargin = 200
+
+// ExpectedResult estimates the game result from white's point of view, 1, 0
+// or -1, from the score of the last search.
+func (e *Engine) ExpectedResult() int {
+	switch {
+	case e.lastScore > learnMargin:
+		return 1
+	case e.lastScore < -learnMargin:
+		return -1
+	}
+	return 0
+}
+
+// EndGame applies book learning with the game result from white's point of
+// view: 1, 0 or -1.
+func (e *Engine) EndGame(result int) {
+	if err := e.Book.Learn(result); err != nil {
+		fmt.Fprintf(e.Output(), "info string book learning failed: %v\n", err)
+	}
+}
+
 func (e *Engine) AddKillerMove(ply int, move board.Move) {
 	if move != e.KillerMoves[ply][0] {
 		e.KillerMoves[ply][1] = e.KillerMoves[ply][0]
@@ -240,25 +344,34 @@ func (e *Engine) PlayMovesUCI(uciMoves string) bool {
 
 func (e *Engine) ReportMove(move, ponder board.Move, allowPonder bool) {
 	if !allowPonder || ponder == 0 {
-		fmt.Printf("bestmove %v\n", move)
+		fmt.Fprintf(e.Output(), "bestmove %v\n", move)
 	} else {
-		fmt.Printf("bestmove %v ponder %v\n", move, ponder)
+		fmt.Fprintf(e.Output(), "bestmove %v ponder %v\n", move, ponder)
 	}
 }
 
 // Display centipawn score. If the eval is in the checkmate score threshold convert to mate score.
 func (e *Engine) ConvertEvalToScore(eval int16) string {
+	if mateDist, ok := MateDistance(eval); ok {
+		return fmt.Sprintf("mate %d", mateDist)
+	}
+
+	return fmt.Sprintf("cp %d", eval)
+}
+
+// MateDistance converts a checkmate score to the number of moves until mate.
+// The distance is negative when the side to move is getting mated. Returns
+// false for regular centipawn scores.
+func MateDistance(eval int16) (int, bool) {
 	if eval < -CheckmateThreshold {
 		mateDist := -CheckmateScore - eval
-		mateDist = mateDist/2 + mateDist%2
-		return fmt.Sprintf("mate %d", mateDist)
+		return int(mateDist/2 + mateDist%2), true
 	}
 
 	if eval > CheckmateThreshold {
 		mateDist := CheckmateScore - eval
-		mateDist = mateDist/2 + mateDist%2
-		return fmt.Sprintf("mate %d", mateDist)
+		return int(mateDist/2 + mateDist%2), true
 	}
 
-	return fmt.Sprintf("cp %d", eval)
+	return 0, false
 }
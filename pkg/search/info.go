@@ -0,0 +1,46 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+	"github.com/likeawizard/tofiks/pkg/wdl"
+)
+
+// Info summarizes one completed iteration of IDSearch.
+type Info struct {
+	PV       []board.Move
+	Time     time.Duration
+	Nodes    int
+	NPS      int64
+	Hashfull uint64
+	Depth    int
+	SelDepth int
+	Score    int16
+}
+
+// reportInfo hands the iteration summary to OnInfo or prints it as a UCI info line.
+func (e *Engine) reportInfo(info Info) {
+	if e.OnInfo != nil {
+		e.OnInfo(info)
+		return
+	}
+
+	var lineStr strings.Builder
+	for _, m := range info.PV {
+		lineStr.WriteString(" " + m.String())
+	}
+
+	score := e.ConvertEvalToScore(info.Score)
+	material := wdl.Material(e.Board)
+	if _, mate := MateDistance(info.Score); e.NormalizeScore && !mate {
+		score = fmt.Sprintf("cp %d", wdl.Default.Normalize(int(info.Score), material))
+	}
+	if e.ShowWDL {
+		win, draw, loss := wdl.Default.WDL(int(info.Score), material)
+		score += fmt.Sprintf(" wdl %d %d %d", win, draw, loss)
+	}
+	fmt.Fprintf(e.Output(), "info depth %d seldepth %d score %s nodes %d nps %d time %d hashfull %d pv%s\n", info.Depth, info.SelDepth, score, info.Nodes, info.NPS, info.Time.Milliseconds(), info.Hashfull, lineStr.String())
+}
@@ -2,7 +2,6 @@ package search
 
 import (
 	"fmt"
-	"strings"
 	"sync"
 	"time"
 
@@ -18,6 +17,39 @@ const (
 	Inf = 2 * CheckmateScore
 )
 
+// Params are the search tunables. Exposed as UCI options so they can be tuned
+// without a rebuild; every engine has its own.
+type Params struct {
+	// RFPMargin is the reverse futility margin per ply of depth.
+	RFPMargin int
+	// FutilityMargin is the futility pruning margin per ply of depth.
+	FutilityMargin int
+	// AspirationLow and AspirationHigh set the aspiration window around the previous score.
+	AspirationLow  int
+	AspirationHigh int
+	// NMPBase and NMPDepthDiv give the null move reduction: NMPBase + depth/NMPDepthDiv.
+	NMPBase     int
+	NMPDepthDiv int
+	// LMPBase is the late move pruning move count at depth 0.
+	LMPBase int
+	// SingularMargin is the singular extension margin per ply of depth.
+	SingularMargin int
+}
+
+// DefaultParams returns the tunables a new engine starts with.
+func DefaultParams() Params {
+	return Params{
+		RFPMargin:      90,
+		FutilityMargin: 154,
+		AspirationLow:  50,
+		AspirationHigh: 100,
+		NMPBase:        3,
+		NMPDepthDiv:    7,
+		LMPBase:        5,
+		SingularMargin: 2,
+	}
+}
+
 func (e *Engine) PVS(pvOrder []board.Move, line *[]board.Move, depth, ply int, alpha, beta int16, nmp bool, side int16) int16 {
 	if e.TC.ShouldAbort() {
 		// Meaningless return. Should never trust the result after abort.
@@ -36,7 +68,7 @@ func (e *Engine) PVS(pvOrder []board.Move, line *[]board.Move, depth, ply int, a
 	}
 
 	if ply > 0 && (e.Board.HalfMoveCounter >= 100 || e.Board.InsufficientMaterial() || e.IsDrawByRepetition()) {
-		return 0
+		return e.DrawScore()
 	}
 
 	// If search depth is reached and not in check enter Qsearch
@@ -45,6 +77,9 @@ func (e *Engine) PVS(pvOrder []board.Move, line *[]board.Move, depth, ply int, a
 	}
 
 	e.Stats.nodes++
+	if e.nodeBudget > 0 && e.Stats.nodes+e.Stats.qNodes >= e.nodeBudget {
+		e.TC.Abort()
+	}
 
 	// Static eval for pruning decisions.
 	var staticEval int16
@@ -57,7 +92,7 @@ func (e *Engine) PVS(pvOrder []board.Move, line *[]board.Move, depth, ply int, a
 		// Reverse futility pruning. If static eval is well above beta at shallow depths,
 		// the opponent is unlikely to improve their position enough to drop below beta.
 		if depth <= 5 {
-			cutoff := staticEval-90*int16(depth) >= beta
+			cutoff := staticEval-int16(e.Params.RFPMargin*depth) >= beta
 			e.Prune.recordRFP(cutoff)
 			if cutoff {
 				return staticEval
@@ -73,7 +108,7 @@ func (e *Engine) PVS(pvOrder []board.Move, line *[]board.Move, depth, ply int, a
 	var ttDepth int
 	var ttBound EntryType
 	ttHit := false
-	if entry, ok := e.TTable.Probe(e.Board.Hash); ok {
+	if entry, ok := e.TTable.Probe(e.ttKey()); ok {
 		ttMove := entry.Move()
 		ttValue = entry.Score()
 		ttDepth = entry.Depth()
@@ -116,7 +151,7 @@ func (e *Engine) PVS(pvOrder []board.Move, line *[]board.Move, depth, ply int, a
 	// - when less than 7 pieces on board (random heuristic) or pawn only endgame due to possible zugzwang situations
 	if !isPV && !inCheck && nmp && e.Board.Occupancy[board.Both].Count() > 6 && !e.Board.IsPawnOnly() {
 		unull := e.Board.MakeNullMove()
-		R := 3 + depth/7
+		R := e.Params.NMPBase + depth/e.Params.NMPDepthDiv
 		e.PrevMove[ply] = 0
 		value := -e.PVS(pvOrder, &[]board.Move{}, depth-R-1, ply+1, -beta, -beta+1, false, -side)
 		unull()
@@ -131,7 +166,7 @@ func (e *Engine) PVS(pvOrder []board.Move, line *[]board.Move, depth, ply int, a
 	if ply > 0 && depth >= 8 && pvMove != 0 && e.ExcludedMove[ply] == 0 &&
 		ttHit && ttDepth >= depth-3 && (ttBound == Lower || ttBound == Exact) &&
 		ttValue > -CheckmateThreshold && ttValue < CheckmateThreshold {
-		singularBeta := ttValue - 2*int16(depth)
+		singularBeta := ttValue - int16(e.Params.SingularMargin*depth)
 		singularDepth := depth / 2
 
 		e.ExcludedMove[ply] = pvMove
@@ -177,7 +212,7 @@ func (e *Engine) PVS(pvOrder []board.Move, line *[]board.Move, depth, ply int, a
 		legalMoves++
 
 		// Late move pruning. At shallow depths, skip quiet moves that are ordered late.
-		lmpThreshold := (5 + 3*depth*depth) / (2 - boolToInt(improving))
+		lmpThreshold := (e.Params.LMPBase + 3*depth*depth) / (2 - boolToInt(improving))
 		if canPrune && depth >= 2 && depth <= 6 &&
 			!currMove.IsCapture() && currMove.Promotion() == 0 &&
 			bestVal > -CheckmateThreshold {
@@ -193,7 +228,7 @@ func (e *Engine) PVS(pvOrder []board.Move, line *[]board.Move, depth, ply int, a
 		if canPrune && depth <= 2 && legalMoves > 1 &&
 			!currMove.IsCapture() && currMove.Promotion() == 0 &&
 			!e.Board.InCheck {
-			prune := staticEval+154*int16(depth) <= alpha
+			prune := staticEval+int16(e.Params.FutilityMargin*depth) <= alpha
 			e.Prune.recordFP(prune)
 			if prune {
 				umove()
@@ -272,9 +307,9 @@ func (e *Engine) PVS(pvOrder []board.Move, line *[]board.Move, depth, ply int, a
 			return int16(ply) - CheckmateScore
 		}
 
-		return 0
+		return e.DrawScore()
 	}
-	e.TTable.Store(e.Board.Hash, entryType, bestVal, depth, ply, bestMove)
+	e.TTable.Store(e.ttKey(), entryType, bestVal, depth, ply, bestMove)
 	return bestVal
 }
 
@@ -289,7 +324,7 @@ func (e *Engine) Quiescence(ply int, alpha, beta, side int16) int16 {
 		e.Stats.SelDepth = ply
 	}
 
-	if entry, ok := e.TTable.Probe(e.Board.Hash); ok {
+	if entry, ok := e.TTable.Probe(e.ttKey()); ok {
 		if eval, ok := entry.GetScore(0, ply, alpha, beta); ok {
 			return eval
 		}
@@ -361,7 +396,7 @@ func (e *Engine) Quiescence(ply int, alpha, beta, side int16) int16 {
 		return eval
 	}
 
-	e.TTable.Store(e.Board.Hash, entryType, bestVal, 0, ply, bestMove)
+	e.TTable.Store(e.ttKey(), entryType, bestVal, 0, ply, bestMove)
 	return bestVal
 }
 
@@ -378,6 +413,7 @@ func (e *Engine) IDSearch(depth int, infinite bool) (board.Move, board.Move, boo
 	if e.Board.Side != board.White {
 		color = -color
 	}
+	e.setContempt()
 	e.TTable.IncAge()
 	e.AgeHistory()
 	e.Stability.reset()
@@ -394,6 +430,7 @@ func (e *Engine) IDSearch(depth int, infinite bool) (board.Move, board.Move, boo
 	}
 
 	done, ok := false, true
+	spent := 0
 	wg.Go(func() {
 		for d := 1; d <= depth; d++ {
 			if done {
@@ -411,6 +448,10 @@ func (e *Engine) IDSearch(depth int, infinite bool) (board.Move, board.Move, boo
 			e.Eval.PawnTable.Stats.Reset()
 			e.MoveOrder.reset()
 			e.Prune.reset()
+			e.nodeBudget = 0
+			if e.NodeLimit > 0 {
+				e.nodeBudget = max(e.NodeLimit-spent, 1)
+			}
 			var pv []board.Move
 			pv = append(pv, line...)
 			eval = e.PVS(pv, &line, d, 0, alpha, beta, true, color)
@@ -425,7 +466,7 @@ func (e *Engine) IDSearch(depth int, infinite bool) (board.Move, board.Move, boo
 			} else {
 				e.Stability.recordAspiration(false)
 			}
-			alpha, beta = eval-50, eval+100
+			alpha, beta = eval-int16(e.Params.AspirationLow), eval+int16(e.Params.AspirationHigh)
 
 			if e.TC.ShouldAbort() {
 				// Search was aborted; results unreliable.
@@ -442,32 +483,39 @@ func (e *Engine) IDSearch(depth int, infinite bool) (board.Move, board.Move, boo
 			}
 			e.TC.IterationFinished()
 			e.TC.RecordIteration(best, eval)
+			e.lastScore = eval * color
 			e.Stability.recordIteration(best, eval)
-			var lineStr strings.Builder
-			for _, m := range line {
-				lineStr.WriteString(" " + m.String())
-			}
 			totalN := e.Stats.nodes + e.Stats.qNodes
 			timeSince := time.Since(start)
 			nps := int64(totalN)
 			if timeSince.Milliseconds() != 0 {
 				nps = (1000 * nps) / timeSince.Milliseconds()
 			}
-			fmt.Printf("info depth %d seldepth %d score %s nodes %d nps %d time %d hashfull %d pv%s\n", d, e.Stats.SelDepth, e.ConvertEvalToScore(eval), totalN, nps, timeSince.Milliseconds(), e.TTable.Hashfull(), lineStr.String())
+			e.reportInfo(Info{
+				PV:       line,
+				Time:     timeSince,
+				Nodes:    totalN,
+				NPS:      nps,
+				Hashfull: e.TTable.Hashfull(),
+				Depth:    d,
+				SelDepth: e.Stats.SelDepth,
+				Score:    eval,
+			})
+			e.Debugf("depth %d %s", d, e.Stats.String())
 			if s := e.TTable.Stats.String(); s != "" {
-				fmt.Printf("info string %s\n", s)
+				fmt.Fprintf(e.Output(), "info string %s\n", s)
 			}
 			if s := e.MoveOrder.String(); s != "" {
-				fmt.Printf("info string %s\n", s)
+				fmt.Fprintf(e.Output(), "info string %s\n", s)
 			}
 			if s := e.Prune.String(); s != "" {
-				fmt.Printf("info string %s\n", s)
+				fmt.Fprintf(e.Output(), "info string %s\n", s)
 			}
 			if s := e.Stability.String(); s != "" {
-				fmt.Printf("info string %s\n", s)
+				fmt.Fprintf(e.Output(), "info string %s\n", s)
 			}
 			if s := e.Eval.PawnTable.Stats.String(); s != "" {
-				fmt.Printf("info string %s\n", s)
+				fmt.Fprintf(e.Output(), "info string %s\n", s)
 			}
 			if eval > CheckmateThreshold || eval < -CheckmateThreshold {
 				e.MateFound = true
@@ -475,6 +523,10 @@ func (e *Engine) IDSearch(depth int, infinite bool) (board.Move, board.Move, boo
 			if !infinite && e.MateFound {
 				done = true
 			}
+			spent += totalN
+			if e.NodeLimit > 0 && spent >= e.NodeLimit {
+				done = true
+			}
 		}
 	})
 
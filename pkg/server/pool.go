@@ -0,0 +1,45 @@
+package server
+
+import (
+	"context"
+
+	"github.com/likeawizard/tofiks/pkg/search"
+)
+
+// Pool is a fixed set of engines shared by concurrent requests. A request
+// holds an engine for its whole duration, so at most Size searches run at once.
+type Pool struct {
+	engines chan *search.Engine
+}
+
+// NewPool creates size engines, each with a transposition table of hash MB.
+func NewPool(size, hash int) *Pool {
+	p := &Pool{engines: make(chan *search.Engine, size)}
+	for range size {
+		e := search.NewEngine()
+		e.TTable = search.NewTTable(hash)
+		p.engines <- e
+	}
+	return p
+}
+
+// Size returns the number of engines in the pool.
+func (p *Pool) Size() int {
+	return cap(p.engines)
+}
+
+// Acquire waits for an idle engine or until ctx is done.
+func (p *Pool) Acquire(ctx context.Context) (*search.Engine, error) {
+	select {
+	case e := <-p.engines:
+		return e, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Release returns an engine acquired from the pool.
+func (p *Pool) Release(e *search.Engine) {
+	e.OnInfo = nil
+	p.engines <- e
+}
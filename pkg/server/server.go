@@ -0,0 +1,384 @@
+// Package server exposes the engine over a local HTTP/JSON API.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+	"github.com/likeawizard/tofiks/pkg/search"
+	"github.com/likeawizard/tofiks/pkg/texel"
+)
+
+const (
+	// Limits applied to /analyze when the request gives none or too much.
+	defaultMovetime = 1000
+	maxMovetime     = 60000
+	maxDepth        = 50
+	maxPerftDepth   = 7
+)
+
+// PositionRequest selects a position: a FEN (empty or "startpos" for the
+// initial position) followed by moves in UCI notation.
+type PositionRequest struct {
+	FEN   string   `json:"fen"`
+	Moves []string `json:"moves"`
+}
+
+// AnalyzeRequest is the body of /analyze. Movetime is in milliseconds. When
+// neither limit is set the search runs for one second.
+type AnalyzeRequest struct {
+	PositionRequest
+	Depth    int `json:"depth"`
+	Movetime int `json:"movetime"`
+}
+
+// PerftRequest is the body of /perft.
+type PerftRequest struct {
+	PositionRequest
+	Depth int `json:"depth"`
+}
+
+// Score is a search score from the side to move: centipawns or moves to mate.
+type Score struct {
+	CP   *int `json:"cp,omitempty"`
+	Mate *int `json:"mate,omitempty"`
+}
+
+// Info is one completed search iteration.
+type Info struct {
+	Score    Score    `json:"score"`
+	PV       []string `json:"pv"`
+	Depth    int      `json:"depth"`
+	SelDepth int      `json:"seldepth"`
+	Nodes    int      `json:"nodes"`
+	NPS      int64    `json:"nps"`
+	Time     int64    `json:"time"`
+}
+
+// AnalyzeResponse is the result of /analyze and the final event of /analyze/stream.
+type AnalyzeResponse struct {
+	BestMove string `json:"bestmove"`
+	Ponder   string `json:"ponder,omitempty"`
+	Info
+}
+
+// EvalTerm is one evaluation term as {white, black} middlegame and endgame values.
+type EvalTerm struct {
+	Name string     `json:"name"`
+	MG   [2]float64 `json:"mg"`
+	EG   [2]float64 `json:"eg"`
+}
+
+// EvalResponse is the result of /eval. Score is in centipawns from white's side.
+type EvalResponse struct {
+	Terms []EvalTerm `json:"terms"`
+	Score int        `json:"score"`
+	Phase int        `json:"phase"`
+}
+
+// PerftResponse is the result of /perft: the total and the count per root move.
+type PerftResponse struct {
+	Moves map[string]int64 `json:"moves"`
+	Nodes int64            `json:"nodes"`
+	Time  int64            `json:"time"`
+}
+
+// LegalResponse is the result of /legal.
+type LegalResponse struct {
+	FEN     string   `json:"fen"`
+	Moves   []string `json:"moves"`
+	InCheck bool     `json:"incheck"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server routes API requests to a pool of engines.
+type Server struct {
+	pool *Pool
+	mux  *http.ServeMux
+}
+
+// New creates a server backed by the given engine pool.
+func New(pool *Pool) *Server {
+	s := &Server{pool: pool, mux: http.NewServeMux()}
+	s.mux.HandleFunc("POST /analyze", s.analyze)
+	s.mux.HandleFunc("GET /analyze/stream", s.analyzeStream)
+	s.mux.HandleFunc("POST /analyze/stream", s.analyzeStream)
+	s.mux.HandleFunc("POST /eval", s.eval)
+	s.mux.HandleFunc("POST /perft", s.perft)
+	s.mux.HandleFunc("GET /legal", s.legal)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe serves the API on addr until the listener fails.
+func ListenAndServe(addr string, pool *Pool) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           New(pool),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return srv.ListenAndServe()
+}
+
+func (s *Server) analyze(w http.ResponseWriter, r *http.Request) {
+	var req AnalyzeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, status, err := s.search(r.Context(), &req, nil)
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// analyzeStream sends every iteration as a server-sent "info" event followed
+// by a single "bestmove" event. GET takes the request from the query string
+// (fen, moves separated by spaces, depth, movetime) for use with EventSource.
+func (s *Server) analyzeStream(w http.ResponseWriter, r *http.Request) {
+	var req AnalyzeRequest
+	if r.Method == http.MethodGet {
+		q := r.URL.Query()
+		req.FEN = q.Get("fen")
+		req.Moves = strings.Fields(q.Get("moves"))
+		_, _ = fmt.Sscan(q.Get("depth"), &req.Depth)
+		_, _ = fmt.Sscan(q.Get("movetime"), &req.Movetime)
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming unsupported"))
+		return
+	}
+
+	started := false
+	sendEvent := func(event string, v any) {
+		if !started {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		flusher.Flush()
+	}
+
+	resp, status, err := s.search(r.Context(), &req, func(info Info) { sendEvent("info", info) })
+	switch {
+	case err == nil:
+		sendEvent("bestmove", resp)
+	case started:
+		sendEvent("error", errorResponse{err.Error()})
+	default:
+		writeError(w, status, err)
+	}
+}
+
+// search runs an analysis on a pooled engine. The search is aborted when ctx
+// is done, e.g. when the client disconnects.
+func (s *Server) search(ctx context.Context, req *AnalyzeRequest, onInfo func(Info)) (*AnalyzeResponse, int, error) {
+	depth, movetime := req.Depth, req.Movetime
+	if depth <= 0 && movetime <= 0 {
+		movetime = defaultMovetime
+	}
+	if depth <= 0 || depth > maxDepth {
+		depth = maxDepth
+	}
+	movetime = min(movetime, maxMovetime)
+
+	e, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return nil, http.StatusServiceUnavailable, err
+	}
+	defer s.pool.Release(e)
+
+	if err := setPosition(e, &req.PositionRequest); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if len(e.Board.LegalMoves()) == 0 {
+		return nil, http.StatusUnprocessableEntity, errors.New("no legal moves")
+	}
+
+	var last Info
+	e.OnInfo = func(info search.Info) {
+		last = newInfo(&info)
+		if onInfo != nil {
+			onInfo(last)
+		}
+	}
+	e.Clock = search.Clock{Movetime: movetime}
+	e.TC = e.Clock.NewTimeControl(int(e.Board.FullMoveCounter), e.Board.Side)
+	defer e.TC.Stop()
+	defer context.AfterFunc(ctx, e.TC.Abort)()
+
+	best, ponder := e.FindMove(depth, false)
+	if ctx.Err() != nil {
+		return nil, http.StatusServiceUnavailable, ctx.Err()
+	}
+
+	resp := &AnalyzeResponse{BestMove: best.String(), Info: last}
+	if ponder != 0 {
+		resp.Ponder = ponder.String()
+	}
+	return resp, http.StatusOK, nil
+}
+
+func (s *Server) eval(w http.ResponseWriter, r *http.Request) {
+	var req PositionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	b, err := loadPosition(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	e, err := s.pool.Acquire(r.Context())
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	score := e.Eval.GetEvaluation(b)
+	s.pool.Release(e)
+
+	resp := EvalResponse{Score: score, Phase: int(b.Phase)}
+	for _, ts := range texel.Breakdown(b) {
+		resp.Terms = append(resp.Terms, EvalTerm{Name: ts.Name, MG: ts.MG, EG: ts.EG})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) perft(w http.ResponseWriter, r *http.Request) {
+	var req PerftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Depth < 1 || req.Depth > maxPerftDepth {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("depth must be between 1 and %d", maxPerftDepth))
+		return
+	}
+	b, err := loadPosition(&req.PositionRequest)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Perft holds no engine but is as expensive as a search, so it takes a pool slot.
+	e, err := s.pool.Acquire(r.Context())
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	defer s.pool.Release(e)
+
+	start := time.Now()
+	resp := PerftResponse{Moves: make(map[string]int64)}
+	for _, m := range b.LegalMoves() {
+		umove := b.MakeMove(m)
+		nodes := b.Perft(req.Depth - 1)
+		umove()
+		resp.Moves[m.String()] = nodes
+		resp.Nodes += nodes
+	}
+	resp.Time = time.Since(start).Milliseconds()
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) legal(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	b, err := loadPosition(&PositionRequest{FEN: q.Get("fen"), Moves: strings.Fields(q.Get("moves"))})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	resp := LegalResponse{FEN: b.ExportFEN(), Moves: []string{}, InCheck: b.InCheck}
+	for _, m := range b.LegalMoves() {
+		resp.Moves = append(resp.Moves, m.String())
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// loadPosition builds the board for req, validating each move.
+func loadPosition(req *PositionRequest) (*board.Board, error) {
+	fen := req.FEN
+	if fen == "" || fen == board.StartPos {
+		fen = board.StartingFEN
+	}
+	b := &board.Board{}
+	if err := b.ImportFEN(fen); err != nil {
+		return nil, err
+	}
+	for _, move := range req.Moves {
+		if _, ok := b.MoveUCI(move); !ok {
+			return nil, fmt.Errorf("illegal move %s", move)
+		}
+	}
+	return b, nil
+}
+
+// setPosition loads req on the engine, keeping the move history for repetition detection.
+func setPosition(e *search.Engine, req *PositionRequest) error {
+	b, err := loadPosition(&PositionRequest{FEN: req.FEN})
+	if err != nil {
+		return err
+	}
+	e.Board = b
+	if !e.PlayMovesUCI(strings.Join(req.Moves, " ")) {
+		return errors.New("illegal move in moves")
+	}
+	return nil
+}
+
+func newInfo(info *search.Info) Info {
+	out := Info{
+		PV:       make([]string, len(info.PV)),
+		Depth:    info.Depth,
+		SelDepth: info.SelDepth,
+		Nodes:    info.Nodes,
+		NPS:      info.NPS,
+		Time:     info.Time.Milliseconds(),
+	}
+	for i, m := range info.PV {
+		out.PV[i] = m.String()
+	}
+	if dist, ok := search.MateDistance(info.Score); ok {
+		out.Score.Mate = &dist
+	} else {
+		cp := int(info.Score)
+		out.Score.CP = &cp
+	}
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{err.Error()})
+}
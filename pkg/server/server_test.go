@@ -0,0 +1,100 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(New(NewPool(2, 1)))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func post(t *testing.T, ts *httptest.Server, path, body string, v any) int {
+	t.Helper()
+	resp, err := http.Post(ts.URL+path, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+func TestAnalyze(t *testing.T) {
+	ts := newTestServer(t)
+
+	var resp AnalyzeResponse
+	status := post(t, ts, "/analyze", `{"fen":"7k/5Q2/6K1/8/8/8/8/8 w - - 0 1","depth":3}`, &resp)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "f7h7", resp.BestMove)
+	if assert.NotNil(t, resp.Score.Mate) {
+		assert.Equal(t, 1, *resp.Score.Mate)
+	}
+
+	var errResp errorResponse
+	status = post(t, ts, "/analyze", `{"moves":["e2e5"],"depth":1}`, &errResp)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.NotEmpty(t, errResp.Error)
+}
+
+func TestAnalyzeStream(t *testing.T) {
+	ts := newTestServer(t)
+
+	resp, err := http.Get(ts.URL + "/analyze/stream?moves=e2e4+e7e5&depth=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	var events []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if event, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+			events = append(events, event)
+		}
+	}
+	assert.Equal(t, []string{"info", "info", "info", "info", "bestmove"}, events)
+}
+
+func TestPerft(t *testing.T) {
+	ts := newTestServer(t)
+
+	var resp PerftResponse
+	status := post(t, ts, "/perft", `{"fen":"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1","depth":3}`, &resp)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, int64(97862), resp.Nodes)
+	assert.Len(t, resp.Moves, 48)
+}
+
+func TestEvalAndLegal(t *testing.T) {
+	ts := newTestServer(t)
+
+	var eval EvalResponse
+	status := post(t, ts, "/eval", `{"fen":"startpos"}`, &eval)
+	assert.Equal(t, http.StatusOK, status)
+	assert.NotEmpty(t, eval.Terms)
+
+	resp, err := http.Get(ts.URL + "/legal?moves=f2f3+e7e5+g2g4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var legal LegalResponse
+	if err := json.NewDecoder(resp.Body).Decode(&legal); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, legal.Moves, 30)
+	assert.Contains(t, legal.Moves, "d8h4")
+}
@@ -0,0 +1,163 @@
+package stats
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+	"github.com/likeawizard/tofiks/pkg/pgn"
+)
+
+// terminationTime is the PGN Termination of games lost on time.
+const terminationTime = "time forfeit"
+
+// Game is the result of one game.
+type Game struct {
+	White, Black string
+	// Opening identifies the opening the game was played from.
+	Opening     string
+	Termination string
+	// Score is the result for white: 1, 0.5 or 0.
+	Score float64
+}
+
+// FromPGN converts the finished games of a PGN file. The opening of a game is
+// its start position followed by its first bookPlies moves.
+func FromPGN(games []pgn.Game, bookPlies int) []Game {
+	var out []Game
+	for _, g := range games {
+		result, ok := pgn.Score(g.Result)
+		if !ok {
+			continue
+		}
+		opening := g.StartFEN
+		if opening == board.StartPos {
+			opening = board.StartingFEN
+		}
+		if n := min(bookPlies, len(g.Moves)); n > 0 {
+			opening += " " + strings.Join(g.Moves[:n], " ")
+		}
+		out = append(out, Game{
+			White:       g.Headers["White"],
+			Black:       g.Headers["Black"],
+			Opening:     opening,
+			Termination: g.Headers["Termination"],
+			Score:       float64(result+1) / 2,
+		})
+	}
+	return out
+}
+
+// Breakdown is the result of the engine in a group of games.
+type Breakdown struct {
+	Name string
+	WDL  WDL
+}
+
+// Report summarizes the games of one engine against its opponents.
+type Report struct {
+	Engine    string
+	Opponents []string
+	WDL       WDL
+	// Penta holds the pairs of games played from the same opening against the
+	// same opponent with colors reversed. Unpaired counts the games left.
+	Penta    Pentanomial
+	Unpaired int
+	// Openings and Terminations break the results down by opening and by PGN
+	// Termination, ordered by name.
+	Openings     []Breakdown
+	Terminations []Breakdown
+	// TimeLosses and OpponentTimeLosses count the games lost on time.
+	TimeLosses         int
+	OpponentTimeLosses int
+}
+
+// Paired reports whether the games were played in pairs with colors
+// reversed, as fastchess -repeat does: at least 90% of them pair up.
+func (r *Report) Paired() bool {
+	games := r.WDL.Games()
+	return games > 0 && 20*r.Penta.Pairs() >= 9*games
+}
+
+// Analyze reports the results of engine in the games it played.
+func Analyze(games []Game, engine string) Report {
+	r := Report{Engine: engine}
+	type pairKey struct {
+		opening, opponent string
+	}
+	// waiting holds, per opening and opponent, the scores of games not paired
+	// yet, by the color the engine played.
+	waiting := make(map[pairKey]*[2][]float64)
+	openings := make(map[string]*WDL)
+	terminations := make(map[string]*WDL)
+	opponents := make(map[string]bool)
+	add := func(m map[string]*WDL, key string, score float64) {
+		if m[key] == nil {
+			m[key] = &WDL{}
+		}
+		m[key].Add(score)
+	}
+
+	for _, g := range games {
+		var score float64
+		var color int
+		var opponent string
+		switch engine {
+		case g.White:
+			score, color, opponent = g.Score, 0, g.Black
+		case g.Black:
+			score, color, opponent = 1-g.Score, 1, g.White
+		default:
+			continue
+		}
+		opponents[opponent] = true
+		r.WDL.Add(score)
+		add(openings, g.Opening, score)
+		termination := g.Termination
+		if termination == "" {
+			termination = "unknown"
+		}
+		add(terminations, termination, score)
+		if termination == terminationTime {
+			if score < 0.5 {
+				r.TimeLosses++
+			} else if score > 0.5 {
+				r.OpponentTimeLosses++
+			}
+		}
+
+		k := pairKey{g.Opening, opponent}
+		w := waiting[k]
+		if w == nil {
+			w = &[2][]float64{}
+			waiting[k] = w
+		}
+		if other := w[1-color]; len(other) > 0 {
+			r.Penta.Add(score + other[0])
+			w[1-color] = other[1:]
+		} else {
+			w[color] = append(w[color], score)
+		}
+	}
+	for _, w := range waiting {
+		r.Unpaired += len(w[0]) + len(w[1])
+	}
+
+	for name := range opponents {
+		r.Opponents = append(r.Opponents, name)
+	}
+	slices.Sort(r.Opponents)
+	r.Openings = breakdowns(openings)
+	r.Terminations = breakdowns(terminations)
+	return r
+}
+
+func breakdowns(m map[string]*WDL) []Breakdown {
+	out := make([]Breakdown, 0, len(m))
+	for name, wdl := range m {
+		out = append(out, Breakdown{Name: name, WDL: *wdl})
+	}
+	slices.SortFunc(out, func(a, b Breakdown) int { return cmp.Compare(a.Name, b.Name) })
+	return out
+}
@@ -0,0 +1,74 @@
+package stats
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/likeawizard/tofiks/pkg/pgn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWDL(t *testing.T) {
+	w := WDL{Wins: 30, Draws: 50, Losses: 20}
+	assert.Equal(t, 100, w.Games())
+	assert.InDelta(t, 0.5, w.DrawRatio(), 1e-9)
+	assert.InDelta(t, 0.55, w.Score(), 1e-9)
+	elo, margin := w.Elo()
+	assert.InDelta(t, 34.8601, elo, 1e-3)
+	assert.InDelta(t, 48.4702, margin, 1e-3)
+	assert.InDelta(t, 0.27255, w.LLR(0, 5), 1e-4)
+	assert.InDelta(t, 0.92344, w.LOS(), 1e-4)
+
+	p := Pentanomial{5, 20, 40, 25, 10}
+	assert.InDelta(t, 0.93054, p.LOS(), 1e-4)
+	assert.InDelta(t, 0.5, (&Pentanomial{}).LOS(), 1e-9)
+}
+
+const games = `[White "a"]
+[Black "b"]
+[Result "1-0"]
+[Termination "normal"]
+
+1. e4 e5 2. Nf3 1-0
+
+[White "b"]
+[Black "a"]
+[Result "1/2-1/2"]
+
+1. e4 e5 2. Nc3 1/2-1/2
+
+[White "a"]
+[Black "b"]
+[Result "0-1"]
+[Termination "time forfeit"]
+
+1. d4 d5 0-1
+
+[White "c"]
+[Black "a"]
+[Result "*"]
+
+1. c4 *
+`
+
+func TestAnalyze(t *testing.T) {
+	parsed, err := pgn.Parse(strings.NewReader(games))
+	assert.NoError(t, err)
+	g := FromPGN(parsed, 2)
+	assert.Len(t, g, 3)
+
+	r := Analyze(g, "a")
+	assert.Equal(t, []string{"b"}, r.Opponents)
+	assert.Equal(t, WDL{Wins: 1, Draws: 1, Losses: 1}, r.WDL)
+	assert.Equal(t, Pentanomial{0, 0, 0, 1, 0}, r.Penta)
+	assert.Equal(t, 1, r.Unpaired)
+	assert.False(t, r.Paired())
+	assert.Len(t, r.Openings, 2)
+	assert.Equal(t, []Breakdown{
+		{Name: "normal", WDL: WDL{Wins: 1}},
+		{Name: "time forfeit", WDL: WDL{Losses: 1}},
+		{Name: "unknown", WDL: WDL{Draws: 1}},
+	}, r.Terminations)
+	assert.Equal(t, 1, r.TimeLosses)
+	assert.Equal(t, 0, r.OpponentTimeLosses)
+}
@@ -0,0 +1,233 @@
+// Package stats estimates Elo differences from game results and runs the
+// sequential probability ratio test (SPRT) on them.
+package stats
+
+import "math"
+
+// z95 is the normal quantile of a two-sided 95% confidence interval.
+const z95 = 1.959964
+
+// sample is the mean and variance of per-game scores over n independent
+// observations: games, or game pairs normalized to one game.
+type sample struct {
+	n, mean, variance float64
+}
+
+// newSample computes the moments of scores observed counts times each.
+func newSample(scores []float64, counts []int) sample {
+	var s sample
+	for _, c := range counts {
+		s.n += float64(c)
+	}
+	if s.n == 0 {
+		return sample{mean: 0.5}
+	}
+	for i, c := range counts {
+		s.mean += float64(c) * scores[i]
+	}
+	s.mean /= s.n
+	for i, c := range counts {
+		d := scores[i] - s.mean
+		s.variance += float64(c) * d * d
+	}
+	s.variance /= s.n
+	return s
+}
+
+func (s sample) stderr() float64 {
+	return math.Sqrt(s.variance / s.n)
+}
+
+func (s sample) elo() (elo, margin float64) {
+	if s.n == 0 {
+		return 0, math.Inf(1)
+	}
+	se := s.stderr()
+	lower, upper := EloFromScore(s.mean-z95*se), EloFromScore(s.mean+z95*se)
+	return EloFromScore(s.mean), (upper - lower) / 2
+}
+
+func (s sample) llr(elo0, elo1 float64) float64 {
+	if s.variance == 0 {
+		return 0
+	}
+	s0, s1 := ScoreFromElo(elo0), ScoreFromElo(elo1)
+	return s.n * (s1 - s0) * (2*s.mean - s0 - s1) / (2 * s.variance)
+}
+
+func (s sample) los() float64 {
+	if s.variance == 0 {
+		switch {
+		case s.mean > 0.5:
+			return 1
+		case s.mean < 0.5:
+			return 0
+		}
+		return 0.5
+	}
+	return 0.5 * (1 + math.Erf((s.mean-0.5)/(s.stderr()*math.Sqrt2)))
+}
+
+var (
+	pentaScores = []float64{0, 0.25, 0.5, 0.75, 1}
+	wdlScores   = []float64{1, 0.5, 0}
+)
+
+// Pentanomial counts game pairs, played from the same opening with colors
+// reversed, by the points the first engine scored in them: 0, 0.5, 1, 1.5
+// or 2.
+type Pentanomial [5]int
+
+// Add records a pair in which the first engine scored points.
+func (p *Pentanomial) Add(points float64) {
+	p[int(math.Round(points*2))]++
+}
+
+// Pairs returns the number of pairs recorded.
+func (p *Pentanomial) Pairs() int {
+	return p[0] + p[1] + p[2] + p[3] + p[4]
+}
+
+func (p *Pentanomial) sample() sample {
+	return newSample(pentaScores, p[:])
+}
+
+// Score returns the mean score per game of the first engine.
+func (p *Pentanomial) Score() float64 {
+	return p.sample().mean
+}
+
+// Elo returns the Elo difference of the first engine and the half width of
+// its 95% confidence interval.
+func (p *Pentanomial) Elo() (elo, margin float64) {
+	return p.sample().elo()
+}
+
+// LLR returns the log-likelihood ratio of the hypothesis that the Elo
+// difference is elo1 against elo0, using the normal approximation of the
+// generalized SPRT. Elo is logistic.
+func (p *Pentanomial) LLR(elo0, elo1 float64) float64 {
+	return p.sample().llr(elo0, elo1)
+}
+
+// LOS returns the likelihood of superiority: the probability that the first
+// engine is stronger.
+func (p *Pentanomial) LOS() float64 {
+	return p.sample().los()
+}
+
+// WDL counts the wins, draws and losses of the first engine. Unlike a
+// Pentanomial it treats games as independent, which overstates the error when
+// games are paired by opening.
+type WDL struct {
+	Wins, Draws, Losses int
+}
+
+// Add records a game in which the first engine scored 1, 0.5 or 0.
+func (w *WDL) Add(score float64) {
+	switch {
+	case score > 0.5:
+		w.Wins++
+	case score < 0.5:
+		w.Losses++
+	default:
+		w.Draws++
+	}
+}
+
+// Games returns the number of games recorded.
+func (w *WDL) Games() int {
+	return w.Wins + w.Draws + w.Losses
+}
+
+// DrawRatio returns the fraction of games drawn.
+func (w *WDL) DrawRatio() float64 {
+	if w.Games() == 0 {
+		return 0
+	}
+	return float64(w.Draws) / float64(w.Games())
+}
+
+func (w *WDL) sample() sample {
+	return newSample(wdlScores, []int{w.Wins, w.Draws, w.Losses})
+}
+
+// Score returns the mean score per game of the first engine.
+func (w *WDL) Score() float64 {
+	return w.sample().mean
+}
+
+// Elo returns the Elo difference of the first engine and the half width of
+// its 95% confidence interval.
+func (w *WDL) Elo() (elo, margin float64) {
+	return w.sample().elo()
+}
+
+// LLR returns the trinomial log-likelihood ratio of elo1 against elo0.
+func (w *WDL) LLR(elo0, elo1 float64) float64 {
+	return w.sample().llr(elo0, elo1)
+}
+
+// LOS returns the likelihood of superiority of the first engine.
+func (w *WDL) LOS() float64 {
+	return w.sample().los()
+}
+
+// EloFromScore converts an expected score to an Elo difference.
+func EloFromScore(score float64) float64 {
+	switch {
+	case score <= 0:
+		return math.Inf(-1)
+	case score >= 1:
+		return math.Inf(1)
+	}
+	return 400 * math.Log10(score/(1-score))
+}
+
+// ScoreFromElo converts an Elo difference to an expected score.
+func ScoreFromElo(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
+
+// SPRT tests H0: Elo = Elo0 against H1: Elo = Elo1 with false positive rate
+// Alpha and false negative rate Beta.
+type SPRT struct {
+	Elo0, Elo1  float64
+	Alpha, Beta float64
+}
+
+// Bounds returns the LLR below which H0 and above which H1 is accepted.
+func (s SPRT) Bounds() (lower, upper float64) {
+	return math.Log(s.Beta / (1 - s.Alpha)), math.Log((1 - s.Beta) / s.Alpha)
+}
+
+// Verdict is the state of an SPRT.
+type Verdict int
+
+const (
+	Continue Verdict = iota
+	AcceptH0
+	AcceptH1
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case AcceptH0:
+		return "H0 accepted"
+	case AcceptH1:
+		return "H1 accepted"
+	}
+	return "continue"
+}
+
+// Test returns the verdict for an LLR.
+func (s SPRT) Test(llr float64) Verdict {
+	lower, upper := s.Bounds()
+	switch {
+	case llr <= lower:
+		return AcceptH0
+	case llr >= upper:
+		return AcceptH1
+	}
+	return Continue
+}
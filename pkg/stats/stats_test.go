@@ -0,0 +1,53 @@
+package stats
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPentanomial(t *testing.T) {
+	tests := []struct {
+		penta  Pentanomial
+		score  float64
+		elo    float64
+		margin float64
+		llr    float64
+	}{
+		{Pentanomial{10, 20, 40, 20, 10}, 0.5, 0, 37.4421, -0.0345},
+		{Pentanomial{5, 20, 40, 25, 10}, 0.5375, 26.1067, 34.8264, 0.3798},
+	}
+	for _, tt := range tests {
+		assert.InDelta(t, tt.score, tt.penta.Score(), 1e-9)
+		elo, margin := tt.penta.Elo()
+		assert.InDelta(t, tt.elo, elo, 1e-3)
+		assert.InDelta(t, tt.margin, margin, 1e-3)
+		assert.InDelta(t, tt.llr, tt.penta.LLR(0, 5), 1e-3)
+	}
+
+	var p Pentanomial
+	for _, points := range []float64{0, 0.5, 1, 1, 1.5, 2} {
+		p.Add(points)
+	}
+	assert.Equal(t, Pentanomial{1, 1, 2, 1, 1}, p)
+	assert.Equal(t, 6, p.Pairs())
+}
+
+func TestEloScore(t *testing.T) {
+	for _, elo := range []float64{-400, -35, 0, 12.5, 800} {
+		assert.InDelta(t, elo, EloFromScore(ScoreFromElo(elo)), 1e-9)
+	}
+	assert.True(t, math.IsInf(EloFromScore(1), 1))
+	assert.True(t, math.IsInf(EloFromScore(0), -1))
+}
+
+func TestSPRT(t *testing.T) {
+	s := SPRT{Elo0: 0, Elo1: 5, Alpha: 0.05, Beta: 0.05}
+	lower, upper := s.Bounds()
+	assert.InDelta(t, -2.9444, lower, 1e-4)
+	assert.InDelta(t, 2.9444, upper, 1e-4)
+	assert.Equal(t, Continue, s.Test(0))
+	assert.Equal(t, AcceptH0, s.Test(-3))
+	assert.Equal(t, AcceptH1, s.Test(3))
+}
@@ -0,0 +1,61 @@
+package texel
+
+import (
+	"github.com/likeawizard/tofiks/pkg/board"
+	"github.com/likeawizard/tofiks/pkg/eval"
+)
+
+// Term names a contiguous block of the weight vector that belongs to one
+// evaluation concept.
+type Term struct {
+	Name  string
+	Start int
+	Count int
+}
+
+// Terms lists the evaluation terms, one per group of eval.Params, in
+// registry order.
+var Terms = func() []Term {
+	var terms []Term
+	for _, p := range eval.Params {
+		if n := len(terms); n > 0 && terms[n-1].Name == p.Group {
+			terms[n-1].Count += p.Size
+			continue
+		}
+		terms = append(terms, Term{p.Group, p.Offset, p.Size})
+	}
+	return terms
+}()
+
+// TermScore is the contribution of a single Term, split by color and by
+// phase. Scores are in centipawns from each color's own point of view.
+type TermScore struct {
+	Name string
+	MG   [2]float64
+	EG   [2]float64
+}
+
+// Breakdown evaluates every Term for both colors with the current eval
+// weights. The pure middlegame and endgame values are reported separately;
+// the engine blends them by b.Phase.
+func Breakdown(b *board.Board) []TermScore {
+	w := InitialParams()
+	mg := traceSides(b, 0)
+	eg := traceSides(b, 256)
+
+	scores := make([]TermScore, len(Terms))
+	for i, term := range Terms {
+		scores[i].Name = term.Name
+		for color := board.White; color <= board.Black; color++ {
+			sign := 1.0
+			if color == board.Black {
+				sign = -1.0
+			}
+			for idx := term.Start; idx < term.Start+term.Count; idx++ {
+				scores[i].MG[color] += sign * mg[color][idx] * w[idx]
+				scores[i].EG[color] += sign * eg[color][idx] * w[idx]
+			}
+		}
+	}
+	return scores
+}
@@ -3,26 +3,54 @@ package texel
 import (
 	"bufio"
 	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
 	"io"
 	"log"
 	"math"
 	"os"
-	"strconv"
-	"strings"
 	"sync"
 
 	"github.com/likeawizard/tofiks/pkg/board"
+	"github.com/likeawizard/tofiks/pkg/eval"
 )
 
-// Binary format per entry:
+// Binary format: the cacheMagic header and the uint64 layoutHash of the
+// weight vector, then per entry:
 //
 //	uint16  count of non-zero coefficients
 //	count × (uint16 index + float32 value)  [6 bytes each, packed]
 //	int16   phase
 //	float32 result
+//	int16   search score, noScore if there is none
+//
+// The coefficient indices are only valid for the parameter layout the cache
+// was built with, so caches of any other layout are rejected.
+
+// cacheMagic starts a cache. Caches of earlier formats start with "TXC2" or
+// with no header at all.
+var cacheMagic = [4]byte{'T', 'X', 'C', '3'}
+
+// ErrCacheLayout is returned for caches built for another parameter layout.
+var ErrCacheLayout = errors.New("cache was built for another parameter layout")
 
-// BuildCache reads a texel data file, computes traces in parallel,
-// and writes a compact binary cache. Returns the number of entries written.
+// layoutHash identifies the weight vector layout: the name, taper and size of
+// every registered parameter.
+func layoutHash() uint64 {
+	h := fnv.New64a()
+	for _, p := range eval.Params {
+		fmt.Fprintf(h, "%s:%d:%d;", p.Name, p.Taper, p.Size)
+	}
+	return h.Sum64()
+}
+
+// noScore marks an entry without a search score.
+const noScore = math.MinInt16
+
+// BuildCache reads a training data file in the format of its extension (see
+// FormatFromPath), computes traces in parallel, and writes a compact binary
+// cache. Returns the number of entries written.
 func BuildCache(dataPath, cachePath string, limit int, workers int) (int, error) {
 	f, err := os.Open(dataPath)
 	if err != nil {
@@ -36,10 +64,18 @@ func BuildCache(dataPath, cachePath string, limit int, workers int) (int, error)
 	}
 	defer out.Close()
 	bw := bufio.NewWriterSize(out, 1<<20)
+	var header [12]byte
+	copy(header[:], cacheMagic[:])
+	binary.LittleEndian.PutUint64(header[4:], layoutHash())
+	if _, err := bw.Write(header[:]); err != nil {
+		return 0, err
+	}
 
 	type rawEntry struct {
-		fen    string
+		b      *board.Board
 		result float64
+		score  int16
+		scored bool
 	}
 
 	const batchSize = 4096
@@ -76,13 +112,13 @@ func BuildCache(dataPath, cachePath string, limit int, workers int) (int, error)
 			for raws := range rawCh {
 				processed := make([]processedEntry, len(raws))
 				for i, r := range raws {
-					b := board.NewBoard(r.fen)
+					b := r.b
 					if b.InCheck {
 						continue
 					}
 					trace, phase := TraceEvaluate(b)
 					processed[i] = processedEntry{
-						entry: Entry{Trace: trace, Phase: phase, Result: r.result},
+						entry: Entry{Trace: trace, Phase: phase, Result: r.result, Score: float64(r.score), HasScore: r.scored},
 						valid: true,
 					}
 				}
@@ -91,30 +127,26 @@ func BuildCache(dataPath, cachePath string, limit int, workers int) (int, error)
 		})
 	}
 
-	// Reader — scan file and dispatch batches.
-	s := bufio.NewScanner(f)
+	// Reader — decode samples and dispatch batches.
+	dr := NewDataReader(f, FormatFromPath(dataPath))
 	var batch []rawEntry
+	var readErr error
 	totalRead := 0
-	for s.Scan() {
-		line := s.Text()
-		scoreStr, fen, found := strings.Cut(line, " ")
-		if !found {
-			continue
-		}
-		result, err := strconv.ParseFloat(scoreStr, 64)
+	for limit <= 0 || totalRead < limit {
+		sample, err := dr.Read()
 		if err != nil {
-			continue
+			if err != io.EOF {
+				readErr = err
+			}
+			break
 		}
-		batch = append(batch, rawEntry{fen: fen, result: result})
+		batch = append(batch, rawEntry{b: sample.Board, result: sample.Result, score: sample.Score, scored: sample.HasScore})
 		totalRead++
 
 		if len(batch) >= batchSize {
 			rawCh <- batch
 			batch = nil
 		}
-		if limit > 0 && totalRead >= limit {
-			break
-		}
 	}
 	if len(batch) > 0 {
 		rawCh <- batch
@@ -124,6 +156,9 @@ func BuildCache(dataPath, cachePath string, limit int, workers int) (int, error)
 	close(resultCh)
 	writeWg.Wait()
 
+	if readErr != nil {
+		return count, readErr
+	}
 	return count, nil
 }
 
@@ -151,7 +186,12 @@ func writeEntry(w io.Writer, e *Entry) error {
 	}
 
 	binary.LittleEndian.PutUint32(buf[:4], math.Float32bits(float32(e.Result)))
-	_, err := w.Write(buf[:4])
+	score := int16(noScore)
+	if e.HasScore {
+		score = int16(max(min(e.Score, math.MaxInt16), noScore+1))
+	}
+	binary.LittleEndian.PutUint16(buf[4:6], uint16(score))
+	_, err := w.Write(buf[:6])
 	return err
 }
 
@@ -161,11 +201,36 @@ type cacheReader struct {
 	buf []byte // scratch buffer, grown as needed
 }
 
-func newCacheReader(r io.Reader) *cacheReader {
-	return &cacheReader{
+// newCacheReader reads the cache header, returning ErrCacheLayout unless the
+// cache was built for the current parameter layout.
+func newCacheReader(r io.Reader) (*cacheReader, error) {
+	cr := &cacheReader{
 		br:  bufio.NewReaderSize(r, 1<<20),
 		buf: make([]byte, 1024),
 	}
+	var header [12]byte
+	if _, err := io.ReadFull(cr.br, header[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrCacheLayout
+		}
+		return nil, err
+	}
+	if [4]byte(header[:4]) != cacheMagic || binary.LittleEndian.Uint64(header[4:]) != layoutHash() {
+		return nil, ErrCacheLayout
+	}
+	return cr, nil
+}
+
+// CheckCache reports whether a cache file can be used: it returns
+// ErrCacheLayout if the cache has to be rebuilt for the current parameters.
+func CheckCache(cachePath string) error {
+	f, err := os.Open(cachePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = newCacheReader(f)
+	return err
 }
 
 // readEntry decodes one entry using raw byte reads (no reflection).
@@ -177,7 +242,7 @@ func (cr *cacheReader) readEntry(e *Entry) error {
 	count := int(binary.LittleEndian.Uint16(cr.buf[:2]))
 
 	// Read all coefficients in one bulk read: count × 6 bytes.
-	need := count*6 + 6 // +6 for phase(2) + result(4)
+	need := count*6 + 8 // +8 for phase(2) + result(4) + score(2)
 	if len(cr.buf) < need {
 		cr.buf = make([]byte, need)
 	}
@@ -202,6 +267,11 @@ func (cr *cacheReader) readEntry(e *Entry) error {
 	e.Phase = int(int16(binary.LittleEndian.Uint16(cr.buf[off : off+2])))
 	off += 2
 	e.Result = float64(math.Float32frombits(binary.LittleEndian.Uint32(cr.buf[off : off+4])))
+	off += 4
+	e.Score, e.HasScore = 0, false
+	if score := int16(binary.LittleEndian.Uint16(cr.buf[off : off+2])); score != noScore {
+		e.Score, e.HasScore = float64(score), true
+	}
 
 	return nil
 }
@@ -209,15 +279,24 @@ func (cr *cacheReader) readEntry(e *Entry) error {
 // ForEachEntry streams through a cache file, calling fn for each entry.
 // Memory usage is O(1) — only one entry is in memory at a time.
 func ForEachEntry(cachePath string, fn func(*Entry)) error {
+	return forEachEntryN(cachePath, -1, fn)
+}
+
+// forEachEntryN is ForEachEntry for the first n entries, or all of them if n
+// is negative.
+func forEachEntryN(cachePath string, n int, fn func(*Entry)) error {
 	f, err := os.Open(cachePath)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
-	cr := newCacheReader(f)
+	cr, err := newCacheReader(f)
+	if err != nil {
+		return err
+	}
 
 	var e Entry
-	for {
+	for ; n != 0; n-- {
 		err := cr.readEntry(&e)
 		if err == io.EOF {
 			return nil
@@ -227,6 +306,7 @@ func ForEachEntry(cachePath string, fn func(*Entry)) error {
 		}
 		fn(&e)
 	}
+	return nil
 }
 
 // CountEntries returns the number of entries in a cache file.
@@ -0,0 +1,110 @@
+package texel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrCheckpointLayout is returned for checkpoints of another parameter layout.
+var ErrCheckpointLayout = errors.New("checkpoint was written for another parameter layout")
+
+// TuneState is the state of a StreamOptimize run. Saved as a checkpoint it
+// lets an interrupted run resume where it stopped, with the same split of the
+// cache into training and validation entries.
+type TuneState struct {
+	// Layout is the layoutHash of the weight vector.
+	Layout    uint64  `json:"layout"`
+	Iteration int     `json:"iteration"`
+	K         float64 `json:"k"`
+	Lambda    float64 `json:"lambda"`
+	// Split divides the cache into the Train entries the weights are fitted
+	// to and the Valid entries held out to measure overfitting.
+	Split Split `json:"split"`
+	Train int   `json:"train"`
+	Valid int   `json:"valid"`
+	// MSE is the training MSE before the last update.
+	MSE     float64            `json:"mse"`
+	Weights [NumParams]float64 `json:"weights"`
+	// Initial holds the weights the run started from.
+	Initial [NumParams]float64 `json:"initial"`
+	// M and V are the Adam moment estimates.
+	M [NumParams]float64 `json:"m"`
+	V [NumParams]float64 `json:"v"`
+	// Best holds the weights with the lowest validation MSE, BestMSE, found
+	// after BestIteration updates. It is nil without a validation set.
+	Best          *[NumParams]float64 `json:"best,omitempty"`
+	BestMSE       float64             `json:"best_mse"`
+	BestIteration int                 `json:"best_iteration"`
+	// Constraints restrict the updates of the run.
+	Constraints *Constraints `json:"constraints"`
+}
+
+// NewTuneState starts a run from weights on a cache of n entries, holding out
+// those split selects for validation. Nil constraints only keep the weights
+// within the bounds of their parameters.
+func NewTuneState(weights *[NumParams]float64, k, lambda float64, n int, split Split, c *Constraints) *TuneState {
+	if c == nil {
+		c = NewConstraints()
+	}
+	train, valid := split.Count(n)
+	return &TuneState{
+		Layout:  layoutHash(),
+		K:       k,
+		Lambda:  lambda,
+		Split:   split,
+		Train:   train,
+		Valid:   valid,
+		Weights: *weights,
+		Initial: *weights,
+
+		Constraints: c,
+	}
+}
+
+// Result returns the tuned weights: those with the best validation MSE, or
+// the latest ones without a validation set.
+func (s *TuneState) Result() *[NumParams]float64 {
+	if s.Best != nil {
+		return s.Best
+	}
+	return &s.Weights
+}
+
+// Save writes the checkpoint, replacing the old one only once the new one is
+// complete.
+func (s *TuneState) Save(path string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadCheckpoint reads a checkpoint written by TuneState.Save. Checkpoints of
+// another parameter layout are rejected with ErrCheckpointLayout.
+func LoadCheckpoint(path string) (*TuneState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s TuneState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if s.Layout != layoutHash() {
+		return nil, fmt.Errorf("%s: %w", path, ErrCheckpointLayout)
+	}
+	if s.Valid > 0 && s.Split.Valid == 0 {
+		return nil, fmt.Errorf("%s holds out the last entries of the cache: start a new run", path)
+	}
+	if s.Constraints == nil {
+		s.Constraints = NewConstraints()
+	}
+	return &s, nil
+}
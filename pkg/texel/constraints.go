@@ -0,0 +1,215 @@
+package texel
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/likeawizard/tofiks/pkg/eval"
+)
+
+// Constraints restrict how the optimizer moves the weights away from their
+// initial values. They are saved with the TuneState of a run.
+type Constraints struct {
+	// Frozen weights keep their initial values.
+	Frozen [NumParams]bool `json:"frozen"`
+	// Min and Max bound each weight. They start as the registry bounds.
+	Min [NumParams]float64 `json:"min"`
+	Max [NumParams]float64 `json:"max"`
+	// L1 and L2 weigh the penalty L1*|w-w0| + L2*(w-w0)² on the distance of
+	// each weight from its initial value w0, added to the MSE to descend.
+	L1 float64 `json:"l1"`
+	L2 float64 `json:"l2"`
+	// Runs are the sequences of weights Monotone keeps in order.
+	Runs []run `json:"monotone,omitempty"`
+}
+
+// run is a sequence of N weights, Stride apart from From on, that may not
+// decrease (Dir 1) or increase (Dir -1).
+type run struct {
+	From   int     `json:"from"`
+	N      int     `json:"n"`
+	Stride int     `json:"stride"`
+	Dir    float64 `json:"dir"`
+}
+
+// NewConstraints returns constraints that only keep the weights within the
+// bounds of their parameters.
+func NewConstraints() *Constraints {
+	c := &Constraints{}
+	for _, p := range eval.Params {
+		for i := range p.Size {
+			c.Min[p.Offset+i], c.Max[p.Offset+i] = float64(p.Min), float64(p.Max)
+		}
+	}
+	return c
+}
+
+// Equal reports whether c and o constrain the weights the same way.
+func (c *Constraints) Equal(o *Constraints) bool {
+	return c.Frozen == o.Frozen && c.Min == o.Min && c.Max == o.Max &&
+		c.L1 == o.L1 && c.L2 == o.L2 && slices.Equal(c.Runs, o.Runs)
+}
+
+// param returns a registered parameter or an error naming it.
+func param(name string) (*eval.Param, error) {
+	p := eval.ParamByName(name)
+	if p == nil {
+		return nil, fmt.Errorf("unknown parameter %s", name)
+	}
+	return p, nil
+}
+
+// Freeze freezes a parameter, or every parameter of a group such as "PST" or
+// "Material".
+func (c *Constraints) Freeze(name string) error {
+	found := false
+	for _, p := range eval.Params {
+		if p.Name == name || p.Group == name {
+			for i := range p.Size {
+				c.Frozen[p.Offset+i] = true
+			}
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("unknown parameter or group %s", name)
+	}
+	return nil
+}
+
+// Clamp bounds every weight of a parameter to [lo, hi].
+func (c *Constraints) Clamp(name string, lo, hi int) error {
+	p, err := param(name)
+	if err != nil {
+		return err
+	}
+	if lo > hi {
+		return fmt.Errorf("%s: empty range [%d, %d]", name, lo, hi)
+	}
+	for i := range p.Size {
+		c.Min[p.Offset+i], c.Max[p.Offset+i] = float64(lo), float64(hi)
+	}
+	return nil
+}
+
+// Monotone keeps the values of an array parameter from decreasing along the
+// array (dir 1) or from increasing (dir -1). The middlegame and endgame
+// values of a Tapered array are each kept in order on their own.
+func (c *Constraints) Monotone(name string, dir int) error {
+	p, err := param(name)
+	if err != nil {
+		return err
+	}
+	if p.Dims == nil {
+		return fmt.Errorf("%s is not an array", name)
+	}
+	if dir != 1 && dir != -1 {
+		return fmt.Errorf("%s: direction %d is not 1 or -1", name, dir)
+	}
+	if p.Taper == eval.Tapered {
+		c.Runs = append(c.Runs,
+			run{p.Offset, p.Size / 2, 2, float64(dir)},
+			run{p.Offset + 1, p.Size / 2, 2, float64(dir)})
+	} else {
+		c.Runs = append(c.Runs, run{p.Offset, p.Size, 1, float64(dir)})
+	}
+	return nil
+}
+
+// Load adds the constraints a parameter file sets.
+func (c *Constraints) Load(f *eval.ParamFile) error {
+	for _, pv := range f.Params {
+		if pv.Freeze {
+			if err := c.Freeze(pv.Name); err != nil {
+				return err
+			}
+		}
+		if pv.Min != nil || pv.Max != nil {
+			p, err := param(pv.Name)
+			if err != nil {
+				return err
+			}
+			lo, hi := p.Min, p.Max
+			if pv.Min != nil {
+				lo = *pv.Min
+			}
+			if pv.Max != nil {
+				hi = *pv.Max
+			}
+			if err := c.Clamp(pv.Name, lo, hi); err != nil {
+				return err
+			}
+		}
+		if pv.Monotone != 0 {
+			if err := c.Monotone(pv.Name, pv.Monotone); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// regularize adds the gradient of the L1 and L2 penalties to grad and zeroes
+// it for frozen weights.
+func (c *Constraints) regularize(grad, w, initial *[NumParams]float64) {
+	for i := range NumParams {
+		if c.Frozen[i] {
+			grad[i] = 0
+			continue
+		}
+		d := w[i] - initial[i]
+		if d != 0 {
+			grad[i] += c.L1*math.Copysign(1, d) + 2*c.L2*d
+		}
+	}
+}
+
+// project moves the weights to the nearest ones that satisfy the monotone
+// runs and bounds, then restores the frozen weights.
+func (c *Constraints) project(w, initial *[NumParams]float64) {
+	for _, r := range c.Runs {
+		values := make([]float64, r.N)
+		for i := range values {
+			values[i] = r.Dir * w[r.From+i*r.Stride]
+		}
+		isotonic(values)
+		for i, v := range values {
+			w[r.From+i*r.Stride] = r.Dir * v
+		}
+	}
+	for i := range NumParams {
+		w[i] = max(min(w[i], c.Max[i]), c.Min[i])
+		if c.Frozen[i] {
+			w[i] = initial[i]
+		}
+	}
+}
+
+// isotonic replaces values by the closest non-decreasing sequence in the
+// least-squares sense, pooling adjacent violators into their mean.
+func isotonic(values []float64) {
+	type block struct {
+		sum float64
+		n   int
+	}
+	blocks := make([]block, 0, len(values))
+	for _, v := range values {
+		blocks = append(blocks, block{v, 1})
+		for len(blocks) > 1 {
+			a, b := blocks[len(blocks)-2], blocks[len(blocks)-1]
+			if a.sum/float64(a.n) <= b.sum/float64(b.n) {
+				break
+			}
+			blocks = blocks[:len(blocks)-1]
+			blocks[len(blocks)-1] = block{a.sum + b.sum, a.n + b.n}
+		}
+	}
+	i := 0
+	for _, b := range blocks {
+		for range b.n {
+			values[i] = b.sum / float64(b.n)
+			i++
+		}
+	}
+}
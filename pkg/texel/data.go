@@ -1,8 +1,321 @@
 package texel
 
-// Entry holds a pre-computed sparse trace and the game result for one position.
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+)
+
+// Entry holds a pre-computed sparse trace, the game result and the optional
+// search score for one position.
 type Entry struct {
 	Trace  Trace
 	Phase  int
 	Result float64 // 1.0 = white win, 0.5 = draw, 0.0 = black win
+	// Score is the search score from white's point of view, set when HasScore
+	// is.
+	Score    float64
+	HasScore bool
+}
+
+// Target returns what the eval of the entry is fitted to: the result, or with
+// a search score lambda*result + (1-lambda)*Sigmoid(k, score).
+func (e *Entry) Target(k, lambda float64) float64 {
+	if !e.HasScore {
+		return e.Result
+	}
+	return lambda*e.Result + (1-lambda)*Sigmoid(k, e.Score)
+}
+
+// ParseDataLine reads one training position. Lines are either "result fen"
+// with a result from 1 (white wins) to 0, or EPD records with the result in c9
+// as a game result ("1-0", "1/2-1/2", "0-1") or a number.
+func ParseDataLine(line string) (fen string, result float64, ok bool) {
+	if score, rest, found := strings.Cut(line, " "); found {
+		if r, err := strconv.ParseFloat(score, 64); err == nil {
+			return rest, r, true
+		}
+	}
+
+	b, ops, err := board.ParseEPD(line)
+	if err != nil {
+		return "", 0, false
+	}
+	result, ok = epdResult(ops)
+	if !ok {
+		return "", 0, false
+	}
+	return b.ExportFEN(), result, true
+}
+
+// epdResult reads the result in c9.
+func epdResult(ops map[string][]string) (float64, bool) {
+	if len(ops["c9"]) != 1 {
+		return 0, false
+	}
+	switch c9 := ops["c9"][0]; c9 {
+	case "1-0":
+		return 1, true
+	case "1/2-1/2":
+		return 0.5, true
+	case "0-1":
+		return 0, true
+	default:
+		result, err := strconv.ParseFloat(c9, 64)
+		return result, err == nil
+	}
+}
+
+// Format is a training data file format.
+type Format int
+
+const (
+	// FormatText is "result fen" lines. Bullet's "fen | score | result" lines
+	// are read as well. The score is not written.
+	FormatText Format = iota
+	// FormatEPD is EPD records with the result in c9 and the optional score
+	// for the side to move in ce.
+	FormatEPD
+	// FormatMarlin and FormatBullet are packed positions.
+	FormatMarlin
+	FormatBullet
+)
+
+var formats = [...]struct {
+	name, ext string
+}{
+	FormatText:   {"text", ".txt"},
+	FormatEPD:    {"epd", ".epd"},
+	FormatMarlin: {"marlin", ".marlin"},
+	FormatBullet: {"bullet", ".bullet"},
+}
+
+func (f Format) String() string {
+	return formats[f].name
+}
+
+// Ext returns the file extension of the format.
+func (f Format) Ext() string {
+	return formats[f].ext
+}
+
+// Packed reports whether the format is a packed one and its layout.
+func (f Format) Packed() (board.PackedFormat, bool) {
+	switch f {
+	case FormatMarlin:
+		return board.Marlinformat, true
+	case FormatBullet:
+		return board.Bulletformat, true
+	}
+	return 0, false
+}
+
+// ParseFormat returns the format of a name: text, epd, marlin or bullet.
+func ParseFormat(name string) (Format, error) {
+	for f, format := range formats {
+		if format.name == name {
+			return Format(f), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown data format %q", name)
+}
+
+// FormatFromPath returns the format of a file by its extension. Files of an
+// unknown extension are text.
+func FormatFromPath(path string) Format {
+	ext := strings.ToLower(filepath.Ext(path))
+	for f, format := range formats {
+		if format.ext == ext {
+			return Format(f)
+		}
+	}
+	return FormatText
+}
+
+// Sample is a training position with its search score in centipawns from
+// white's point of view, if HasScore is set, and the game result for white
+// from 1 to 0.
+type Sample struct {
+	Board    *board.Board
+	Score    int16
+	HasScore bool
+	Result   float64
+}
+
+// ParseSample reads a line of text or EPD training data.
+func ParseSample(line string) (Sample, bool) {
+	if fen, rest, found := strings.Cut(line, "|"); found {
+		return parseBulletText(fen, rest)
+	}
+	if result, fen, found := strings.Cut(line, " "); found {
+		if r, err := strconv.ParseFloat(result, 64); err == nil {
+			b := &board.Board{}
+			if err := b.ImportFEN(fen); err != nil {
+				return Sample{}, false
+			}
+			return Sample{Board: b, Result: r}, true
+		}
+	}
+
+	b, ops, err := board.ParseEPD(line)
+	if err != nil {
+		return Sample{}, false
+	}
+	s := Sample{Board: b}
+	var ok bool
+	if s.Result, ok = epdResult(ops); !ok {
+		return Sample{}, false
+	}
+	if len(ops["ce"]) == 1 {
+		ce, err := strconv.Atoi(ops["ce"][0])
+		if err != nil {
+			return Sample{}, false
+		}
+		if b.Side == board.Black {
+			ce = -ce
+		}
+		s.Score, s.HasScore = int16(ce), true
+	}
+	return s, true
+}
+
+// parseBulletText reads the fen and " score | result" parts of a
+// "fen | score | result" line.
+func parseBulletText(fen, rest string) (Sample, bool) {
+	score, result, found := strings.Cut(rest, "|")
+	if !found {
+		return Sample{}, false
+	}
+	b := &board.Board{}
+	if err := b.ImportFEN(strings.TrimSpace(fen)); err != nil {
+		return Sample{}, false
+	}
+	cp, err := strconv.Atoi(strings.TrimSpace(score))
+	if err != nil {
+		return Sample{}, false
+	}
+	r, err := strconv.ParseFloat(strings.TrimSpace(result), 64)
+	if err != nil {
+		return Sample{}, false
+	}
+	return Sample{Board: b, Score: int16(cp), HasScore: true, Result: r}, true
+}
+
+// DataReader reads samples from training data.
+type DataReader struct {
+	format Format
+	lines  *bufio.Scanner
+	r      *bufio.Reader
+	buf    [board.PackedSize]byte
+	n      int
+}
+
+// NewDataReader returns a reader of training data in format f.
+func NewDataReader(r io.Reader, f Format) *DataReader {
+	dr := &DataReader{format: f}
+	if _, ok := f.Packed(); ok {
+		dr.r = bufio.NewReaderSize(r, 1<<20)
+	} else {
+		dr.lines = bufio.NewScanner(r)
+	}
+	return dr
+}
+
+// Read returns the next sample, or io.EOF after the last. Text lines that do
+// not hold a position are skipped.
+func (dr *DataReader) Read() (Sample, error) {
+	layout, ok := dr.format.Packed()
+	if !ok {
+		for dr.lines.Scan() {
+			if s, ok := ParseSample(dr.lines.Text()); ok {
+				return s, nil
+			}
+		}
+		if err := dr.lines.Err(); err != nil {
+			return Sample{}, err
+		}
+		return Sample{}, io.EOF
+	}
+
+	if _, err := io.ReadFull(dr.r, dr.buf[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			err = fmt.Errorf("truncated record %d", dr.n+1)
+		}
+		return Sample{}, err
+	}
+	dr.n++
+	p, err := board.UnmarshalPacked(layout, dr.buf[:])
+	if err != nil {
+		return Sample{}, fmt.Errorf("record %d: %w", dr.n, err)
+	}
+	return Sample{Board: p.Board, Score: p.Score, HasScore: true, Result: p.Result()}, nil
+}
+
+// ErrNoScore is returned for a sample without a search score written in a
+// packed format, which stores a score with every position.
+var ErrNoScore = errors.New("packed formats need a search score")
+
+// DataWriter writes samples as training data.
+type DataWriter struct {
+	format Format
+	w      *bufio.Writer
+}
+
+// NewDataWriter returns a writer of training data in format f. Flush must be
+// called after the last sample.
+func NewDataWriter(w io.Writer, f Format) *DataWriter {
+	return &DataWriter{format: f, w: bufio.NewWriterSize(w, 1<<16)}
+}
+
+// Write writes a sample. Packed formats round the result to a win, draw or
+// loss and reject samples without a score with ErrNoScore.
+func (dw *DataWriter) Write(s Sample) error {
+	layout, packed := dw.format.Packed()
+	if packed && !s.HasScore {
+		return ErrNoScore
+	}
+	var err error
+	switch dw.format {
+	case FormatText:
+		_, err = fmt.Fprintf(dw.w, "%s %s\n", strconv.FormatFloat(s.Result, 'f', -1, 64), s.Board.ExportFEN())
+	case FormatEPD:
+		ops := map[string][]string{"c9": {formatResult(s.Result)}}
+		if s.HasScore {
+			ce := s.Score
+			if s.Board.Side == board.Black {
+				ce = -ce
+			}
+			ops["ce"] = []string{strconv.Itoa(int(ce))}
+		}
+		_, err = fmt.Fprintln(dw.w, s.Board.ExportEPD(ops))
+	default:
+		p := board.PackedPosition{Board: s.Board, Score: s.Score, WDL: board.WDLFromResult(s.Result)}
+		rec := p.Marshal(layout)
+		_, err = dw.w.Write(rec[:])
+	}
+	return err
+}
+
+// Flush writes buffered samples to the underlying writer.
+func (dw *DataWriter) Flush() error {
+	return dw.w.Flush()
+}
+
+// formatResult formats a result for the c9 of EPD training data.
+func formatResult(result float64) string {
+	switch result {
+	case 1:
+		return "1-0"
+	case 0.5:
+		return "1/2-1/2"
+	case 0:
+		return "0-1"
+	}
+	return strconv.FormatFloat(result, 'f', -1, 64)
 }
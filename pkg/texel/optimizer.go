@@ -4,7 +4,11 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/likeawizard/tofiks/pkg/eval"
 )
 
 // Sigmoid maps an eval score to a [0, 1] probability.
@@ -18,45 +22,58 @@ func SigmoidPrime(k, x float64) float64 {
 	return s * (1.0 - s) * k * math.Log(10.0) / 400.0
 }
 
-// MeanSquaredError computes the average squared error over all entries.
-func MeanSquaredError(entries []Entry, weights *[NumParams]float64, k float64) float64 {
+// MeanSquaredError computes the average squared error over all entries
+// against their targets (see Entry.Target).
+func MeanSquaredError(entries []Entry, weights *[NumParams]float64, k, lambda float64) float64 {
 	var total float64
 	for i := range entries {
 		e := EvalFromTrace(&entries[i].Trace, weights)
-		diff := entries[i].Result - Sigmoid(k, e)
+		diff := entries[i].Target(k, lambda) - Sigmoid(k, e)
 		total += diff * diff
 	}
 	return total / float64(len(entries))
 }
 
-// StreamMSE computes MSE by streaming through a cache file. O(1) memory.
-func StreamMSE(cachePath string, n int, weights *[NumParams]float64, k float64) float64 {
+// StreamMSE computes MSE over the training entries of a cache file by
+// streaming through it. O(1) memory.
+func StreamMSE(cachePath string, split Split, weights *[NumParams]float64, k, lambda float64) float64 {
 	var total float64
+	i, n := 0, 0
 	if err := ForEachEntry(cachePath, func(e *Entry) {
+		if i++; split.Held(i - 1) {
+			return
+		}
 		ev := EvalFromTrace(&e.Trace, weights)
-		diff := e.Result - Sigmoid(k, ev)
+		diff := e.Target(k, lambda) - Sigmoid(k, ev)
 		total += diff * diff
+		n++
 	}); err != nil {
 		log.Fatalf("StreamMSE: %v", err)
 	}
 	return total / float64(n)
 }
 
-// StreamGradientAndMSE computes both gradient and MSE in a single pass.
-func StreamGradientAndMSE(cachePath string, n int, weights *[NumParams]float64, k float64) ([NumParams]float64, float64) {
-	var grad [NumParams]float64
-	var mseTotal float64
-	nf := float64(n)
+// StreamGradientAndMSE computes both gradient and MSE over the training
+// entries of a cache file in a single pass. The entries split holds out only
+// have their MSE computed, returned as validMSE (0 if there are none).
+func StreamGradientAndMSE(cachePath string, split Split, weights *[NumParams]float64, k, lambda float64) (grad [NumParams]float64, mse, validMSE float64) {
+	var mseTotal, validTotal float64
+	i, train, valid := 0, 0, 0
 
 	if err := ForEachEntry(cachePath, func(e *Entry) {
 		ev := EvalFromTrace(&e.Trace, weights)
 		sig := Sigmoid(k, ev)
-		sigPrime := SigmoidPrime(k, ev)
-
-		diff := e.Result - sig
+		target := e.Target(k, lambda)
+		diff := target - sig
+		if i++; split.Held(i - 1) {
+			validTotal += diff * diff
+			valid++
+			return
+		}
 		mseTotal += diff * diff
+		train++
 
-		factor := 2.0 * (sig - e.Result) * sigPrime / nf
+		factor := 2.0 * (sig - target) * SigmoidPrime(k, ev)
 		for _, c := range e.Trace {
 			grad[c.Index] += factor * float64(c.Value)
 		}
@@ -64,17 +81,26 @@ func StreamGradientAndMSE(cachePath string, n int, weights *[NumParams]float64,
 		log.Fatalf("StreamGradientAndMSE: %v", err)
 	}
 
-	return grad, mseTotal / nf
+	nf := float64(train)
+	for j := range grad {
+		grad[j] /= nf
+	}
+	if valid > 0 {
+		validMSE = validTotal / float64(valid)
+	}
+	return grad, mseTotal / nf, validMSE
 }
 
-// OptimizeK finds the K constant that minimizes MSE on the given entries.
+// OptimizeK finds the K constant that minimizes MSE on the given entries
+// against their game results. Search scores are left out: with them in the
+// target K would shrink towards 0, where eval and score both map to 0.5.
 func OptimizeK(entries []Entry, weights *[NumParams]float64) float64 {
 	lo, hi := 0.0, 10.0
 	for range 100 {
 		m1 := lo + (hi-lo)/3.0
 		m2 := hi - (hi-lo)/3.0
-		e1 := MeanSquaredError(entries, weights, m1)
-		e2 := MeanSquaredError(entries, weights, m2)
+		e1 := MeanSquaredError(entries, weights, m1, 1)
+		e2 := MeanSquaredError(entries, weights, m2, 1)
 		if e1 < e2 {
 			hi = m2
 		} else {
@@ -84,15 +110,16 @@ func OptimizeK(entries []Entry, weights *[NumParams]float64) float64 {
 	return (lo + hi) / 2.0
 }
 
-// StreamOptimizeK finds optimal K by streaming through the cache file.
+// StreamOptimizeK finds optimal K on the training entries by streaming through
+// the cache file. As in OptimizeK only game results count.
 // 30 ternary search steps give precision to ~1e-9, more than enough.
-func StreamOptimizeK(cachePath string, n int, weights *[NumParams]float64) float64 {
+func StreamOptimizeK(cachePath string, split Split, weights *[NumParams]float64) float64 {
 	lo, hi := 0.0, 10.0
 	for range 30 {
 		m1 := lo + (hi-lo)/3.0
 		m2 := hi - (hi-lo)/3.0
-		e1 := StreamMSE(cachePath, n, weights, m1)
-		e2 := StreamMSE(cachePath, n, weights, m2)
+		e1 := StreamMSE(cachePath, split, weights, m1, 1)
+		e2 := StreamMSE(cachePath, split, weights, m2, 1)
 		if e1 < e2 {
 			hi = m2
 		} else {
@@ -120,18 +147,20 @@ func DefaultAdamConfig() AdamConfig {
 	}
 }
 
-// Optimize runs Adam gradient descent on the weight vector.
-func Optimize(entries []Entry, weights *[NumParams]float64, k float64, iterations int, cfg AdamConfig) {
+// Optimize runs Adam gradient descent on the weight vector. lambda weighs the
+// game result against the search score in the target (see Entry.Target).
+func Optimize(entries []Entry, weights *[NumParams]float64, k, lambda float64, iterations int, cfg AdamConfig) {
 	var m, v [NumParams]float64 // First and second moment estimates.
+	c, initial := NewConstraints(), *weights
 
 	log.Printf("Starting optimization: %d params, %d entries, K=%.6f", NumParams, len(entries), k)
-	log.Printf("Initial MSE: %.10f", MeanSquaredError(entries, weights, k))
+	log.Printf("Initial MSE: %.10f", MeanSquaredError(entries, weights, k, lambda))
 
 	for iter := 1; iter <= iterations; iter++ {
 		start := time.Now()
 
 		// Compute analytical gradients.
-		grad := ComputeGradient(entries, weights, k)
+		grad := ComputeGradient(entries, weights, k, lambda)
 
 		// Adam update.
 		t := float64(iter)
@@ -142,16 +171,17 @@ func Optimize(entries []Entry, weights *[NumParams]float64, k float64, iteration
 			vHat := v[i] / (1.0 - math.Pow(cfg.Beta2, t))
 			weights[i] -= cfg.LR * mHat / (math.Sqrt(vHat) + cfg.Epsilon)
 		}
+		c.project(weights, &initial)
 
-		mse := MeanSquaredError(entries, weights, k)
+		mse := MeanSquaredError(entries, weights, k, lambda)
 		elapsed := time.Since(start)
 		log.Printf("Iter %d/%d  MSE: %.10f  (%v)", iter, iterations, mse, elapsed)
 	}
 }
 
 // ComputeGradient computes the analytical gradient of MSE w.r.t. all weights.
-// dMSE/dw_i = (2/N) * Σ (sigmoid(eval) - result) * sigmoid'(eval) * trace[i].
-func ComputeGradient(entries []Entry, weights *[NumParams]float64, k float64) [NumParams]float64 {
+// dMSE/dw_i = (2/N) * Σ (sigmoid(eval) - target) * sigmoid'(eval) * trace[i].
+func ComputeGradient(entries []Entry, weights *[NumParams]float64, k, lambda float64) [NumParams]float64 {
 	var grad [NumParams]float64
 	n := float64(len(entries))
 
@@ -159,7 +189,7 @@ func ComputeGradient(entries []Entry, weights *[NumParams]float64, k float64) [N
 		e := EvalFromTrace(&entries[i].Trace, weights)
 		sig := Sigmoid(k, e)
 		sigPrime := SigmoidPrime(k, e)
-		factor := 2.0 * (sig - entries[i].Result) * sigPrime / n
+		factor := 2.0 * (sig - entries[i].Target(k, lambda)) * sigPrime / n
 
 		for _, c := range entries[i].Trace {
 			grad[c.Index] += factor * float64(c.Value)
@@ -169,175 +199,147 @@ func ComputeGradient(entries []Entry, weights *[NumParams]float64, k float64) [N
 	return grad
 }
 
-// StreamOptimize runs Adam gradient descent by streaming from a cache file.
-// Gradient and MSE are computed in a single pass per iteration.
-// Memory usage is O(NumParams), independent of dataset size.
-// Stops early if dMSE falls below earlyStopThreshold (0 = disabled).
-func StreamOptimize(cachePath string, n int, weights *[NumParams]float64, k float64, iterations int, cfg AdamConfig, earlyStopThreshold float64) {
-	var m, v [NumParams]float64
-
-	log.Printf("Starting optimization: %d params, %d entries, K=%.6f", NumParams, n, k)
+// StreamConfig controls a StreamOptimize run.
+type StreamConfig struct {
+	Adam AdamConfig
+	// Iterations is the number of updates after which the run ends, including
+	// those made before a resume.
+	Iterations int
+	// EarlyStop ends a run without a validation set once |dMSE| falls below
+	// it (0 = disabled).
+	EarlyStop float64
+	// Patience ends a run with a validation set after as many updates without
+	// a new best validation MSE (0 = disabled).
+	Patience int
+	// Checkpoint is the file the state is saved to every CheckpointEvery
+	// updates and at the end of the run (empty = never).
+	Checkpoint      string
+	CheckpointEvery int
+}
 
-	prevMSE := StreamMSE(cachePath, n, weights, k)
-	log.Printf("Initial MSE: %.10f", prevMSE)
+// StreamOptimize runs Adam gradient descent by streaming from a cache file,
+// continuing the run of st. Gradient and MSE are computed in a single pass
+// per iteration, along with the MSE of the validation entries.
+// Memory usage is O(NumParams), independent of dataset size.
+func StreamOptimize(cachePath string, st *TuneState, cfg StreamConfig) error {
+	c := st.Constraints
+	log.Printf("Starting optimization: %d params, %d entries (%d held out), K=%.6f, lambda=%.2f",
+		NumParams, st.Train+st.Valid, st.Valid, st.K, st.Lambda)
+
+	if st.Iteration == 0 {
+		// Start from weights that satisfy the constraints.
+		c.project(&st.Weights, &st.Initial)
+		st.MSE = StreamMSE(cachePath, st.Split, &st.Weights, st.K, st.Lambda)
+		log.Printf("Initial MSE: %.10f", st.MSE)
+	} else {
+		log.Printf("Resuming after iteration %d, MSE: %.10f", st.Iteration, st.MSE)
+	}
 
-	for iter := 1; iter <= iterations; iter++ {
+	for st.Iteration < cfg.Iterations {
 		start := time.Now()
 
-		// Single pass: compute gradient and MSE together.
-		grad, mse := StreamGradientAndMSE(cachePath, n, weights, k)
-		dMSE := prevMSE - mse
+		// Single pass: compute gradient and MSE together. Both measure the
+		// weights before this update.
+		weights := st.Weights
+		grad, mse, validMSE := StreamGradientAndMSE(cachePath, st.Split, &weights, st.K, st.Lambda)
+		dMSE := st.MSE - mse
+		st.MSE = mse
+		if st.Valid > 0 && (st.Best == nil || validMSE < st.BestMSE) {
+			st.Best, st.BestMSE, st.BestIteration = &weights, validMSE, st.Iteration
+		}
+
+		c.regularize(&grad, &st.Weights, &st.Initial)
 
 		// Adam update.
-		t := float64(iter)
+		st.Iteration++
+		t := float64(st.Iteration)
 		for i := range NumParams {
-			m[i] = cfg.Beta1*m[i] + (1.0-cfg.Beta1)*grad[i]
-			v[i] = cfg.Beta2*v[i] + (1.0-cfg.Beta2)*grad[i]*grad[i]
-			mHat := m[i] / (1.0 - math.Pow(cfg.Beta1, t))
-			vHat := v[i] / (1.0 - math.Pow(cfg.Beta2, t))
-			weights[i] -= cfg.LR * mHat / (math.Sqrt(vHat) + cfg.Epsilon)
+			if c.Frozen[i] {
+				continue
+			}
+			st.M[i] = cfg.Adam.Beta1*st.M[i] + (1.0-cfg.Adam.Beta1)*grad[i]
+			st.V[i] = cfg.Adam.Beta2*st.V[i] + (1.0-cfg.Adam.Beta2)*grad[i]*grad[i]
+			mHat := st.M[i] / (1.0 - math.Pow(cfg.Adam.Beta1, t))
+			vHat := st.V[i] / (1.0 - math.Pow(cfg.Adam.Beta2, t))
+			st.Weights[i] -= cfg.Adam.LR * mHat / (math.Sqrt(vHat) + cfg.Adam.Epsilon)
 		}
 
 		// Re-center PSTs: shift mean into piece weights so PSTs stay positional.
-		recenterPSTs(weights)
+		recenterPSTs(&st.Weights, &c.Frozen)
+		c.project(&st.Weights, &st.Initial)
 
 		elapsed := time.Since(start)
-		log.Printf("Iter %d/%d  MSE: %.10f  dMSE: %+.2e  (%v)", iter, iterations, mse, dMSE, elapsed)
+		if st.Valid > 0 {
+			log.Printf("Iter %d/%d  MSE: %.10f  dMSE: %+.2e  valid MSE: %.10f  (%v)", st.Iteration, cfg.Iterations, mse, dMSE, validMSE, elapsed)
+		} else {
+			log.Printf("Iter %d/%d  MSE: %.10f  dMSE: %+.2e  (%v)", st.Iteration, cfg.Iterations, mse, dMSE, elapsed)
+		}
+
+		if cfg.Checkpoint != "" && cfg.CheckpointEvery > 0 && st.Iteration%cfg.CheckpointEvery == 0 {
+			if err := st.Save(cfg.Checkpoint); err != nil {
+				return err
+			}
+		}
 
-		if earlyStopThreshold > 0 && iter > 10 && math.Abs(dMSE) < earlyStopThreshold {
-			log.Printf("Early stop: |dMSE| %.2e < threshold %.2e", math.Abs(dMSE), earlyStopThreshold)
+		if st.Valid > 0 {
+			if stale := st.Iteration - st.BestIteration; cfg.Patience > 0 && stale > cfg.Patience {
+				log.Printf("Early stop: no better validation MSE in %d iterations", stale-1)
+				break
+			}
+		} else if cfg.EarlyStop > 0 && st.Iteration > 10 && math.Abs(dMSE) < cfg.EarlyStop {
+			log.Printf("Early stop: |dMSE| %.2e < threshold %.2e", math.Abs(dMSE), cfg.EarlyStop)
 			break
 		}
+	}
 
-		prevMSE = mse
+	if st.Best != nil {
+		log.Printf("Best validation MSE %.10f after iteration %d", st.BestMSE, st.BestIteration)
+	}
+	if cfg.Checkpoint != "" {
+		return st.Save(cfg.Checkpoint)
 	}
+	return nil
 }
 
-// PrintParams outputs the tuned weights in a format ready to paste into Go source.
+// PrintParams outputs the tuned weights in a format ready to paste into Go
+// source, one block per parameter group.
 func PrintParams(w *[NumParams]float64) {
-	fmt.Println("// === Piece-Square Tables ===")
-	pieceNames := []string{"pawn", "bishop", "knight", "rook", "queen", "king"}
-	stageNames := []string{"", "EG"}
-	for stage := range 2 {
-		for piece := range 6 {
-			fmt.Printf("var %s%sPST = [64]int{\n", pieceNames[piece], stageNames[stage])
-			for sq := range 64 {
-				if sq%8 == 0 {
-					fmt.Print("\t")
-				}
-				fmt.Printf("%d, ", int(math.Round(w[pstIndex(stage, piece, sq)])))
-				if sq%8 == 7 {
-					fmt.Println()
-				}
+	group := ""
+	for _, p := range eval.Params {
+		if p.Group != group {
+			if group != "" {
+				fmt.Println()
 			}
-			fmt.Println("}")
-			fmt.Println()
+			group = p.Group
+			fmt.Printf("// === %s ===\n", group)
 		}
-	}
 
-	fmt.Println("// === Piece Weights ===")
-	fmt.Printf("PieceWeights = [6]int{%d, %d, %d, %d, %d, 10000}\n\n",
-		int(math.Round(w[pieceWeightStart+0])),
-		int(math.Round(w[pieceWeightStart+1])),
-		int(math.Round(w[pieceWeightStart+2])),
-		int(math.Round(w[pieceWeightStart+3])),
-		int(math.Round(w[pieceWeightStart+4])))
-
-	fmt.Println("// === Mobility ===")
-	fmt.Printf("QueenMobility  = %d\n", int(math.Round(w[mobilityStart+0])))
-	fmt.Printf("RookMobility   = %d\n", int(math.Round(w[mobilityStart+1])))
-	fmt.Printf("BishopMobility = %d\n", int(math.Round(w[mobilityStart+2])))
-	fmt.Printf("KnightMobility = %d\n\n", int(math.Round(w[mobilityStart+3])))
-
-	fmt.Println("// === Threats ===")
-	fmt.Printf("QueenThreat  = %d\n", int(math.Round(w[threatStart+0])))
-	fmt.Printf("RookThreat   = %d\n", int(math.Round(w[threatStart+1])))
-	fmt.Printf("BishopThreat = %d\n", int(math.Round(w[threatStart+2])))
-	fmt.Printf("KnightThreat = %d\n\n", int(math.Round(w[threatStart+3])))
-
-	fmt.Println("// === Pawn Structure ===")
-	fmt.Printf("PawnProtected      = %d\n", int(math.Round(w[pawnStructStart+0])))
-	fmt.Printf("PawnDoubled        = %d\n", int(math.Round(w[pawnStructStart+1])))
-	fmt.Printf("PawnIsolated       = %d\n", int(math.Round(w[pawnStructStart+2])))
-	fmt.Printf("PawnBackwardDeep   = %d\n", int(math.Round(w[pawnStructStart+3])))
-	fmt.Printf("PawnBackwardMid    = %d\n", int(math.Round(w[pawnStructStart+4])))
-	fmt.Printf("PawnBackwardOpen   = %d\n", int(math.Round(w[pawnStructStart+5])))
-	fmt.Printf("PawnBlocked        = %d\n", int(math.Round(w[pawnStructStart+6])))
-	fmt.Printf("PawnConnectedPasser = %d\n", int(math.Round(w[pawnStructStart+7])))
-	fmt.Printf("PawnCandidate      = %d\n\n", int(math.Round(w[pawnStructStart+8])))
-
-	fmt.Println("// === Passed Pawn Bonus ===")
-	fmt.Printf("PassedPawnBonus = [8]int{0, %d, %d, %d, %d, %d, %d, 0}\n\n",
-		int(math.Round(w[passedPawnStart+0])),
-		int(math.Round(w[passedPawnStart+1])),
-		int(math.Round(w[passedPawnStart+2])),
-		int(math.Round(w[passedPawnStart+3])),
-		int(math.Round(w[passedPawnStart+4])),
-		int(math.Round(w[passedPawnStart+5])))
-
-	fmt.Println("// === Rook Files ===")
-	fmt.Printf("RookOpenFile      = %d\n", int(math.Round(w[rookFileStart+0])))
-	fmt.Printf("RookSemiOpenFile = %d\n\n", int(math.Round(w[rookFileStart+1])))
-
-	fmt.Println("// === Bishop Pair ===")
-	fmt.Printf("BishopPairBonus = %d\n\n", int(math.Round(w[bishopPairStart])))
-
-	fmt.Println("// === King Safety (MG) ===")
-	fmt.Printf("KingSafetyDistCenter = %d\n", int(math.Round(w[kingSafetyStart+0])))
-	fmt.Printf("KingSafetyPawnShield = %d\n", int(math.Round(w[kingSafetyStart+1])))
-	fmt.Printf("KingSafetyFriendly   = %d\n\n", int(math.Round(w[kingSafetyStart+2])))
-
-	fmt.Println("// === King Activity (EG) ===")
-	fmt.Printf("KingActivityDistCenter  = %d\n", int(math.Round(w[kingActivityStart+0])))
-	fmt.Printf("KingActivityDistSquares = %d\n\n", int(math.Round(w[kingActivityStart+1])))
-
-	fmt.Println("// === Outposts ===")
-	for _, name := range []string{"knight", "bishop"} {
-		offset := outpostStart
-		if name == "bishop" {
-			offset += 64
+		values := p.Elements()
+		for i := range p.Size {
+			values[p.From+i] = int(math.Round(w[p.Offset+i]))
 		}
-		fmt.Printf("var %sOutposts = [64]int{\n", name)
-		for sq := range 64 {
-			if sq%8 == 0 {
-				fmt.Print("\t")
+		elem, items := "int", make([]string, 0, len(values))
+		if p.Taper == eval.Tapered {
+			elem = "Score"
+			for i := 0; i < len(values); i += 2 {
+				items = append(items, fmt.Sprintf("S(%d, %d)", values[i], values[i+1]))
 			}
-			fmt.Printf("%d, ", int(math.Round(w[offset+sq])))
-			if sq%8 == 7 {
-				fmt.Println()
+		} else {
+			for _, v := range values {
+				items = append(items, strconv.Itoa(v))
 			}
 		}
-		fmt.Println("}")
-		fmt.Println()
+		switch {
+		case p.Dims == nil:
+			fmt.Printf("%s = %s\n", p.Name, items[0])
+		case len(items) == 64:
+			fmt.Printf("var %s = [64]%s{\n", p.Name, elem)
+			for rank := range 8 {
+				fmt.Printf("\t%s,\n", strings.Join(items[rank*8:rank*8+8], ", "))
+			}
+			fmt.Println("}")
+		default:
+			fmt.Printf("%s = [%d]%s{%s}\n", p.Name, len(items), elem, strings.Join(items, ", "))
+		}
 	}
-
-	fmt.Println("// === Kaufman Piece-Value Slopes ===")
-	fmt.Printf("KnightPawnSlope = %d\n", int(math.Round(w[knightPawnSlopeStart])))
-	fmt.Printf("RookPawnSlope   = %d\n", int(math.Round(w[rookPawnSlopeStart])))
-	fmt.Println()
-
-	fmt.Println("// === Passed Pawn King Proximity (EG) ===")
-	fmt.Printf("PasserEnemyKingDist    = %d\n", int(math.Round(w[passerKingProxStart+0])))
-	fmt.Printf("PasserFriendlyKingDist = %d\n", int(math.Round(w[passerKingProxStart+1])))
-	fmt.Println()
-
-	fmt.Println("// === Tempo ===")
-	fmt.Printf("Tempo = %d\n", int(math.Round(w[tempoStart])))
-	fmt.Println()
-
-	fmt.Println("// === Threats ===")
-	fmt.Printf("ThreatPawnOnMinor  = %d\n", int(math.Round(w[threatsStart+0])))
-	fmt.Printf("ThreatPawnOnMajor  = %d\n", int(math.Round(w[threatsStart+1])))
-	fmt.Printf("ThreatMinorOnRook  = %d\n", int(math.Round(w[threatsStart+2])))
-	fmt.Printf("ThreatMinorOnQueen = %d\n", int(math.Round(w[threatsStart+3])))
-	fmt.Printf("ThreatRookOnQueen  = %d\n", int(math.Round(w[threatsStart+4])))
-	fmt.Println()
-
-	fmt.Println("// === Bad Bishop ===")
-	fmt.Printf("BadBishop = %d\n", int(math.Round(w[badBishopStart])))
-	fmt.Println()
-
-	fmt.Println("// === Pawn Break ===")
-	fmt.Printf("PawnBreak = %d\n", int(math.Round(w[pawnBreakStart])))
-	fmt.Println()
 }
@@ -0,0 +1,35 @@
+package texel
+
+// Split divides the cache entries into training and validation entries. An
+// entry is held out by a hash of its index, so the validation entries are
+// spread over the whole cache rather than taken from the games at its end,
+// and every pass over the cache divides it the same way.
+type Split struct {
+	// Valid is the fraction of entries held out for validation.
+	Valid float64 `json:"valid"`
+}
+
+// Held reports whether entry i of the cache is held out for validation.
+func (s Split) Held(i int) bool {
+	return float64(mix(uint64(i))) < s.Valid*(1<<64)
+}
+
+// Count returns the number of training and validation entries among the
+// first n entries of the cache.
+func (s Split) Count(n int) (train, valid int) {
+	for i := range n {
+		if s.Held(i) {
+			valid++
+		}
+	}
+	return n - valid, valid
+}
+
+// mix is the splitmix64 finalizer, spreading consecutive indices over the
+// whole range of uint64.
+func mix(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	return x ^ x>>31
+}
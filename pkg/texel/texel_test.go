@@ -1,10 +1,18 @@
 package texel
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
 	"math"
+	"os"
+	"path/filepath"
+	"slices"
 	"testing"
 
 	"github.com/likeawizard/tofiks/pkg/board"
+	"github.com/likeawizard/tofiks/pkg/eval"
 	"github.com/likeawizard/tofiks/pkg/search"
 )
 
@@ -161,6 +169,335 @@ func TestSigmoid(t *testing.T) {
 	}
 }
 
+func TestParseDataLine(t *testing.T) {
+	const fen = "4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1"
+	tests := []struct {
+		line   string
+		result float64
+		ok     bool
+	}{
+		{"0.5 " + fen, 0.5, true},
+		{"4k3/8/8/3p4/4P3/8/8/4K3 w - - c9 \"1-0\";", 1, true},
+		{"4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1 c9 \"1/2-1/2\";", 0.5, true},
+		{"4k3/8/8/3p4/4P3/8/8/4K3 w - - c9 0;", 0, true},
+		{"4k3/8/8/3p4/4P3/8/8/4K3 w - - c9 \"*\";", 0, false},
+		{"4k3/8/8/3p4/4P3/8/8/4K3 w - - id \"no result\";", 0, false},
+	}
+	for _, tt := range tests {
+		got, result, ok := ParseDataLine(tt.line)
+		if ok != tt.ok {
+			t.Errorf("ParseDataLine(%q) ok = %v, want %v", tt.line, ok, tt.ok)
+			continue
+		}
+		if ok && (got != fen || result != tt.result) {
+			t.Errorf("ParseDataLine(%q) = %q, %v, want %q, %v", tt.line, got, result, fen, tt.result)
+		}
+	}
+}
+
+func TestParseSample(t *testing.T) {
+	const fen = "4k3/8/8/3p4/4P3/8/8/4K3 b - - 0 1"
+	tests := []struct {
+		line   string
+		score  int16
+		result float64
+		ok     bool
+	}{
+		{"0.5 " + fen, 0, 0.5, true},
+		{fen + " | -35 | 1.0", -35, 1, true},
+		{"4k3/8/8/3p4/4P3/8/8/4K3 b - - ce 20; c9 \"0-1\";", -20, 0, true},
+		{"4k3/8/8/3p4/4P3/8/8/4K3 b - - c9 \"1/2-1/2\";", 0, 0.5, true},
+		{fen + " | x | 1.0", 0, 0, false},
+		{"1 not a fen", 0, 0, false},
+	}
+	for _, tt := range tests {
+		s, ok := ParseSample(tt.line)
+		if ok != tt.ok {
+			t.Errorf("ParseSample(%q) ok = %v, want %v", tt.line, ok, tt.ok)
+			continue
+		}
+		if ok && (s.Board.ExportFEN() != fen || s.Score != tt.score || s.Result != tt.result) {
+			t.Errorf("ParseSample(%q) = %q, %d, %v, want %q, %d, %v",
+				tt.line, s.Board.ExportFEN(), s.Score, s.Result, fen, tt.score, tt.result)
+		}
+	}
+}
+
+// TestDataRoundTrip writes samples in every format and reads them back.
+func TestDataRoundTrip(t *testing.T) {
+	samples := []Sample{
+		{Board: board.NewBoard(benchPositions[1]), Score: 45, HasScore: true, Result: 1},
+		{Board: board.NewBoard("r1bqkb1r/pppppppp/2n2n2/8/4P3/2N5/PPPP1PPP/R1BQKBNR b KQkq - 2 3"), Score: -12, HasScore: true, Result: 0.5},
+		{Board: board.NewBoard(benchPositions[2]), Score: -310, HasScore: true, Result: 0},
+	}
+	for _, f := range []Format{FormatText, FormatEPD, FormatMarlin, FormatBullet} {
+		var buf bytes.Buffer
+		w := NewDataWriter(&buf, f)
+		for _, s := range samples {
+			if err := w.Write(s); err != nil {
+				t.Fatalf("%v: %v", f, err)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatalf("%v: %v", f, err)
+		}
+
+		r := NewDataReader(&buf, f)
+		for i, want := range samples {
+			got, err := r.Read()
+			if err != nil {
+				t.Fatalf("%v: sample %d: %v", f, i, err)
+			}
+			wantFEN, wantScore := want.Board.ExportFEN(), want.Score
+			switch f {
+			case FormatText:
+				wantScore = 0
+			case FormatBullet:
+				// Positions come back from the side to move's point of view
+				// with castling rights, en passant square and counters lost.
+				b := want.Board.Copy()
+				if b.Side == board.Black {
+					b.Flip()
+					wantScore = -wantScore
+				}
+				b.CastlingRights, b.EnPassantTarget = 0, -1
+				b.HalfMoveCounter, b.FullMoveCounter = 0, 1
+				wantFEN = b.ExportFEN()
+			}
+			if got.Board.ExportFEN() != wantFEN || got.Score != wantScore {
+				t.Errorf("%v: sample %d = %q %d, want %q %d", f, i, got.Board.ExportFEN(), got.Score, wantFEN, wantScore)
+			}
+			if f != FormatBullet && got.Result != want.Result {
+				t.Errorf("%v: sample %d result = %v, want %v", f, i, got.Result, want.Result)
+			}
+		}
+		if _, err := r.Read(); err != io.EOF {
+			t.Errorf("%v: read past the end: %v", f, err)
+		}
+	}
+
+	// Packed formats have no way to mark a position unscored.
+	unscored := Sample{Board: board.NewBoard(benchPositions[1]), Result: 1}
+	for _, f := range []Format{FormatMarlin, FormatBullet} {
+		if err := NewDataWriter(io.Discard, f).Write(unscored); !errors.Is(err, ErrNoScore) {
+			t.Errorf("%v: unscored sample written: %v", f, err)
+		}
+	}
+
+	if got := FormatFromPath("data/selfplay.bullet"); got != FormatBullet {
+		t.Errorf("FormatFromPath(.bullet) = %v", got)
+	}
+	if got := FormatFromPath("texel_data.txt"); got != FormatText {
+		t.Errorf("FormatFromPath(.txt) = %v", got)
+	}
+}
+
+func TestCacheScores(t *testing.T) {
+	dir := t.TempDir()
+	data := filepath.Join(dir, "data.epd")
+	lines := "4k3/8/8/3p4/4P3/8/8/4K3 b - - ce 40; c9 \"1-0\";\n" +
+		"4k3/8/8/3p4/4P3/8/8/4K3 w - - c9 \"1/2-1/2\";\n"
+	if err := os.WriteFile(data, []byte(lines), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cache := filepath.Join(dir, "data.bin")
+	if n, err := BuildCache(data, cache, 0, 1); err != nil || n != 2 {
+		t.Fatalf("BuildCache = %d, %v", n, err)
+	}
+	var entries []Entry
+	if err := ForEachEntry(cache, func(e *Entry) {
+		entries = append(entries, Entry{Result: e.Result, Score: e.Score, HasScore: e.HasScore})
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || !entries[0].HasScore || entries[0].Score != -40 || entries[1].HasScore {
+		t.Fatalf("entries = %+v", entries)
+	}
+	if got, want := entries[0].Target(1, 0.25), 0.25+0.75*Sigmoid(1, -40); math.Abs(got-want) > 1e-12 {
+		t.Errorf("Target = %v, want %v", got, want)
+	}
+	if got := entries[1].Target(1, 0.25); got != 0.5 {
+		t.Errorf("Target without score = %v, want 0.5", got)
+	}
+
+	// Caches of an earlier format or parameter layout are rejected.
+	var old bytes.Buffer
+	old.WriteString("TXC2")
+	for _, v := range []any{uint16(1), uint16(7), float32(2), int16(128), float32(1), int16(noScore)} {
+		if err := binary.Write(&old, binary.LittleEndian, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	legacy := filepath.Join(dir, "legacy.bin")
+	if err := os.WriteFile(legacy, old.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ForEachEntry(legacy, func(*Entry) {}); !errors.Is(err, ErrCacheLayout) {
+		t.Errorf("ForEachEntry(legacy) = %v, want ErrCacheLayout", err)
+	}
+	if err := CheckCache(cache); err != nil {
+		t.Errorf("CheckCache = %v", err)
+	}
+}
+
+// benchCache builds a cache of the bench positions with made-up results.
+func benchCache(t *testing.T, dir string) (string, int) {
+	t.Helper()
+	data := filepath.Join(dir, "data.txt")
+	var lines bytes.Buffer
+	for i, fen := range benchPositions {
+		lines.WriteString([]string{"1.0 ", "0.5 ", "0.0 "}[i%3] + fen + "\n")
+	}
+	if err := os.WriteFile(data, lines.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cache := filepath.Join(dir, "data.bin")
+	n, err := BuildCache(data, cache, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cache, n
+}
+
+// TestStreamOptimizeResume verifies that a run resumed from its checkpoint
+// ends where an uninterrupted one does, with the validation entries held out.
+func TestStreamOptimizeResume(t *testing.T) {
+	dir := t.TempDir()
+	cache, n := benchCache(t, dir)
+
+	split := Split{Valid: 0.2}
+	train, valid := split.Count(n)
+	// The held out entries are spread over the cache, not taken from its end.
+	if _, tail := split.Count(train); valid == 0 || tail == 0 {
+		t.Fatalf("%d of %d entries held out, %d before the last %d", valid, n, tail, valid)
+	}
+	weights := InitialParams()
+	cfg := StreamConfig{Adam: DefaultAdamConfig(), Iterations: 4}
+	full := NewTuneState(&weights, 1, 1, n, split, nil)
+	if err := StreamOptimize(cache, full, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Checkpoint, cfg.Iterations = filepath.Join(dir, "data.ckpt"), 2
+	if err := StreamOptimize(cache, NewTuneState(&weights, 1, 1, n, split, nil), cfg); err != nil {
+		t.Fatal(err)
+	}
+	resumed, err := LoadCheckpoint(cfg.Checkpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.Iteration != 2 || resumed.Split != split || resumed.Train != train {
+		t.Fatalf("checkpoint at iteration %d with %d training entries", resumed.Iteration, resumed.Train)
+	}
+	cfg.Iterations = 4
+	if err := StreamOptimize(cache, resumed, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if resumed.Weights != full.Weights || resumed.M != full.M || resumed.V != full.V {
+		t.Error("resumed run differs from the uninterrupted one")
+	}
+	if full.Best == nil || full.BestMSE <= 0 || *resumed.Best != *full.Best {
+		t.Errorf("best validation MSE %v after iteration %d", full.BestMSE, full.BestIteration)
+	}
+	var total float64
+	i := 0
+	if err := ForEachEntry(cache, func(e *Entry) {
+		if i++; split.Held(i - 1) {
+			diff := e.Target(1, 1) - Sigmoid(1, EvalFromTrace(&e.Trace, full.Best))
+			total += diff * diff
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got := total / float64(valid); math.Abs(got-full.BestMSE) > 1e-12 {
+		t.Errorf("validation MSE %v, want %v over the held out entries", full.BestMSE, got)
+	}
+}
+
+func TestConstraints(t *testing.T) {
+	values := []float64{1, 3, 2, 2, 0, 5}
+	isotonic(values)
+	if want := []float64{1, 1.75, 1.75, 1.75, 1.75, 5}; !slices.Equal(values, want) {
+		t.Errorf("isotonic = %v, want %v", values, want)
+	}
+
+	c := NewConstraints()
+	lo, hi := 0, 20
+	f := &eval.ParamFile{Version: eval.ParamsVersion, Params: []eval.ParamValue{
+		{Name: "Material", Freeze: true},
+		{Name: "Tempo", Min: &lo, Max: &hi},
+		{Name: "PassedPawnBonus", Monotone: 1},
+	}}
+	if err := c.Load(f); err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range []error{c.Freeze("NoSuchGroup"), c.Clamp("Tempo", 2, 1), c.Monotone("Tempo", 1)} {
+		if err == nil {
+			t.Error("invalid constraint accepted")
+		}
+	}
+
+	initial := InitialParams()
+	w := initial
+	w[pieceWeights] += 50
+	w[tempo], w[tempo+1] = -5, 25
+	for r := range 6 {
+		w[passedPawnBonus+2*r] = float64(10 - r)
+	}
+	c.project(&w, &initial)
+	if w[pieceWeights] != initial[pieceWeights] {
+		t.Errorf("frozen pawn weight moved to %v", w[pieceWeights])
+	}
+	if w[tempo] != 0 || w[tempo+1] != 20 {
+		t.Errorf("Tempo = (%v, %v), want (0, 20)", w[tempo], w[tempo+1])
+	}
+	for r := 1; r < 6; r++ {
+		if w[passedPawnBonus+2*r] < w[passedPawnBonus+2*(r-1)] || w[passedPawnBonus+2*r+1] < w[passedPawnBonus+2*(r-1)+1] {
+			t.Errorf("PassedPawnBonus decreases at rank %d", r+1)
+		}
+	}
+
+	c.L1, c.L2 = 1, 0.5
+	var grad [NumParams]float64
+	c.regularize(&grad, &w, &initial)
+	// The endgame tempo was clamped down to 20: L1 adds -1, L2 2*0.5*(20-w0).
+	if grad[pieceWeights] != 0 || grad[tempo+1] != -1+(20-initial[tempo+1]) {
+		t.Errorf("regularized gradient %v, %v", grad[pieceWeights], grad[tempo+1])
+	}
+
+	// Frozen PSTs survive a run, recentering included, and the constraints
+	// are saved with the checkpoint.
+	dir := t.TempDir()
+	cache, n := benchCache(t, dir)
+	c = NewConstraints()
+	c.L2 = 0.1
+	if err := c.Freeze("PST"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Monotone("PassedPawnBonus", 1); err != nil {
+		t.Fatal(err)
+	}
+	st := NewTuneState(&initial, 1, 1, n, Split{}, c)
+	ckpt := filepath.Join(dir, "data.ckpt")
+	if err := StreamOptimize(cache, st, StreamConfig{Adam: DefaultAdamConfig(), Iterations: 3, Checkpoint: ckpt}); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := LoadCheckpoint(ckpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !saved.Constraints.Equal(c) || saved.Constraints.Equal(NewConstraints()) {
+		t.Error("checkpoint constraints differ from the run's")
+	}
+	if !slices.Equal(st.Weights[:pieceWeights], initial[:pieceWeights]) {
+		t.Error("frozen PSTs changed")
+	}
+	if st.Weights == initial {
+		t.Error("no weight changed")
+	}
+}
+
 // BenchmarkTraceEvaluate measures trace computation speed per position.
 func BenchmarkTraceEvaluate(b *testing.B) {
 	for _, fen := range benchPositions {
@@ -196,7 +533,7 @@ func BenchmarkComputeGradient(b *testing.B) {
 	K := 0.2109375
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		ComputeGradient(entries, &weights, K)
+		ComputeGradient(entries, &weights, K, 1)
 	}
 }
 
@@ -212,6 +549,61 @@ func BenchmarkMSE(b *testing.B) {
 	K := 0.2109375
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		MeanSquaredError(entries, &weights, K)
+		MeanSquaredError(entries, &weights, K, 1)
+	}
+}
+
+// TestBreakdownMatchesTrace verifies that the per-term breakdown, blended by
+// phase, adds up to the trace eval.
+func TestBreakdownMatchesTrace(t *testing.T) {
+	weights := InitialParams()
+
+	for _, fen := range benchPositions {
+		b := board.NewBoard(fen)
+		trace, phase := TraceEvaluate(b)
+		traceEval := EvalFromTrace(&trace, &weights)
+
+		var total float64
+		for _, ts := range Breakdown(b) {
+			mg := ts.MG[board.White] - ts.MG[board.Black]
+			eg := ts.EG[board.White] - ts.EG[board.Black]
+			total += (mg*float64(256-phase) + eg*float64(phase)) / 256
+		}
+
+		if math.Abs(total-traceEval) > 1e-6 {
+			t.Errorf("FEN %s: breakdown=%v trace=%v", fen, total, traceEval)
+		}
+	}
+}
+
+// TestParamsLayout verifies that the registry lays the parameters out back to
+// back and that the Terms cover every weight once.
+func TestParamsLayout(t *testing.T) {
+	w := InitialParams()
+	next := 0
+	for _, p := range eval.Params {
+		if p.Offset != next {
+			t.Errorf("%s: offset %d, want %d", p.Name, p.Offset, next)
+		}
+		next = p.Offset + p.Size
+		for i, v := range p.Default {
+			if w[p.Offset+i] != float64(v) {
+				t.Errorf("%s[%d]: weight %v, default %d", p.Name, i, w[p.Offset+i], v)
+			}
+		}
+	}
+	if next != NumParams {
+		t.Errorf("params hold %d weights, want %d", next, NumParams)
+	}
+
+	next = 0
+	for _, term := range Terms {
+		if term.Start != next {
+			t.Errorf("%s: start %d, want %d", term.Name, term.Start, next)
+		}
+		next = term.Start + term.Count
+	}
+	if next != NumParams {
+		t.Errorf("terms cover %d weights, want %d", next, NumParams)
 	}
 }
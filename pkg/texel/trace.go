@@ -12,20 +12,50 @@ type TraceCoeff struct {
 }
 
 // Trace holds the non-zero coefficients for a position in sparse format.
-// This reduces memory from ~7.7 KB (dense [968]float64) to ~500 bytes per position.
+// This reduces memory from ~7.5 KB (dense [NumParams]float64) to ~500 bytes per position.
 type Trace []TraceCoeff
 
 // denseTrace is used internally during trace computation, then compacted to sparse.
 type denseTrace [NumParams]float64
 
+// taper holds the middlegame and endgame shares of the game phase.
+type taper [2]float64
+
+// addScore adds the coefficient v of a Tapered parameter, split between its
+// middlegame and endgame weights.
+func (t *denseTrace) addScore(idx int, tp taper, v float64) {
+	t[idx] += tp[0] * v
+	t[idx+1] += tp[1] * v
+}
+
+// sideTrace keeps the coefficients contributed by each color apart. Black's
+// coefficients carry a negative sign, so the sum of both is the full trace.
+type sideTrace [2]denseTrace
+
 // TraceEvaluate computes the coefficient trace for a position.
 // The resulting trace, when dotted with the weight vector, reproduces the eval.
 // Returns the phase (0-256) for external use.
 func TraceEvaluate(b *board.Board) (Trace, int) {
-	var t denseTrace
 	phase := b.GetGamePhase()
+	st := traceSides(b, phase)
+
+	// Compact to sparse representation.
+	var sparse Trace
+	for i := range NumParams {
+		if v := st[board.White][i] + st[board.Black][i]; v != 0 {
+			sparse = append(sparse, TraceCoeff{Index: uint16(i), Value: float32(v)})
+		}
+	}
+	return sparse, phase
+}
+
+// traceSides computes the per-color coefficient traces at an explicit phase.
+// Passing phase 0 or 256 isolates the pure middlegame or endgame coefficients.
+func traceSides(b *board.Board, phase int) *sideTrace {
+	var st sideTrace
 	mgPhase := float64(256-phase) / 256.0
 	egPhase := float64(phase) / 256.0
+	tp := taper{mgPhase, egPhase}
 
 	oppKing := [2]board.BBoard{
 		board.KingAttacks[board.Square(b.Pieces[board.Black][board.Kings].LS1B())],
@@ -37,6 +67,7 @@ func TraceEvaluate(b *board.Board) (Trace, int) {
 		if color == board.Black {
 			sign = -1.0
 		}
+		t := &st[color]
 		numPawns := b.Pieces[color][board.Pawns].Count()
 		friendlyKingSq := b.Pieces[color][board.Kings].LS1B()
 		enemyKingSq := b.Pieces[color^1][board.Kings].LS1B()
@@ -57,8 +88,8 @@ func TraceEvaluate(b *board.Board) (Trace, int) {
 				t[pstIndex(1, pieceType, pstSq)] += sign * egPhase
 
 				// Piece weight coefficient (not phase-dependent).
-				if pieceType < pieceWeightCount {
-					t[pieceWeightStart+pieceType] += sign
+				if pieceType < board.Kings {
+					t[pieceWeights+pieceType] += sign
 				}
 
 				// Piece-specific eval traces.
@@ -66,15 +97,15 @@ func TraceEvaluate(b *board.Board) (Trace, int) {
 				case board.Pawns:
 					// Handled separately in tracePawns.
 				case board.Knights:
-					traceKnight(b, board.Square(sq), color, oppKing[color], sign, numPawns, &t)
+					traceKnight(b, board.Square(sq), color, oppKing[color], sign, numPawns, tp, t)
 				case board.Bishops:
-					traceBishop(b, board.Square(sq), color, oppKing[color], sign, &t)
+					traceBishop(b, board.Square(sq), color, oppKing[color], sign, tp, t)
 				case board.Rooks:
-					traceRook(b, board.Square(sq), color, oppKing[color], sign, numPawns, &t)
+					traceRook(b, board.Square(sq), color, oppKing[color], sign, numPawns, tp, t)
 				case board.Queens:
-					traceQueen(b, board.Square(sq), color, oppKing[color], sign, &t)
+					traceQueen(b, board.Square(sq), color, oppKing[color], sign, tp, t)
 				case board.Kings:
-					traceKing(b, board.Square(sq), color, sign, phase, &t)
+					traceKing(b, board.Square(sq), color, sign, phase, t)
 				}
 			}
 		}
@@ -91,8 +122,8 @@ func TraceEvaluate(b *board.Board) (Trace, int) {
 		}
 		enemyMinors := b.Pieces[color^1][board.Knights] | b.Pieces[color^1][board.Bishops]
 		enemyMajors := b.Pieces[color^1][board.Rooks] | b.Pieces[color^1][board.Queens]
-		t[threatsStart+0] += sign * float64((pawnAttackBB & enemyMinors).Count())
-		t[threatsStart+1] += sign * float64((pawnAttackBB & enemyMajors).Count())
+		t.addScore(threatPawnOnMinor, tp, sign*float64((pawnAttackBB&enemyMinors).Count()))
+		t.addScore(threatPawnOnMajor, tp, sign*float64((pawnAttackBB&enemyMajors).Count()))
 
 		// Passed-pawn king proximity (EG-only). Matches the second pass in
 		// eval.GetEvaluation; kept out of tracePawns because it depends on
@@ -108,16 +139,16 @@ func TraceEvaluate(b *board.Board) (Trace, int) {
 			}
 			enemyDist := eval.DistSquares(enemyKingSq, sq)
 			friendlyDist := eval.DistSquares(friendlyKingSq, sq)
-			t[passerKingProxStart+0] += sign * egPhase * float64(rank*enemyDist)
-			t[passerKingProxStart+1] += sign * egPhase * float64(rank*friendlyDist)
+			t[passerEnemyKingDist] += sign * egPhase * float64(rank*enemyDist)
+			t[passerFriendlyKingDist] += sign * egPhase * float64(rank*friendlyDist)
 		}
 	}
 
 	// Tempo bonus: +1 for white to move, -1 for black.
 	if b.Side == board.White {
-		t[tempoStart] += 1.0
+		st[board.White].addScore(tempo, tp, 1)
 	} else {
-		t[tempoStart] -= 1.0
+		st[board.Black].addScore(tempo, tp, -1)
 	}
 
 	// Pawn breaks: net (white - black) count of push-to-empty squares that
@@ -131,20 +162,13 @@ func TraceEvaluate(b *board.Board) (Trace, int) {
 	wDouble := (((wPawns & board.Rank2) >> 8) & empty) >> 8 & empty
 	bSingle := (bPawns << 8) & empty
 	bDouble := (((bPawns & board.Rank7) << 8) & empty) << 8 & empty
-	t[pawnBreakStart] += float64(((wSingle | wDouble) & wBreakTarget).Count() -
-		((bSingle | bDouble) & bBreakTarget).Count())
+	st[board.White].addScore(pawnBreak, tp, float64(((wSingle | wDouble) & wBreakTarget).Count()))
+	st[board.Black].addScore(pawnBreak, tp, -float64(((bSingle | bDouble) & bBreakTarget).Count()))
 
-	// Pawn structure (not phase-dependent).
-	tracePawns(b, &t)
+	// Pawn structure.
+	tracePawns(b, tp, &st)
 
-	// Compact to sparse representation.
-	var sparse Trace
-	for i, v := range t {
-		if v != 0 {
-			sparse = append(sparse, TraceCoeff{Index: uint16(i), Value: float32(v)})
-		}
-	}
-	return sparse, phase
+	return &st
 }
 
 // EvalFromTrace reconstructs the eval from a trace and weight vector.
@@ -156,20 +180,20 @@ func EvalFromTrace(t *Trace, w *[NumParams]float64) float64 {
 	return sum
 }
 
-func traceKnight(b *board.Board, sq board.Square, side int, oppKing board.BBoard, sign float64, numPawns int, t *denseTrace) {
+func traceKnight(b *board.Board, sq board.Square, side int, oppKing board.BBoard, sign float64, numPawns int, tp taper, t *denseTrace) {
 	moves := board.KnightAttacks[sq] & ^b.Occupancy[side]
 	moveCount := float64(moves.Count())
 	threatCount := float64((moves & oppKing).Count())
 
-	t[mobilityStart+3] += sign * moveCount // KnightMobility
-	t[threatStart+3] += sign * threatCount // KnightThreat
+	t.addScore(knightMobility, tp, sign*moveCount)
+	t.addScore(knightThreat, tp, sign*threatCount)
 
 	// Minor-on-major threats.
-	t[threatsStart+2] += sign * float64((moves & b.Pieces[side^1][board.Rooks]).Count())
-	t[threatsStart+3] += sign * float64((moves & b.Pieces[side^1][board.Queens]).Count())
+	t.addScore(threatMinorOnRook, tp, sign*float64((moves&b.Pieces[side^1][board.Rooks]).Count()))
+	t.addScore(threatMinorOnQueen, tp, sign*float64((moves&b.Pieces[side^1][board.Queens]).Count()))
 
 	// Kaufman knight-pawn slope: bonus = slope * (numPawns - 5).
-	t[knightPawnSlopeStart] += sign * float64(numPawns-5)
+	t.addScore(knightPawnSlope, tp, sign*float64(numPawns-5))
 
 	// Outpost.
 	if board.Outposts[side][sq]&b.Pieces[side^1][board.Pawns] == 0 &&
@@ -178,21 +202,21 @@ func traceKnight(b *board.Board, sq board.Square, side int, oppKing board.BBoard
 		if side == board.Black {
 			outSq = (7-outSq/8)*8 + outSq%8
 		}
-		t[outpostStart+outSq] += sign
+		t[knightOutposts+outSq] += sign
 	}
 }
 
-func traceBishop(b *board.Board, sq board.Square, side int, oppKing board.BBoard, sign float64, t *denseTrace) {
+func traceBishop(b *board.Board, sq board.Square, side int, oppKing board.BBoard, sign float64, tp taper, t *denseTrace) {
 	moves := board.GetBishopAttacks(int(sq), b.Occupancy[board.Both])
 	moveCount := float64(moves.Count())
 	threatCount := float64((moves & oppKing).Count())
 
-	t[mobilityStart+2] += sign * moveCount // BishopMobility
-	t[threatStart+2] += sign * threatCount // BishopThreat
+	t.addScore(bishopMobility, tp, sign*moveCount)
+	t.addScore(bishopThreat, tp, sign*threatCount)
 
 	// Minor-on-major threats.
-	t[threatsStart+2] += sign * float64((moves & b.Pieces[side^1][board.Rooks]).Count())
-	t[threatsStart+3] += sign * float64((moves & b.Pieces[side^1][board.Queens]).Count())
+	t.addScore(threatMinorOnRook, tp, sign*float64((moves&b.Pieces[side^1][board.Rooks]).Count()))
+	t.addScore(threatMinorOnQueen, tp, sign*float64((moves&b.Pieces[side^1][board.Queens]).Count()))
 
 	// Outpost.
 	if board.Outposts[side][sq]&b.Pieces[side^1][board.Pawns] == 0 &&
@@ -201,49 +225,49 @@ func traceBishop(b *board.Board, sq board.Square, side int, oppKing board.BBoard
 		if side == board.Black {
 			outSq = (7-outSq/8)*8 + outSq%8
 		}
-		t[outpostStart+64+outSq] += sign
+		t[bishopOutposts+outSq] += sign
 	}
 
 	// Bishop pair.
 	if b.Pieces[side][board.Bishops].Count() > 1 {
-		t[bishopPairStart] += sign
+		t.addScore(bishopPair, tp, sign)
 	}
 
-	t[badBishopStart] += sign * float64((b.Pieces[side][board.Pawns] & board.SquareColorMask[sq]).Count())
+	t.addScore(badBishop, tp, sign*float64((b.Pieces[side][board.Pawns]&board.SquareColorMask[sq]).Count()))
 }
 
-func traceRook(b *board.Board, sq board.Square, side int, oppKing board.BBoard, sign float64, numPawns int, t *denseTrace) {
+func traceRook(b *board.Board, sq board.Square, side int, oppKing board.BBoard, sign float64, numPawns int, tp taper, t *denseTrace) {
 	moves := board.GetRookAttacks(int(sq), b.Occupancy[board.Both])
 	moveCount := float64(moves.Count())
 	threatCount := float64((moves & oppKing).Count())
 
-	t[mobilityStart+1] += sign * moveCount // RookMobility
-	t[threatStart+1] += sign * threatCount // RookThreat
+	t.addScore(rookMobility, tp, sign*moveCount)
+	t.addScore(rookThreat, tp, sign*threatCount)
 
 	// Rook-on-queen threat.
-	t[threatsStart+4] += sign * float64((moves & b.Pieces[side^1][board.Queens]).Count())
+	t.addScore(threatRookOnQueen, tp, sign*float64((moves&b.Pieces[side^1][board.Queens]).Count()))
 
 	// Kaufman rook-pawn slope: bonus = slope * (numPawns - 5).
-	t[rookPawnSlopeStart] += sign * float64(numPawns-5)
+	t.addScore(rookPawnSlope, tp, sign*float64(numPawns-5))
 
 	// Rook on open / semi-open file.
 	file := board.FileMasks[sq%8]
 	if file&b.Pieces[side][board.Pawns] == 0 {
 		if file&b.Pieces[side^1][board.Pawns] == 0 {
-			t[rookFileStart+0] += sign // open file
+			t.addScore(rookOpenFile, tp, sign)
 		} else {
-			t[rookFileStart+1] += sign // semi-open file
+			t.addScore(rookSemiOpenFile, tp, sign)
 		}
 	}
 }
 
-func traceQueen(b *board.Board, sq board.Square, _ int, oppKing board.BBoard, sign float64, t *denseTrace) {
+func traceQueen(b *board.Board, sq board.Square, _ int, oppKing board.BBoard, sign float64, tp taper, t *denseTrace) {
 	moves := board.GetQueenAttacks(int(sq), b.Occupancy[board.Both])
 	moveCount := float64(moves.Count())
 	threatCount := float64((moves & oppKing).Count())
 
-	t[mobilityStart+0] += sign * moveCount // QueenMobility
-	t[threatStart+0] += sign * threatCount // QueenThreat
+	t.addScore(queenMobility, tp, sign*moveCount)
+	t.addScore(queenThreat, tp, sign*threatCount)
 }
 
 func traceKing(b *board.Board, king board.Square, side int, sign float64, phase int, t *denseTrace) {
@@ -262,23 +286,24 @@ func traceKing(b *board.Board, king board.Square, side int, sign float64, phase
 	allFriendly := float64((board.KingSafetyMask[side][king] & b.Occupancy[side]).Count())
 	friendlyNonPawn := allFriendly - pawnShield
 
-	t[kingSafetyStart+0] += sign * distC * mgPhase
-	t[kingSafetyStart+1] += sign * pawnShield * mgPhase
-	t[kingSafetyStart+2] += sign * friendlyNonPawn * mgPhase
+	t[kingSafetyDistCenter] += sign * distC * mgPhase
+	t[kingSafetyPawnShield] += sign * pawnShield * mgPhase
+	t[kingSafetyFriendly] += sign * friendlyNonPawn * mgPhase
 
 	// King activity (EG): distCenter, distSquares.
 	distS := float64(eval.DistSquares(int(king), b.Pieces[side^1][board.Kings].LS1B()))
-	t[kingActivityStart+0] += sign * distC * egPhase
-	t[kingActivityStart+1] += sign * distS * egPhase
+	t[kingActivityDistCenter] += sign * distC * egPhase
+	t[kingActivityDistSquares] += sign * distS * egPhase
 }
 
 // tracePawns computes pawn structure coefficients for both sides.
-func tracePawns(b *board.Board, t *denseTrace) {
+func tracePawns(b *board.Board, tp taper, st *sideTrace) {
 	for color := board.White; color <= board.Black; color++ {
 		sign := 1.0
 		if color == board.Black {
 			sign = -1.0
 		}
+		t := &st[color]
 		opp := color ^ 1
 		ownPawns := b.Pieces[color][board.Pawns]
 		oppPawns := b.Pieces[opp][board.Pawns]
@@ -290,15 +315,15 @@ func tracePawns(b *board.Board, t *denseTrace) {
 			file := int(sq) % 8
 
 			if eval.IsProtected(b, sq, color) {
-				t[pawnStructStart+0] += sign // protected
+				t.addScore(pawnProtected, tp, sign)
 			}
 			if eval.IsDoubled(b, sq, color) {
-				t[pawnStructStart+1] += sign // doubled
+				t.addScore(pawnDoubled, tp, sign)
 			}
 
 			isolated := eval.IsIsolated(b, sq, color)
 			if isolated {
-				t[pawnStructStart+2] += sign // isolated
+				t.addScore(pawnIsolated, tp, sign)
 			}
 
 			passed := eval.IsPassed(b, sq, color)
@@ -309,7 +334,7 @@ func tracePawns(b *board.Board, t *denseTrace) {
 				}
 				// Passed pawn bonus (ranks 1-6 map to indices 0-5).
 				if rank >= 1 && rank <= 6 {
-					t[passedPawnStart+rank-1] += sign
+					t.addScore(passedPawnBonus+2*(rank-1), tp, sign)
 				}
 
 				// Connected passed pawns.
@@ -318,7 +343,7 @@ func tracePawns(b *board.Board, t *denseTrace) {
 					for adjPassers > 0 {
 						adjSq := board.Square(adjPassers.PopLS1B())
 						if eval.IsPassed(b, adjSq, color) {
-							t[pawnStructStart+7] += sign // connectedPass
+							t.addScore(pawnConnectedPasser, tp, sign)
 							break
 						}
 					}
@@ -340,12 +365,12 @@ func tracePawns(b *board.Board, t *denseTrace) {
 							ownRank = int(sq) / 8
 						}
 						if ownRank <= 2 {
-							t[pawnStructStart+3] += sign // backwardDeep
+							t.addScore(pawnBackwardDeep, tp, sign)
 						} else {
-							t[pawnStructStart+4] += sign // backwardMid
+							t.addScore(pawnBackwardMid, tp, sign)
 						}
 						if board.FileMasks[file]&oppPawns == 0 {
-							t[pawnStructStart+5] += sign // backwardOpen
+							t.addScore(pawnBackwardOpen, tp, sign)
 						}
 					}
 				}
@@ -357,7 +382,7 @@ func tracePawns(b *board.Board, t *denseTrace) {
 				stopSq = int(sq) + 8
 			}
 			if stopSq >= 0 && stopSq < 64 && board.SquareBitboards[stopSq]&oppPawns != 0 {
-				t[pawnStructStart+6] += sign // blocked
+				t.addScore(pawnBlocked, tp, sign)
 			}
 
 			// Candidate passed pawn.
@@ -367,7 +392,7 @@ func tracePawns(b *board.Board, t *denseTrace) {
 				helpers := board.AdjacentFiles[file] & board.FrontSpan[color^1][sq] & ownPawns
 				totalSupport := supporters.Count() + helpers.Count()
 				if sentries != 0 && totalSupport >= sentries.Count() {
-					t[pawnStructStart+8] += sign // candidate
+					t.addScore(pawnCandidate, tp, sign)
 				}
 			}
 		}
@@ -0,0 +1,77 @@
+package uci
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/likeawizard/tofiks/pkg/search"
+)
+
+// LoadConfig applies a YAML config file to the engine. Each key names a UCI
+// option, ignoring case and spaces (hash, moveOverhead, debugLogFile, RFPMargin),
+// and is set through the same options as setoption, so setoption overrides the
+// file. Two keys are special: debug turns on debug mode and ownBook accepts a
+// book path, which selects and enables the book.
+func LoadConfig(e *search.Engine, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s: expected option names mapped to values", path)
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if value.Kind != yaml.ScalarNode {
+			return fmt.Errorf("%s:%d: %s must be a single value", path, key.Line, key.Value)
+		}
+		if err := applySetting(e, key.Value, value.Value); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, key.Line, err)
+		}
+	}
+	return nil
+}
+
+// ApplyOption sets the UCI option name to value as setoption would, but
+// returns errors, including a process-wide option refused while several
+// engines run (see SetShared).
+func ApplyOption(e *search.Engine, name, value string) error {
+	return applySetting(e, name, value)
+}
+
+func applySetting(e *search.Engine, name, value string) error {
+	switch optionKey(name) {
+	case "debug":
+		on, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value %q for %s", value, name)
+		}
+		e.Debug = on
+		return nil
+	case "ownbook":
+		if _, err := strconv.ParseBool(value); err != nil {
+			if err := applySetting(e, "Book File", value); err != nil {
+				return err
+			}
+			value = "true"
+		}
+	}
+
+	opt, err := newOption(name, value)
+	if err != nil {
+		return err
+	}
+	return setOption(e, opt)
+}
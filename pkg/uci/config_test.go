@@ -0,0 +1,92 @@
+package uci
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/likeawizard/tofiks/pkg/eval"
+	"github.com/likeawizard/tofiks/pkg/search"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	e := search.NewEngine()
+	e.Out = &bytes.Buffer{}
+	tt := e.TTable
+	path := writeConfig(t, "hash: 16\nthreads: 1\nmoveOverhead: 30\ndebug: true\nRFPMargin: 120\n")
+
+	assert.NoError(t, LoadConfig(e, path))
+	assert.NotSame(t, tt, e.TTable)
+	assert.Equal(t, 30, e.Clock.Overhead)
+	assert.True(t, e.Debug)
+	assert.Equal(t, 120, e.Params.RFPMargin)
+	assert.Equal(t, search.DefaultParams(), search.NewEngine().Params)
+
+	// setoption is applied on top of the config file.
+	Handle(e, "setoption name Move Overhead value 50")
+	e.WG.Wait()
+	assert.Equal(t, 50, e.Clock.Overhead)
+}
+
+func TestScoreTunables(t *testing.T) {
+	defer eval.ResetParams()
+
+	e := search.NewEngine()
+	assert.NoError(t, LoadConfig(e, writeConfig(t, "Tempo: 35\nBishopPair: 30\nBishopPairEG: 50\n")))
+	assert.Equal(t, eval.S(35, 35), eval.Tempo)
+	assert.Equal(t, eval.S(30, 50), eval.BishopPair)
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	e := search.NewEngine()
+	for content, want := range map[string]string{
+		"hash: 16\nthinkHarder: true\n": "config.yml:2: unknown option \"thinkHarder\"",
+		"hash: lots\n":                  "config.yml:1: invalid value \"lots\" for hash",
+		"moveOverhead: 5000\n":          "config.yml:1: invalid value \"5000\" for moveOverhead",
+		"- hash\n":                      "config.yml: expected option names mapped to values",
+	} {
+		err := LoadConfig(e, writeConfig(t, content))
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), want)
+		}
+	}
+}
+
+func TestSharedOptions(t *testing.T) {
+	defer eval.ResetParams()
+	defer func() { processOptions.values = make(map[string]string) }()
+	path := filepath.Join(t.TempDir(), "params.json")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, eval.CurrentParams().Write(f))
+	assert.NoError(t, f.Close())
+
+	e := search.NewEngine()
+	assert.Error(t, ApplyOption(e, "EvalParams", filepath.Join(t.TempDir(), "missing.json")))
+	assert.NoError(t, ApplyOption(e, "EvalParams", path))
+	assert.NoError(t, ApplyOption(e, "TempoMG", "40"))
+
+	// Once shared, the settings may be repeated but not changed.
+	SetShared(true)
+	defer SetShared(false)
+	assert.NoError(t, ApplyOption(e, "EvalParams", path))
+	assert.NoError(t, ApplyOption(e, "TempoMG", "40"))
+	assert.Error(t, ApplyOption(e, "TempoMG", "41"))
+	assert.Error(t, ApplyOption(e, "EvalParams", ""))
+	assert.NoError(t, ApplyOption(e, "RFPMargin", "100"))
+	assert.Equal(t, 40, eval.Tempo.MG())
+}
@@ -0,0 +1,145 @@
+package uci
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+	"github.com/likeawizard/tofiks/pkg/search"
+	"github.com/likeawizard/tofiks/pkg/texel"
+)
+
+func (c *Display) Exec(e *search.Engine) bool {
+	defer e.WG.Done()
+	fmt.Fprint(e.Output(), e.Board)
+	fmt.Fprintf(e.Output(), "\nFen: %s\n", e.Board.ExportFEN())
+	fmt.Fprintf(e.Output(), "Key: %016X\n", e.Board.Hash)
+	fmt.Fprintf(e.Output(), "Pawn key: %016X\n", e.Board.PawnHash)
+
+	var checkers strings.Builder
+	for bb := e.Board.Checkers(); bb > 0; {
+		checkers.WriteString(" " + board.Square(bb.PopLS1B()).String())
+	}
+	fmt.Fprintf(e.Output(), "Checkers:%s\n", checkers.String())
+	return true
+}
+
+func (c *Evaluate) Exec(e *search.Engine) bool {
+	defer e.WG.Done()
+	fmt.Fprintln(e.Output(), "      Term         |     White     |     Black     |     Total")
+	fmt.Fprintln(e.Output(), "                   |   MG     EG   |   MG     EG   |   MG     EG")
+	fmt.Fprintln(e.Output(), " ------------------+---------------+---------------+---------------")
+	var mgTotal, egTotal float64
+	for _, ts := range texel.Breakdown(e.Board) {
+		mg := ts.MG[board.White] - ts.MG[board.Black]
+		eg := ts.EG[board.White] - ts.EG[board.Black]
+		mgTotal += mg
+		egTotal += eg
+		fmt.Fprintf(e.Output(), " %17s | %6.2f %6.2f | %6.2f %6.2f | %6.2f %6.2f\n", ts.Name,
+			ts.MG[board.White]/100, ts.EG[board.White]/100,
+			ts.MG[board.Black]/100, ts.EG[board.Black]/100,
+			mg/100, eg/100)
+	}
+	fmt.Fprintln(e.Output(), " ------------------+---------------+---------------+---------------")
+	fmt.Fprintf(e.Output(), " %17s |               |               | %6.2f %6.2f\n", "Total", mgTotal/100, egTotal/100)
+
+	score := e.Eval.GetEvaluation(e.Board)
+	fmt.Fprintf(e.Output(), "\nPhase: %d/256\n", e.Board.Phase)
+	fmt.Fprintf(e.Output(), "Final evaluation: %+.2f (white side)\n", float64(score)/100)
+	return true
+}
+
+func (c *Flip) Exec(e *search.Engine) bool {
+	defer e.WG.Done()
+	e.Board.Flip()
+	e.Ply = 0
+	return true
+}
+
+func (c *Result) Exec(e *search.Engine) bool {
+	defer e.WG.Done()
+	e.EndGame(c.score)
+	return true
+}
+
+func (c *Moves) Exec(e *search.Engine) bool {
+	defer e.WG.Done()
+	moves := e.Board.LegalMoves()
+	var line strings.Builder
+	for _, m := range moves {
+		line.WriteString(" " + m.String())
+	}
+	fmt.Fprintf(e.Output(), "%d legal moves:%s\n", len(moves), line.String())
+	return true
+}
+
+func (c *Bench) Exec(e *search.Engine) bool {
+	defer e.WG.Done()
+	if err := c.run(e.Output()); err != nil {
+		fmt.Fprintf(e.Output(), "info string %v\n", err)
+	}
+	return true
+}
+
+// parseBench reads the bench arguments "[depth] [threads] [hash] [file]" in
+// the order OpenBench passes them. The search is single-threaded, so threads
+// is accepted and ignored.
+func parseBench(args []string) (*Bench, error) {
+	c := &Bench{depth: search.DefaultBenchDepth, threads: 1, hash: search.DefaultBenchHash}
+	for i, n := range []*int{&c.depth, &c.threads, &c.hash} {
+		if i >= len(args) {
+			break
+		}
+		v, err := strconv.Atoi(args[i])
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("invalid bench argument %q", args[i])
+		}
+		*n = v
+	}
+	if len(args) > 3 {
+		c.file = args[3]
+	}
+	return c, nil
+}
+
+// RunBench runs bench with the arguments "[depth] [threads] [hash] [file]".
+func RunBench(w io.Writer, args []string) error {
+	c, err := parseBench(args)
+	if err != nil {
+		return err
+	}
+	return c.run(w)
+}
+
+// run searches the bench positions and prints the node count of each and the
+// totals in the format OpenBench parses.
+func (c *Bench) run(w io.Writer) error {
+	positions := search.BenchPositions
+	if c.file != "" {
+		var err error
+		if positions, err = search.ReadBenchFile(c.file); err != nil {
+			return err
+		}
+	}
+	nodes, elapsed, err := search.Bench(positions, c.depth, c.hash)
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	for i, n := range nodes {
+		fmt.Fprintf(w, "Position %2d/%d: %d nodes\n", i+1, len(nodes), n)
+		total += n
+	}
+	nps := int64(total)
+	if elapsed.Milliseconds() > 0 {
+		nps = (1000 * int64(total)) / elapsed.Milliseconds()
+	}
+	fmt.Fprintln(w, "===========================")
+	fmt.Fprintf(w, "Total time (ms) : %d\n", elapsed.Milliseconds())
+	fmt.Fprintf(w, "Nodes searched  : %d\n", total)
+	fmt.Fprintf(w, "Nodes/second    : %d\n", nps)
+	return nil
+}
@@ -0,0 +1,83 @@
+package uci
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/likeawizard/tofiks/pkg/search"
+)
+
+// debugLog wraps the engine output and tees all traffic to a file. Input lines
+// are marked with >> and output lines with <<.
+type debugLog struct {
+	out     io.Writer // The wrapped output, nil for os.Stdout.
+	file    *os.File
+	partial []byte
+	mu      sync.Mutex
+}
+
+// debugLogMu guards the engine output against being swapped by SetDebugLog
+// while logInput looks at it. Handle logs stop and unknown lines without
+// waiting for the setoption running before them.
+var debugLogMu sync.Mutex
+
+// SetDebugLog starts logging the engine traffic to path, replacing a log
+// already in use. An empty path stops logging.
+func SetDebugLog(e *search.Engine, path string) error {
+	debugLogMu.Lock()
+	defer debugLogMu.Unlock()
+	if l, ok := e.Out.(*debugLog); ok {
+		e.Out = l.out
+		l.file.Close()
+	}
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	e.Out = &debugLog{out: e.Out, file: f}
+	return nil
+}
+
+// logInput records an input line if the engine output is being logged.
+func logInput(e *search.Engine, line string) {
+	debugLogMu.Lock()
+	defer debugLogMu.Unlock()
+	if l, ok := e.Out.(*debugLog); ok {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.writeLine(">>", line)
+	}
+}
+
+func (l *debugLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Log complete lines only; output is not always written a line at a time.
+	l.partial = append(l.partial, p...)
+	for {
+		i := bytes.IndexByte(l.partial, '\n')
+		if i < 0 {
+			break
+		}
+		l.writeLine("<<", string(l.partial[:i]))
+		l.partial = l.partial[i+1:]
+	}
+
+	if l.out == nil {
+		return os.Stdout.Write(p)
+	}
+	return l.out.Write(p)
+}
+
+func (l *debugLog) writeLine(dir, line string) {
+	fmt.Fprintf(l.file, "%s %s %s\n", time.Now().Format("2006-01-02 15:04:05.000"), dir, line)
+}
@@ -0,0 +1,71 @@
+package uci
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/likeawizard/tofiks/pkg/search"
+)
+
+func TestDebugLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "debug.log")
+	var out bytes.Buffer
+	e := search.NewEngine()
+	e.Out = &out
+
+	for _, line := range []string{
+		"setoption name Debug Log File value " + path,
+		"debug on",
+		"position startpos moves e2e4",
+		"isready",
+		"setoption name Debug Log File value <empty>",
+		"isready",
+	} {
+		Handle(e, line)
+	}
+	e.WG.Wait()
+
+	assert.Equal(t, "info string position rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1\nreadyok\nreadyok\n", out.String())
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []string
+	for line := range strings.Lines(string(data)) {
+		// Drop the date and time.
+		fields := strings.SplitN(strings.TrimSpace(line), " ", 3)
+		entries = append(entries, fields[2])
+	}
+	assert.Equal(t, []string{
+		">> debug on",
+		">> position startpos moves e2e4",
+		"<< info string position rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+		">> isready",
+		"<< readyok",
+		">> setoption name Debug Log File value <empty>",
+	}, entries)
+}
+
+// TestDebugLogSwap logs stop and unknown lines, which Handle runs without
+// waiting, while setoption swaps the log. Run with -race.
+func TestDebugLogSwap(t *testing.T) {
+	dir := t.TempDir()
+	e := search.NewEngine()
+	e.Out = &bytes.Buffer{}
+	for i := range 20 {
+		Handle(e, "setoption name Debug Log File value "+filepath.Join(dir, fmt.Sprintf("debug%d.log", i%2)))
+		Handle(e, "stop")
+		Handle(e, "no such command")
+	}
+	Handle(e, "setoption name Debug Log File value <empty>")
+	e.WG.Wait()
+	_, logging := e.Out.(*debugLog)
+	assert.False(t, logging)
+}
@@ -0,0 +1,20 @@
+package uci
+
+import (
+	"github.com/likeawizard/tofiks/pkg/eval"
+	"github.com/likeawizard/tofiks/pkg/search"
+)
+
+// SetEvalParams applies the evaluation parameter file at path, as written by
+// cmd/texel, or restores the built-in weights for an empty path. Both replace
+// what the Tempo and BishopPair options set before. The weights are shared by
+// every engine in the process.
+func SetEvalParams(e *search.Engine, path string) error {
+	if path == "" {
+		eval.ResetParams()
+	} else if err := eval.LoadParams(path); err != nil {
+		return err
+	}
+	e.Eval.PawnTable.Clear()
+	return nil
+}
@@ -0,0 +1,75 @@
+package uci
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+
+	"github.com/likeawizard/tofiks/pkg/search"
+)
+
+// Listen accepts UCI sessions over TCP on addr. See Serve.
+func Listen(addr string, maxConns int, setup func(*search.Engine) error) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return Serve(ln, maxConns, setup)
+}
+
+// Serve accepts connections on ln until it is closed. Every connection speaks
+// the same UCI protocol as stdin with an engine of its own. When maxConns is
+// positive, connections beyond the limit are refused with an info string.
+// setup, if not nil, configures each new engine. Unless maxConns is 1 the
+// engines run side by side and process-wide options are refused (see
+// SetShared).
+func Serve(ln net.Listener, maxConns int, setup func(*search.Engine) error) error {
+	if maxConns != 1 {
+		SetShared(true)
+		defer SetShared(false)
+	}
+	var slots chan struct{}
+	if maxConns > 0 {
+		slots = make(chan struct{}, maxConns)
+	}
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+			default:
+				fmt.Fprintln(conn, "info string connection limit reached")
+				conn.Close()
+				continue
+			}
+		}
+		go func() {
+			serveConn(conn, setup)
+			if slots != nil {
+				<-slots
+			}
+		}()
+	}
+}
+
+func serveConn(conn net.Conn, setup func(*search.Engine) error) {
+	defer conn.Close()
+	e := search.NewEngine()
+	e.Out = conn
+	if setup != nil {
+		if err := setup(e); err != nil {
+			fmt.Fprintf(conn, "info string %v\n", err)
+			return
+		}
+	}
+	Run(bufio.NewScanner(conn), e)
+
+	// The client quit or disconnected, possibly mid-search. Stop the search
+	// and wait for it before the engine is dropped.
+	e.TC.Abort()
+	e.WG.Wait()
+}
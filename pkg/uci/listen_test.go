@@ -0,0 +1,104 @@
+package uci
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func listen(t *testing.T, maxConns int) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go Serve(ln, maxConns, nil) //nolint:errcheck // Returns when the listener is closed.
+	return ln.Addr().String()
+}
+
+func dial(t *testing.T, addr string) (net.Conn, *bufio.Scanner) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	return conn, bufio.NewScanner(conn)
+}
+
+// readUntil returns the first line with the given prefix.
+func readUntil(t *testing.T, s *bufio.Scanner, prefix string) string {
+	t.Helper()
+	for s.Scan() {
+		if strings.HasPrefix(s.Text(), prefix) {
+			return s.Text()
+		}
+	}
+	t.Fatalf("no line starting with %q: %v", prefix, s.Err())
+	return ""
+}
+
+func TestTCPSession(t *testing.T) {
+	addr := listen(t, 0)
+	conn, s := dial(t, addr)
+
+	fmt.Fprintln(conn, "uci")
+	readUntil(t, s, "uciok")
+	fmt.Fprintln(conn, "isready")
+	readUntil(t, s, "readyok")
+	fmt.Fprintln(conn, "position fen 7k/5Q2/6K1/8/8/8/8/8 w - - 0 1")
+	fmt.Fprintln(conn, "go depth 3")
+	assert.Equal(t, "bestmove f7h7", readUntil(t, s, "bestmove"))
+}
+
+func TestTCPConnectionLimit(t *testing.T) {
+	addr := listen(t, 1)
+	first, s := dial(t, addr)
+	fmt.Fprintln(first, "go infinite")
+	readUntil(t, s, "info depth")
+
+	_, refused := dial(t, addr)
+	assert.Equal(t, "info string connection limit reached", readUntil(t, refused, "info string"))
+
+	// Disconnecting mid-search must abort it and free the slot.
+	first.Close()
+	assert.Eventually(t, func() bool {
+		conn, s := dial(t, addr)
+		fmt.Fprintln(conn, "isready")
+		return s.Scan() && s.Text() == "readyok"
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+// TestTCPOptionsPerConnection sets options on one connection while another
+// searches. Search weights belong to each engine; eval weights are shared by
+// the process and refused. Run with -race.
+func TestTCPOptionsPerConnection(t *testing.T) {
+	addr := listen(t, 0)
+	first, s1 := dial(t, addr)
+	second, s2 := dial(t, addr)
+
+	fmt.Fprintln(first, "go infinite")
+	readUntil(t, s1, "info depth")
+	for _, line := range []string{
+		"setoption name RFPMargin value 120",
+		"setoption name SingularMargin value 4",
+		"setoption name TempoMG value 40",
+		"setoption name EvalParams value <empty>",
+		"go depth 4",
+	} {
+		fmt.Fprintln(second, line)
+	}
+	assert.Equal(t, "info string TempoMG is shared by all engines of the process and cannot be changed while several run", readUntil(t, s2, "info string"))
+	assert.Equal(t, "info string EvalParams is shared by all engines of the process and cannot be changed while several run", readUntil(t, s2, "info string"))
+	readUntil(t, s2, "bestmove")
+
+	fmt.Fprintln(first, "stop")
+	readUntil(t, s1, "bestmove")
+}
@@ -0,0 +1,192 @@
+package uci
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/likeawizard/tofiks/pkg/book"
+	"github.com/likeawizard/tofiks/pkg/eval"
+	"github.com/likeawizard/tofiks/pkg/search"
+)
+
+// optionParsers builds the Opt for a setoption value, keyed by optionKey of
+// the option name. It serves both setoption and the config file.
+var optionParsers = map[string]func(value string) (Opt, error){
+	"ponder": func(value string) (Opt, error) {
+		enable, err := strconv.ParseBool(value)
+		return &Ponder{enable: enable}, err
+	},
+	"hash": func(value string) (Opt, error) {
+		size, err := parseSpin(value, 1, 256)
+		return &Hash{size: size}, err
+	},
+	"clearhash": func(string) (Opt, error) {
+		return &Clear{}, nil
+	},
+	"moveoverhead": func(value string) (Opt, error) {
+		delay, err := parseSpin(value, 0, 1000)
+		return &MoveOverhead{delay: delay}, err
+	},
+	"ownbook": func(value string) (Opt, error) {
+		enable, err := strconv.ParseBool(value)
+		return &OwnBook{enable: enable}, err
+	},
+	"contempt": func(value string) (Opt, error) {
+		v, err := parseSpin(value, -200, 200)
+		return &Contempt{value: v}, err
+	},
+	"analysiscontempt": func(value string) (Opt, error) {
+		for mode, name := range search.AnalysisContemptModes {
+			if strings.EqualFold(value, name) {
+				return &AnalysisContempt{mode: mode}, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown mode %q", value)
+	},
+	"uci_showwdl": func(value string) (Opt, error) {
+		enable, err := strconv.ParseBool(value)
+		return &ShowWDL{enable: enable}, err
+	},
+	"normalizescore": func(value string) (Opt, error) {
+		enable, err := strconv.ParseBool(value)
+		return &NormalizeScore{enable: enable}, err
+	},
+	"bookfile": func(value string) (Opt, error) {
+		return &BookFile{path: value}, nil
+	},
+	"bookmode": func(value string) (Opt, error) {
+		for mode, name := range book.Modes {
+			if strings.EqualFold(value, name) {
+				return &BookMode{mode: book.Mode(mode)}, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown mode %q", value)
+	},
+	"bookdepth": func(value string) (Opt, error) {
+		depth, err := parseSpin(value, 0, 255)
+		return &BookDepth{depth: depth}, err
+	},
+	"bookminweight": func(value string) (Opt, error) {
+		percent, err := parseSpin(value, 0, 100)
+		return &BookMinWeight{percent: percent}, err
+	},
+	"booklearning": func(value string) (Opt, error) {
+		enable, err := strconv.ParseBool(value)
+		return &BookLearning{enable: enable}, err
+	},
+	"threads": func(value string) (Opt, error) {
+		_, err := parseSpin(value, 1, 1)
+		return &Threads{}, err
+	},
+	"debuglogfile": func(value string) (Opt, error) {
+		if value == "<empty>" {
+			value = ""
+		}
+		return &DebugLogFile{path: value}, nil
+	},
+	"evalparams": func(value string) (Opt, error) {
+		if value == "<empty>" {
+			value = ""
+		}
+		return &EvalParams{path: value}, nil
+	},
+}
+
+// tunables are search and eval weights exposed as spin options for tuning.
+// The search weights belong to the engine, the eval weights to the process.
+var tunables = []*tunable{
+	newTunable("RFPMargin", func(p *search.Params) *int { return &p.RFPMargin }, 0, 400),
+	newTunable("FutilityMargin", func(p *search.Params) *int { return &p.FutilityMargin }, 0, 400),
+	newTunable("AspirationLow", func(p *search.Params) *int { return &p.AspirationLow }, 5, 400),
+	newTunable("AspirationHigh", func(p *search.Params) *int { return &p.AspirationHigh }, 5, 400),
+	newTunable("NMPBase", func(p *search.Params) *int { return &p.NMPBase }, 1, 6),
+	newTunable("NMPDepthDiv", func(p *search.Params) *int { return &p.NMPDepthDiv }, 1, 20),
+	newTunable("LMPBase", func(p *search.Params) *int { return &p.LMPBase }, 0, 20),
+	newTunable("SingularMargin", func(p *search.Params) *int { return &p.SingularMargin }, 0, 10),
+	newScoreTunable("Tempo", &eval.Tempo, bothStages, 0, 100),
+	newScoreTunable("TempoMG", &eval.Tempo, 0, 0, 100),
+	newScoreTunable("TempoEG", &eval.Tempo, 1, 0, 100),
+	newScoreTunable("BishopPair", &eval.BishopPair, bothStages, 0, 100),
+	newScoreTunable("BishopPairMG", &eval.BishopPair, 0, 0, 100),
+	newScoreTunable("BishopPairEG", &eval.BishopPair, 1, 0, 100),
+}
+
+func init() {
+	for _, t := range tunables {
+		optionParsers[optionKey(t.name)] = func(value string) (Opt, error) {
+			v, err := parseSpin(value, t.min, t.max)
+			return &Tunable{param: t, value: v}, err
+		}
+	}
+}
+
+// newTunable exposes a search weight of the engine.
+func newTunable(name string, field func(*search.Params) *int, minValue, maxValue int) *tunable {
+	def := search.DefaultParams()
+	return &tunable{
+		set:  func(e *search.Engine, v int) { *field(&e.Params) = v },
+		name: name, def: *field(&def), min: minValue, max: maxValue,
+	}
+}
+
+// bothStages selects both halves of a Score.
+const bothStages = -1
+
+// newScoreTunable exposes the middlegame (stage 0) or endgame (stage 1) half
+// of an eval Score, or sets both to the same value with bothStages, as the
+// untapered options of earlier versions did. It is a process-wide option.
+func newScoreTunable(name string, score *eval.Score, stage, minValue, maxValue int) *tunable {
+	t := &tunable{name: name, min: minValue, max: maxValue, process: true}
+	switch stage {
+	case bothStages:
+		t.def = score.MG()
+		t.set = func(_ *search.Engine, v int) { *score = eval.S(v, v) }
+	case 0:
+		t.def = score.MG()
+		t.set = func(_ *search.Engine, v int) { *score = eval.S(v, score.EG()) }
+	default:
+		t.def = score.EG()
+		t.set = func(_ *search.Engine, v int) { *score = eval.S(score.MG(), v) }
+	}
+	return t
+}
+
+// availableOptions lists the options announced in reply to uci, in order.
+func availableOptions() []Opt {
+	opts := []Opt{&Ponder{}, &Hash{}, &Clear{}, &MoveOverhead{}, &OwnBook{}, &BookFile{}, &BookMode{}, &BookDepth{}, &BookMinWeight{}, &BookLearning{}, &Threads{}, &Contempt{}, &AnalysisContempt{}, &ShowWDL{}, &NormalizeScore{}, &DebugLogFile{}, &EvalParams{}}
+	for _, t := range tunables {
+		opts = append(opts, &Tunable{param: t})
+	}
+	return opts
+}
+
+// newOption builds the option called name from its value.
+func newOption(name, value string) (Opt, error) {
+	parse, ok := optionParsers[optionKey(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown option %q", name)
+	}
+	opt, err := parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q for %s", value, name)
+	}
+	return opt, nil
+}
+
+// optionKey normalizes an option name so that "Move Overhead" and
+// "moveOverhead" refer to the same option.
+func optionKey(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, " ", ""))
+}
+
+func parseSpin(value string, minValue, maxValue int) (int, error) {
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if v < minValue || v > maxValue {
+		return 0, fmt.Errorf("%d out of range [%d, %d]", v, minValue, maxValue)
+	}
+	return v, nil
+}
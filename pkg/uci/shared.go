@@ -0,0 +1,79 @@
+package uci
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/likeawizard/tofiks/pkg/search"
+)
+
+// processOption is an option that may set state shared by every engine of the
+// process, such as eval weights, instead of the engine's own.
+type processOption interface {
+	Opt
+	// setting returns the option name and value, and whether setting it
+	// changes process-wide state.
+	setting() (name, value string, process bool)
+}
+
+// fallibleOption is an option whose setting can fail. Set reports the error
+// as an info string, apply returns it.
+type fallibleOption interface {
+	Opt
+	apply(e *search.Engine) error
+}
+
+// processOptions records the values process-wide options were set to while
+// the process ran a single engine. Once several engines run at a time, they
+// may only be set to the same values again, which leaves them alone.
+var processOptions = struct {
+	sync.Mutex
+	shared bool
+	values map[string]string
+}{values: make(map[string]string)}
+
+// SetShared marks whether several engines run in the process at a time. While
+// they do, options that change process-wide state, such as the eval weights,
+// are refused unless they repeat the value set before, as they would change
+// the searches of the other engines.
+func SetShared(shared bool) {
+	processOptions.Lock()
+	defer processOptions.Unlock()
+	processOptions.shared = shared
+}
+
+// setOption sets opt on the engine, unless it would change the process-wide
+// state other engines are using.
+func setOption(e *search.Engine, opt Opt) error {
+	p, ok := opt.(processOption)
+	if !ok {
+		return set(e, opt)
+	}
+	name, value, process := p.setting()
+	if !process {
+		return set(e, opt)
+	}
+
+	processOptions.Lock()
+	defer processOptions.Unlock()
+	key := optionKey(name)
+	if processOptions.shared {
+		if old, ok := processOptions.values[key]; ok && old == value {
+			return nil
+		}
+		return fmt.Errorf("%s is shared by all engines of the process and cannot be changed while several run", name)
+	}
+	if err := set(e, opt); err != nil {
+		return err
+	}
+	processOptions.values[key] = value
+	return nil
+}
+
+func set(e *search.Engine, opt Opt) error {
+	if f, ok := opt.(fallibleOption); ok {
+		return f.apply(e)
+	}
+	opt.Set(e)
+	return nil
+}
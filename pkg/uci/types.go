@@ -1,12 +1,15 @@
 package uci
 
 import (
+	"io"
+
+	"github.com/likeawizard/tofiks/pkg/book"
 	"github.com/likeawizard/tofiks/pkg/search"
 )
 
 const (
-	CmdUci = "uci"
-	// CmdDebug      = "debug" // on | off.
+	CmdUci       = "uci"
+	CmdDebug     = "debug" // on | off
 	CmdIsReady   = "isready"
 	CmdSetOption = "setoption" // name [value]
 	CmdPosition  = "position"  // [ fen | startpos] moves ...
@@ -15,6 +18,14 @@ const (
 	CmdPonderhit = "ponderhit"
 	CmdQuit      = "quit"
 	CmdNewGame   = "ucinewgame"
+
+	// Non-standard console commands for debugging.
+	CmdDisplay = "d"
+	CmdEval    = "eval"
+	CmdFlip    = "flip"
+	CmdMoves   = "moves"
+	CmdBench   = "bench"  // [depth] [threads] [hash] [file]
+	CmdResult  = "result" // 1-0 | 0-1 | 1/2-1/2, ends the game for book learning
 )
 
 type Cmd interface {
@@ -44,7 +55,9 @@ type Go struct {
 	depth     int
 	movetime  int
 	movestogo int
+	nodes     int
 	infinite  bool
+	ponder    bool
 	isPerft   bool
 }
 
@@ -54,8 +67,32 @@ type SetOption struct {
 
 type NewGame struct{}
 
+type Debug struct {
+	on bool
+}
+
+type Display struct{}
+
+// Result ends the game with a known result for book learning.
+type Result struct {
+	score int
+}
+
+type Evaluate struct{}
+
+type Flip struct{}
+
+type Moves struct{}
+
+type Bench struct {
+	file    string
+	depth   int
+	threads int
+	hash    int
+}
+
 type Opt interface {
-	Info()
+	Info(w io.Writer)
 	Set(e *search.Engine)
 }
 
@@ -76,3 +113,66 @@ type Clear struct{}
 type MoveOverhead struct {
 	delay int
 }
+
+type DebugLogFile struct {
+	path string
+}
+
+type EvalParams struct {
+	path string
+}
+
+type Contempt struct {
+	value int
+}
+
+type AnalysisContempt struct {
+	mode int
+}
+
+type ShowWDL struct {
+	enable bool
+}
+
+type NormalizeScore struct {
+	enable bool
+}
+
+type BookFile struct {
+	path string
+}
+
+type BookMode struct {
+	mode book.Mode
+}
+
+type BookDepth struct {
+	depth int
+}
+
+type BookMinWeight struct {
+	percent int
+}
+
+type BookLearning struct {
+	enable bool
+}
+
+// Threads is accepted for GUI compatibility; the search is single-threaded.
+type Threads struct{}
+
+// tunable is an integer search or eval weight exposed as a UCI option.
+type tunable struct {
+	set  func(*search.Engine, int)
+	name string
+	def  int
+	min  int
+	max  int
+	// process marks a weight shared by every engine of the process.
+	process bool
+}
+
+type Tunable struct {
+	param *tunable
+	value int
+}
@@ -1,11 +1,55 @@
 package uci
 
 import (
+	"bufio"
 	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/likeawizard/tofiks/pkg/pgn"
+	"github.com/likeawizard/tofiks/pkg/search"
 )
 
+// Run reads UCI commands line by line and executes them on the engine until
+// quit is received or the input is exhausted.
+func Run(input *bufio.Scanner, e *search.Engine) {
+	for input.Scan() {
+		if Handle(e, input.Text()) {
+			return
+		}
+	}
+}
+
+// Handle parses and executes a single UCI command line. Commands other than
+// stop run in the background once the previous command has finished. Returns
+// true when the engine should quit. The line is logged when it is executed so
+// that the debug log keeps input and output in order.
+func Handle(e *search.Engine, line string) bool {
+	cmd := ParseUCI(line)
+	switch c := cmd.(type) {
+	case nil:
+		logInput(e, line)
+	case *Quit:
+		logInput(e, line)
+		return true
+	case *Stop:
+		logInput(e, line)
+		go cmd.Exec(e)
+	case *Go:
+		e.WG.Wait()
+		logInput(e, line)
+		c.arm(e)
+		e.WG.Add(1)
+		go cmd.Exec(e)
+	default:
+		e.WG.Wait()
+		logInput(e, line)
+		e.WG.Add(1)
+		go cmd.Exec(e)
+	}
+	return false
+}
+
 // Parse uci command and return executable Cmd on successful parse or nil.
 func ParseUCI(uciCmd string) Cmd {
 	cmdRE := regexp.MustCompile(`(?P<cmd>^\w+)\s?(?P<args>.*)`)
@@ -19,6 +63,8 @@ func ParseUCI(uciCmd string) Cmd {
 	switch cmd {
 	case CmdUci:
 		return &UCI{}
+	case CmdDebug:
+		return &Debug{on: strings.TrimSpace(args) == "on"}
 	case CmdIsReady:
 		return &IsReady{}
 	case CmdStop:
@@ -38,38 +84,19 @@ func ParseUCI(uciCmd string) Cmd {
 		return &pos
 	case CmdSetOption:
 		opt := SetOption{}
-		optRE := regexp.MustCompile(`name\s(?P<name>[\w\s]+)\svalue\s(?P<value>\w+)`)
+		optRE := regexp.MustCompile(`name\s(?P<name>.+?)(?:\svalue\s(?P<value>.*))?$`)
 		match = optRE.FindStringSubmatch(args)
+		if match == nil {
+			return nil
+		}
 		name := match[optRE.SubexpIndex("name")]
-		value := match[optRE.SubexpIndex("value")]
-		switch name {
-		case "Ponder":
-			enable := false
-			if value == "true" {
-				enable = true
-			}
-			opt.option = &Ponder{enable: enable}
-			return &opt
-		case "Hash":
-			size, _ := strconv.Atoi(value)
-			opt.option = &Hash{size: size}
-			return &opt
-		case "OwnBook":
-			enable := false
-			if value == "true" {
-				enable = true
-			}
-			opt.option = &OwnBook{enable: enable}
-			return &opt
-		case "Clear Hash":
-			opt.option = &Clear{}
-			return &opt
-		case "Move Overhead":
-			delay, _ := strconv.Atoi(value)
-			opt.option = &MoveOverhead{delay: delay}
-			return &opt
+		value := strings.TrimSpace(match[optRE.SubexpIndex("value")])
+		option, err := newOption(name, value)
+		if err != nil {
+			return nil
 		}
-		return nil
+		opt.option = option
+		return &opt
 	case CmdGo:
 		goCmd := Go{}
 		goParts := strings.Fields(args)
@@ -89,8 +116,13 @@ func ParseUCI(uciCmd string) Cmd {
 				goCmd.depth, _ = strconv.Atoi(goParts[i+1])
 			case "movetime":
 				goCmd.movetime, _ = strconv.Atoi(goParts[i+1])
-			case "infinite", "ponder":
+			case "nodes":
+				goCmd.nodes, _ = strconv.Atoi(goParts[i+1])
+			case "infinite":
 				goCmd.infinite = true
+			case "ponder":
+				goCmd.infinite = true
+				goCmd.ponder = true
 			case "perft": // non-uci command execute perft instead
 				goCmd.isPerft = true
 				goCmd.depth, _ = strconv.Atoi(goParts[i+1])
@@ -99,6 +131,26 @@ func ParseUCI(uciCmd string) Cmd {
 		return &goCmd
 	case CmdNewGame:
 		return &NewGame{}
+	case CmdDisplay:
+		return &Display{}
+	case CmdResult:
+		score, ok := pgn.Score(strings.TrimSpace(args))
+		if !ok {
+			return nil
+		}
+		return &Result{score: score}
+	case CmdEval:
+		return &Evaluate{}
+	case CmdFlip:
+		return &Flip{}
+	case CmdMoves:
+		return &Moves{}
+	case CmdBench:
+		bench, err := parseBench(strings.Fields(args))
+		if err != nil {
+			return nil
+		}
+		return bench
 	}
 
 	return nil
@@ -2,6 +2,8 @@ package uci
 
 import (
 	"fmt"
+	"io"
+	"strconv"
 	"time"
 
 	"github.com/likeawizard/tofiks/pkg/board"
@@ -9,13 +11,12 @@ import (
 	"github.com/likeawizard/tofiks/pkg/search"
 )
 
-func (c *Go) Exec(e *search.Engine) bool {
-	defer e.WG.Done()
+// arm sets the clock and the time control before the search goroutine is
+// started, so that a stop sent right after go cannot be lost.
+func (c *Go) arm(e *search.Engine) {
 	if c.isPerft {
-		e.Board.PerftDebug(c.depth)
-		return true
+		return
 	}
-
 	e.Clock.Wtime = c.wtime
 	e.Clock.Winc = c.winc
 	e.Clock.Btime = c.btime
@@ -23,12 +24,24 @@ func (c *Go) Exec(e *search.Engine) bool {
 	e.Clock.Movestogo = c.movestogo
 	e.Clock.Movetime = c.movetime
 	e.Clock.Infinite = c.infinite
+	e.Analysis = c.infinite && !c.ponder
+	e.NodeLimit = c.nodes
+	e.TC = e.Clock.NewTimeControl(int(e.Board.FullMoveCounter), e.Board.Side)
+}
+
+func (c *Go) Exec(e *search.Engine) bool {
+	defer e.WG.Done()
+	if c.isPerft {
+		e.Board.PerftDebug(e.Output(), c.depth)
+		return true
+	}
+
 	depth := c.depth
 	if depth == 0 {
 		depth = 50
 	}
 	defer func() { e.TC.Stop() }()
-	move, ponder := e.GetMove(depth, c.infinite)
+	move, ponder := e.FindMove(depth, c.infinite)
 	e.ReportMove(move, ponder, e.Ponder)
 
 	return true
@@ -50,40 +63,46 @@ func (c *Quit) Exec(_ *search.Engine) bool {
 func (c *Position) Exec(e *search.Engine) bool {
 	defer e.WG.Done()
 	e.Board = board.NewBoard(c.pos)
-	return e.PlayMovesUCI(c.moves)
+	ok := e.PlayMovesUCI(c.moves)
+	e.Debugf("position %s", e.Board.ExportFEN())
+	return ok
+}
+
+func (c *Debug) Exec(e *search.Engine) bool {
+	defer e.WG.Done()
+	e.Debug = c.on
+	return true
 }
 
 func (c *IsReady) Exec(e *search.Engine) bool {
 	defer e.WG.Done()
-	fmt.Println("readyok")
+	fmt.Fprintln(e.Output(), "readyok")
 	return true
 }
 
 func (c *UCI) Exec(e *search.Engine) bool {
 	defer e.WG.Done()
-	availOpts := []Opt{&Ponder{}, &Hash{}, &Clear{}, &MoveOverhead{}, &OwnBook{}}
-	fmt.Println("id name Tofiks v1.5.0")
-	fmt.Println("id author Arturs Priede")
+	availOpts := availableOptions()
+	fmt.Fprintln(e.Output(), "id name "+search.Name)
+	fmt.Fprintln(e.Output(), "id author Arturs Priede")
 	for _, opt := range availOpts {
-		opt.Info()
+		opt.Info(e.Output())
 	}
-	fmt.Println("uciok")
+	fmt.Fprintln(e.Output(), "uciok")
 	return true
 }
 
 func (c *SetOption) Exec(e *search.Engine) bool {
 	defer e.WG.Done()
-	c.option.Set(e)
+	if err := setOption(e, c.option); err != nil {
+		fmt.Fprintf(e.Output(), "info string %v\n", err)
+	}
 	return true
 }
 
 func (c *NewGame) Exec(e *search.Engine) bool {
 	defer e.WG.Done()
-	e.TTable.Clear()
-	e.Eval.PawnTable.Clear()
-	e.KillerMoves = [100][2]board.Move{}
-	e.Plys = [512]uint64{}
-	e.History = search.HistoryHeuristic{}
+	e.NewGame()
 	return true
 }
 
@@ -91,26 +110,31 @@ func (o *Hash) Set(e *search.Engine) {
 	e.TTable = search.NewTTable(o.size)
 }
 
-func (o *Hash) Info() {
-	fmt.Println("option name Hash type spin default 64 min 1 max 256")
+func (o *Hash) Info(w io.Writer) {
+	fmt.Fprintln(w, "option name Hash type spin default 64 min 1 max 256")
 }
 
+// Set enables the book, opening the default book file unless Book File
+// already opened one.
 func (o *OwnBook) Set(e *search.Engine) {
 	e.OwnBook = o.enable
+	if o.enable && e.Book.Path() == "" {
+		if err := e.Book.Open(book.DefaultFile); err != nil {
+			fmt.Fprintf(e.Output(), "info string no book: %v\n", err)
+		}
+	}
 }
 
-func (o *OwnBook) Info() {
-	if book.LoadBook("book.bin") > 0 {
-		fmt.Println("option name OwnBook type check default false")
-	}
+func (o *OwnBook) Info(w io.Writer) {
+	fmt.Fprintln(w, "option name OwnBook type check default false")
 }
 
 func (o *Ponder) Set(e *search.Engine) {
 	e.Ponder = o.enable
 }
 
-func (o *Ponder) Info() {
-	fmt.Println("option name Ponder type check default false")
+func (o *Ponder) Info(w io.Writer) {
+	fmt.Fprintln(w, "option name Ponder type check default false")
 }
 
 func (o *Clear) Set(e *search.Engine) {
@@ -118,14 +142,146 @@ func (o *Clear) Set(e *search.Engine) {
 	e.Eval.PawnTable.Clear()
 }
 
-func (o *Clear) Info() {
-	fmt.Println("option name Clear Hash type button")
+func (o *Clear) Info(w io.Writer) {
+	fmt.Fprintln(w, "option name Clear Hash type button")
 }
 
 func (o *MoveOverhead) Set(e *search.Engine) {
 	e.Clock.Overhead = o.delay
 }
 
-func (o *MoveOverhead) Info() {
-	fmt.Println("option name Move Overhead type spin default 0 min 0 max 1000")
+func (o *MoveOverhead) Info(w io.Writer) {
+	fmt.Fprintln(w, "option name Move Overhead type spin default 0 min 0 max 1000")
+}
+
+func (o *DebugLogFile) Set(e *search.Engine) {
+	if err := o.apply(e); err != nil {
+		fmt.Fprintf(e.Output(), "info string %v\n", err)
+	}
+}
+
+func (o *DebugLogFile) apply(e *search.Engine) error {
+	return SetDebugLog(e, o.path)
+}
+
+func (o *DebugLogFile) Info(w io.Writer) {
+	fmt.Fprintln(w, "option name Debug Log File type string default <empty>")
+}
+
+func (o *EvalParams) Set(e *search.Engine) {
+	if err := o.apply(e); err != nil {
+		fmt.Fprintf(e.Output(), "info string %v\n", err)
+	}
+}
+
+func (o *EvalParams) apply(e *search.Engine) error {
+	return SetEvalParams(e, o.path)
+}
+
+func (o *EvalParams) setting() (string, string, bool) {
+	return "EvalParams", o.path, true
+}
+
+func (o *EvalParams) Info(w io.Writer) {
+	fmt.Fprintln(w, "option name EvalParams type string default <empty>")
+}
+
+func (o *BookFile) Set(e *search.Engine) {
+	if err := e.Book.Open(o.path); err != nil {
+		fmt.Fprintf(e.Output(), "info string no book: %v\n", err)
+	}
+}
+
+func (o *BookFile) Info(w io.Writer) {
+	fmt.Fprintf(w, "option name Book File type string default %s\n", book.DefaultFile)
+}
+
+func (o *BookMode) Set(e *search.Engine) {
+	e.Book.Mode = o.mode
+}
+
+func (o *BookMode) Info(w io.Writer) {
+	fmt.Fprintf(w, "option name Book Mode type combo default %s", book.Modes[book.Weighted])
+	for _, mode := range book.Modes {
+		fmt.Fprint(w, " var ", mode)
+	}
+	fmt.Fprintln(w)
+}
+
+func (o *BookDepth) Set(e *search.Engine) {
+	e.Book.Depth = o.depth
+}
+
+func (o *BookDepth) Info(w io.Writer) {
+	fmt.Fprintln(w, "option name Book Depth type spin default 0 min 0 max 255")
+}
+
+func (o *BookMinWeight) Set(e *search.Engine) {
+	e.Book.MinWeight = o.percent
+}
+
+func (o *BookMinWeight) Info(w io.Writer) {
+	fmt.Fprintf(w, "option name Book Min Weight type spin default %d min 0 max 100\n", book.DefaultMinWeight)
+}
+
+func (o *BookLearning) Set(e *search.Engine) {
+	e.Book.Learning = o.enable
+}
+
+func (o *BookLearning) Info(w io.Writer) {
+	fmt.Fprintln(w, "option name Book Learning type check default false")
+}
+
+func (o *Contempt) Set(e *search.Engine) {
+	e.Contempt = o.value
+}
+
+func (o *Contempt) Info(w io.Writer) {
+	fmt.Fprintln(w, "option name Contempt type spin default 0 min -200 max 200")
+}
+
+func (o *AnalysisContempt) Set(e *search.Engine) {
+	e.AnalysisContempt = o.mode
+}
+
+func (o *AnalysisContempt) Info(w io.Writer) {
+	fmt.Fprint(w, "option name Analysis Contempt type combo default Off")
+	for _, mode := range search.AnalysisContemptModes {
+		fmt.Fprint(w, " var ", mode)
+	}
+	fmt.Fprintln(w)
+}
+
+func (o *ShowWDL) Set(e *search.Engine) {
+	e.ShowWDL = o.enable
+}
+
+func (o *ShowWDL) Info(w io.Writer) {
+	fmt.Fprintln(w, "option name UCI_ShowWDL type check default false")
+}
+
+func (o *NormalizeScore) Set(e *search.Engine) {
+	e.NormalizeScore = o.enable
+}
+
+func (o *NormalizeScore) Info(w io.Writer) {
+	fmt.Fprintln(w, "option name Normalize Score type check default false")
+}
+
+func (o *Threads) Set(_ *search.Engine) {}
+
+func (o *Threads) Info(w io.Writer) {
+	fmt.Fprintln(w, "option name Threads type spin default 1 min 1 max 1")
+}
+
+func (o *Tunable) Set(e *search.Engine) {
+	o.param.set(e, o.value)
+}
+
+func (o *Tunable) setting() (string, string, bool) {
+	return o.param.name, strconv.Itoa(o.value), o.param.process
+}
+
+func (o *Tunable) Info(w io.Writer) {
+	fmt.Fprintf(w, "option name %s type spin default %d min %d max %d\n", o.param.name, o.param.def, o.param.min, o.param.max)
 }
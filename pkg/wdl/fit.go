@@ -0,0 +1,106 @@
+package wdl
+
+import (
+	"math"
+)
+
+// Sample is one position with its score and the final game result, both from
+// the same side's point of view. Result is 1, 0.5 or 0.
+type Sample struct {
+	Score    int
+	Material int
+	Result   float64
+}
+
+// bucket aggregates all samples sharing a score and material.
+type bucket struct {
+	counts   [3]float64 // Losses, draws, wins.
+	score    float64
+	material int
+}
+
+func aggregate(samples []Sample) []bucket {
+	type key struct{ score, material int }
+	index := make(map[key]int)
+	var buckets []bucket
+	for _, s := range samples {
+		k := key{s.Score, s.Material}
+		i, ok := index[k]
+		if !ok {
+			i = len(buckets)
+			index[k] = i
+			buckets = append(buckets, bucket{score: float64(s.Score), material: s.Material})
+		}
+		buckets[i].counts[int(2*s.Result)]++
+	}
+	return buckets
+}
+
+const probFloor = 1e-9
+
+// LogLoss returns the mean negative log-likelihood of the samples under m.
+func (m *Model) LogLoss(samples []Sample) float64 {
+	loss, _ := m.gradient(aggregate(samples))
+	return loss / float64(len(samples))
+}
+
+// gradient returns the total negative log-likelihood over the buckets and its
+// gradient with respect to the A and B coefficients.
+func (m *Model) gradient(buckets []bucket) (float64, [8]float64) {
+	var total float64
+	var grad [8]float64
+	for i := range buckets {
+		bk := &buckets[i]
+		x := scaledMaterial(bk.material)
+		a, b := m.params(bk.material)
+		u := (bk.score - a) / b
+		s := (-bk.score - a) / b
+		w, l := sigmoid(u), sigmoid(s)
+		d := max(1-w-l, probFloor)
+		dw, dl := w*(1-w), l*(1-l)
+
+		// Derivatives of the negative log-likelihood by a and b.
+		var da, db float64
+		losses, draws, wins := bk.counts[0], bk.counts[1], bk.counts[2]
+		total -= wins*math.Log(max(w, probFloor)) + losses*math.Log(max(l, probFloor)) + draws*math.Log(d)
+		da += wins * (1 - w) / b
+		db += wins * (1 - w) * u / b
+		da += losses * (1 - l) / b
+		db += losses * (1 - l) * s / b
+		da -= draws * (dw + dl) / (b * d)
+		db -= draws * (dw*u + dl*s) / (b * d)
+
+		for k, p := 0, x*x*x; k < 4; k, p = k+1, p/x {
+			grad[k] += da * p
+			grad[4+k] += db * p
+		}
+	}
+	return total, grad
+}
+
+// Fit maximizes the likelihood of the samples with Adam, starting from start.
+func Fit(samples []Sample, start Model, iterations int, lr float64) Model {
+	const beta1, beta2, epsilon = 0.9, 0.999, 1e-8
+	buckets := aggregate(samples)
+	n := float64(len(samples))
+
+	m := start
+	var mom, vel [8]float64
+	for t := 1; t <= iterations; t++ {
+		_, grad := m.gradient(buckets)
+		for k := range grad {
+			g := grad[k] / n
+			mom[k] = beta1*mom[k] + (1-beta1)*g
+			vel[k] = beta2*vel[k] + (1-beta2)*g*g
+			mHat := mom[k] / (1 - math.Pow(beta1, float64(t)))
+			vHat := vel[k] / (1 - math.Pow(beta2, float64(t)))
+			step := lr * mHat / (math.Sqrt(vHat) + epsilon)
+			if k < 4 {
+				m.A[k] -= step
+			} else {
+				m.B[k-4] -= step
+			}
+		}
+	}
+	return m
+}
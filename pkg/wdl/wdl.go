@@ -0,0 +1,97 @@
+// Package wdl converts engine scores into win/draw/loss probabilities.
+//
+// The model is logistic in the score with parameters depending on the
+// material left on the board:
+//
+//	win(v)  = 1 / (1 + exp((a - v) / b))
+//	loss(v) = win(-v)
+//	draw(v) = 1 - win(v) - loss(v)
+//
+// a and b are cubic polynomials in material/58, clamped to [17, 78]. a is the
+// score with an even chance to win, which makes it the normalization unit:
+// a normalized score of 100 is a 50% win probability.
+package wdl
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+)
+
+const (
+	minMaterial = 17
+	maxMaterial = 78
+	// Material is scaled by the value it is typically worth after the opening.
+	materialScale = 58.0
+)
+
+// Model holds the cubic coefficients of a and b, highest power first.
+type Model struct {
+	A [4]float64
+	B [4]float64
+}
+
+// Default is the model used by the engine, fitted by cmd/wdlfit -depth 4 to
+// the 257k quiet positions of 3000 self-play games at 5000 nodes per move.
+// Refit it whenever the eval scale changes.
+var Default = Model{
+	A: [4]float64{212.0314, -160.3353, -373.7142, 712.5975},
+	B: [4]float64{-26.4762, 34.1563, 107.1266, 198.6973},
+}
+
+// Material counts the material on board with the classical 1/3/3/5/9 values.
+func Material(b *board.Board) int {
+	count := func(piece int) int {
+		return b.Pieces[board.White][piece].Count() + b.Pieces[board.Black][piece].Count()
+	}
+	return count(board.Pawns) + 3*count(board.Knights) + 3*count(board.Bishops) +
+		5*count(board.Rooks) + 9*count(board.Queens)
+}
+
+func scaledMaterial(material int) float64 {
+	return float64(min(max(material, minMaterial), maxMaterial)) / materialScale
+}
+
+func poly(c *[4]float64, x float64) float64 {
+	return ((c[0]*x+c[1])*x+c[2])*x + c[3]
+}
+
+// params returns a and b for the given material. Both are kept positive so
+// that the draw probability cannot go negative.
+func (m *Model) params(material int) (a, b float64) {
+	x := scaledMaterial(material)
+	return max(poly(&m.A, x), 1), max(poly(&m.B, x), 1)
+}
+
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+// Probabilities returns the win, draw and loss probabilities for a score in
+// centipawns from the point of view of the side it belongs to.
+func (m *Model) Probabilities(score, material int) (win, draw, loss float64) {
+	a, b := m.params(material)
+	win = sigmoid((float64(score) - a) / b)
+	loss = sigmoid((-float64(score) - a) / b)
+	return win, 1 - win - loss, loss
+}
+
+// WDL returns the probabilities in per mille as reported by UCI_ShowWDL.
+func (m *Model) WDL(score, material int) (win, draw, loss int) {
+	w, _, l := m.Probabilities(score, material)
+	win, loss = int(math.Round(1000*w)), int(math.Round(1000*l))
+	return win, 1000 - win - loss, loss
+}
+
+// Normalize rescales a score so that 100 means a 50% chance to win.
+func (m *Model) Normalize(score, material int) int {
+	a, _ := m.params(material)
+	return int(math.Round(100 * float64(score) / a))
+}
+
+// String prints the model as a Go literal for pasting into Default.
+func (m Model) String() string {
+	return fmt.Sprintf("wdl.Model{\n\tA: [4]float64{%.4f, %.4f, %.4f, %.4f},\n\tB: [4]float64{%.4f, %.4f, %.4f, %.4f},\n}",
+		m.A[0], m.A[1], m.A[2], m.A[3], m.B[0], m.B[1], m.B[2], m.B[3])
+}
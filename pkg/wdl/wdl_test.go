@@ -0,0 +1,86 @@
+package wdl
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+)
+
+func TestModel(t *testing.T) {
+	assert.Equal(t, 78, Material(board.NewBoard(board.StartPos)))
+
+	m := Default
+	for _, material := range []int{10, 40, 78} {
+		win, draw, loss := m.WDL(0, material)
+		assert.Equal(t, win, loss)
+		assert.Equal(t, 1000, win+draw+loss)
+
+		// A normalized score of 100 is an even chance to win, up to the half
+		// centipawn a is rounded by.
+		a, b := m.params(material)
+		score := int(math.Round(a))
+		w, _, _ := m.Probabilities(score, material)
+		assert.InDelta(t, 0.5, w, 0.5/(4*b))
+		assert.Equal(t, 100, m.Normalize(score, material))
+
+		prev := 0.0
+		for score := -1000; score <= 1000; score += 50 {
+			w, d, l := m.Probabilities(score, material)
+			assert.Greater(t, w, prev)
+			assert.GreaterOrEqual(t, d, 0.0)
+			assert.InDelta(t, 1.0, w+d+l, 1e-9)
+			prev = w
+		}
+	}
+}
+
+// TestDefault checks the fitted model at the material typical after the
+// opening. In the self-play games it was fitted to, a 390 centipawn edge won
+// half the games, so a raw score of 100 normalizes to about 26.
+func TestDefault(t *testing.T) {
+	const typical = 58
+	a, _ := Default.params(typical)
+	assert.InDelta(t, 390, a, 5)
+	assert.Equal(t, 100, Default.Normalize(390, typical))
+	assert.Equal(t, 26, Default.Normalize(100, typical))
+
+	// Equal positions are mostly drawn, more so with little material left.
+	_, draw, _ := Default.WDL(0, typical)
+	_, endgameDraw, _ := Default.WDL(0, 20)
+	assert.InDelta(t, 550, draw, 50)
+	assert.Greater(t, endgameDraw, draw)
+}
+
+// TestFit draws results from a known model and checks that fitting recovers it.
+func TestFit(t *testing.T) {
+	truth := Model{
+		A: [4]float64{0, 0, 150, 150},
+		B: [4]float64{0, 0, 40, 60},
+	}
+	rng := rand.New(rand.NewPCG(1, 2))
+	samples := make([]Sample, 200000)
+	for i := range samples {
+		s := Sample{Score: 10*rng.IntN(121) - 600, Material: 17 + rng.IntN(62)}
+		w, d, _ := truth.Probabilities(s.Score, s.Material)
+		switch r := rng.Float64(); {
+		case r < w:
+			s.Result = 1
+		case r < w+d:
+			s.Result = 0.5
+		}
+		samples[i] = s
+	}
+
+	fitted := Fit(samples, Default, 3000, 2)
+	assert.LessOrEqual(t, fitted.LogLoss(samples), truth.LogLoss(samples)+1e-3)
+	for _, material := range []int{20, 50, 78} {
+		wantA, wantB := truth.params(material)
+		a, b := fitted.params(material)
+		assert.Less(t, math.Abs(a-wantA)/wantA, 0.05, "a at material %d", material)
+		assert.Less(t, math.Abs(b-wantB)/wantB, 0.1, "b at material %d", material)
+	}
+}
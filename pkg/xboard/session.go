@@ -0,0 +1,224 @@
+package xboard
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+	"github.com/likeawizard/tofiks/pkg/search"
+)
+
+const (
+	defaultDepth = 50
+	// xboard reports mate in N as 100000+N.
+	mateScore = 100000
+)
+
+// session holds the CECP game state on top of the engine. Commands are
+// handled on the reader goroutine; thinking and analysis run on a search
+// goroutine tracked by wg. Every command that touches the board calls stop first.
+type session struct {
+	out  io.Writer
+	e    *search.Engine
+	outM sync.Mutex
+	wg   sync.WaitGroup
+
+	startFEN string
+	moves    []string
+
+	// Engine clock (time) and opponent clock (otim) in centiseconds.
+	time, otim int
+	// Increment and fixed time per move (st) in milliseconds.
+	inc, moveTime int
+	depth         int
+
+	engineSide int8
+	force      bool
+	analyzing  bool
+
+	post    atomic.Bool
+	discard atomic.Bool
+}
+
+func newSession(out io.Writer, e *search.Engine) *session {
+	s := &session{out: out, e: e}
+	e.OnInfo = s.thinking
+	s.newGame()
+	return s
+}
+
+// send writes a single protocol line.
+func (s *session) send(line string) {
+	s.outM.Lock()
+	defer s.outM.Unlock()
+	fmt.Fprintln(s.out, line)
+}
+
+// thinking prints a search iteration in the xboard post format: ply score time nodes pv.
+func (s *session) thinking(info search.Info) {
+	if !s.post.Load() && !s.analyzing {
+		return
+	}
+
+	score := int(info.Score)
+	if dist, ok := search.MateDistance(info.Score); ok {
+		if dist > 0 {
+			score = mateScore + dist
+		} else {
+			score = -mateScore + dist
+		}
+	}
+
+	var pv strings.Builder
+	for _, m := range info.PV {
+		pv.WriteString(" " + m.String())
+	}
+	s.send(fmt.Sprintf("%d %d %d %d%s", info.Depth, score, info.Time.Milliseconds()/10, info.Nodes, pv.String()))
+}
+
+// newGame resets the position and the engine state. The engine plays black.
+func (s *session) newGame() {
+	s.e.NewGame()
+	s.startFEN = board.StartPos
+	s.moves = s.moves[:0]
+	s.replay()
+	s.engineSide = board.Black
+	s.force = false
+	s.depth = defaultDepth
+	s.moveTime = 0
+}
+
+// replay rebuilds the board from the start position and the game moves.
+func (s *session) replay() {
+	s.e.Board = board.NewBoard(s.startFEN)
+	s.e.PlayMovesUCI(strings.Join(s.moves, " "))
+}
+
+func (s *session) setBoard(fen string) {
+	b := &board.Board{}
+	if err := b.ImportFEN(fen); err != nil {
+		s.send("tellusererror Illegal position")
+		return
+	}
+	s.startFEN = fen
+	s.moves = s.moves[:0]
+	s.replay()
+}
+
+func (s *session) takeBack(n int) {
+	if len(s.moves) < n {
+		return
+	}
+	s.moves = s.moves[:len(s.moves)-n]
+	s.replay()
+}
+
+// setLevel parses "level MPS BASE INC" where BASE is minutes or minutes:seconds
+// and INC is seconds. The moves per session are left to the engine's own allocation.
+func (s *session) setLevel(args string) {
+	fields := strings.Fields(args)
+	if len(fields) != 3 {
+		s.send("Error (bad level): " + args)
+		return
+	}
+
+	minutes, seconds, _ := strings.Cut(fields[1], ":")
+	base, _ := strconv.Atoi(minutes)
+	sec, _ := strconv.Atoi(seconds)
+	inc, _ := strconv.ParseFloat(fields[2], 64)
+
+	s.time = (base*60 + sec) * 100
+	s.otim = s.time
+	s.inc = int(inc * 1000)
+	s.moveTime = 0
+}
+
+func (s *session) userMove(move string) {
+	s.stop()
+	if _, ok := s.e.Board.MoveUCI(move); !ok {
+		s.send("Illegal move: " + move)
+		s.resume()
+		return
+	}
+	s.moves = append(s.moves, move)
+	s.e.AddPly()
+
+	if result := gameResult(s.e); result != "" && !s.analyzing {
+		s.send(result)
+		return
+	}
+	s.resume()
+}
+
+// resume starts analysis or thinking if the current state calls for it.
+func (s *session) resume() {
+	switch {
+	case s.analyzing:
+		s.analyze()
+	case !s.force && s.e.Board.Side == s.engineSide && gameResult(s.e) == "":
+		s.think()
+	}
+}
+
+// setClock maps the xboard clocks onto the engine clock.
+func (s *session) setClock(infinite bool) {
+	own, opp := s.time*10, s.otim*10
+	if s.engineSide == board.Black {
+		own, opp = opp, own
+	}
+	s.e.Clock.Wtime, s.e.Clock.Btime = own, opp
+	s.e.Clock.Winc, s.e.Clock.Binc = s.inc, s.inc
+	s.e.Clock.Movetime = s.moveTime
+	s.e.Clock.Infinite = infinite
+	s.e.Analysis = infinite
+}
+
+// think searches the current position and plays the best move.
+func (s *session) think() {
+	e := s.e
+	s.setClock(false)
+	// Arm the time control here so that a ? received right after cannot be lost.
+	e.TC = e.Clock.NewTimeControl(int(e.Board.FullMoveCounter), e.Board.Side)
+	s.wg.Go(func() {
+		defer e.TC.Stop()
+		best, _ := e.FindMove(s.depth, false)
+		if s.discard.Load() {
+			return
+		}
+		move := best.String()
+		if _, ok := e.Board.MoveUCI(move); !ok {
+			return
+		}
+		s.moves = append(s.moves, move)
+		e.AddPly()
+		s.send("move " + move)
+		if result := gameResult(e); result != "" {
+			s.send(result)
+		}
+	})
+}
+
+// analyze searches the current position until stopped.
+func (s *session) analyze() {
+	e := s.e
+	if gameResult(e) != "" {
+		return
+	}
+	s.setClock(true)
+	e.TC = e.Clock.NewTimeControl(int(e.Board.FullMoveCounter), e.Board.Side)
+	s.wg.Go(func() {
+		e.IDSearch(defaultDepth, true)
+	})
+}
+
+// stop aborts any running search without playing its result.
+func (s *session) stop() {
+	s.discard.Store(true)
+	s.e.TC.Abort()
+	s.wg.Wait()
+	s.discard.Store(false)
+}
@@ -0,0 +1,186 @@
+// Package xboard implements the WinBoard/CECP protocol front end. It drives
+// the same search.Engine as the UCI front end.
+package xboard
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+	"github.com/likeawizard/tofiks/pkg/pgn"
+	"github.com/likeawizard/tofiks/pkg/search"
+)
+
+const (
+	CmdXboard   = "xboard"
+	CmdProtover = "protover" // N
+	CmdNew      = "new"
+	CmdForce    = "force"
+	CmdGo       = "go"
+	CmdUsermove = "usermove" // MOVE
+	CmdTime     = "time"     // centiseconds
+	CmdOtim     = "otim"     // centiseconds
+	CmdLevel    = "level"    // MPS BASE INC
+	CmdSt       = "st"       // seconds
+	CmdSd       = "sd"       // depth
+	CmdAnalyze  = "analyze"
+	CmdExit     = "exit"
+	CmdUndo     = "undo"
+	CmdRemove   = "remove"
+	CmdSetboard = "setboard" // FEN
+	CmdPost     = "post"
+	CmdNopost   = "nopost"
+	CmdPing     = "ping" // N
+	CmdMoveNow  = "?"
+	CmdResult   = "result" // RESULT {COMMENT}
+	CmdMemory   = "memory" // MB
+	CmdQuit     = "quit"
+)
+
+// features is the reply to protover 2.
+const features = `feature myname="` + search.Name + `" ping=1 setboard=1 usermove=1 time=1 draw=0 sigint=0 sigterm=0 reuse=1 analyze=1 colors=0 memory=1 variants="normal" done=1`
+
+// ignored lists commands that are valid but need no action.
+var ignored = map[string]bool{
+	CmdXboard: true, "accepted": true, "rejected": true, "random": true, "computer": true,
+	"hard": true, "easy": true, "draw": true, "name": true, "rating": true, "ics": true, ".": true,
+}
+
+// Run reads CECP commands line by line and executes them on the engine until
+// quit is received or the input is exhausted. On end of input a move in
+// progress is still played, analysis is stopped. The leading xboard command is expected to have
+// been consumed by the caller.
+func Run(input *bufio.Scanner, out io.Writer, e *search.Engine) {
+	s := newSession(out, e)
+	for input.Scan() {
+		if s.handle(strings.TrimSpace(input.Text())) {
+			s.stop()
+			return
+		}
+	}
+	if s.analyzing {
+		s.stop()
+	}
+	s.wg.Wait()
+}
+
+// handle executes a single command line. Returns true on quit.
+func (s *session) handle(line string) bool {
+	cmd, args, _ := strings.Cut(line, " ")
+	args = strings.TrimSpace(args)
+
+	switch {
+	case cmd == "" || ignored[cmd]:
+	case cmd == CmdQuit:
+		return true
+	case cmd == CmdProtover:
+		s.send(features)
+	case cmd == CmdPing:
+		s.send("pong " + args)
+	case cmd == CmdPost:
+		s.post.Store(true)
+	case cmd == CmdNopost:
+		s.post.Store(false)
+	case cmd == CmdTime:
+		s.time, _ = strconv.Atoi(args)
+	case cmd == CmdOtim:
+		s.otim, _ = strconv.Atoi(args)
+	case cmd == CmdMoveNow:
+		s.e.TC.Abort()
+	case cmd == CmdLevel:
+		s.setLevel(args)
+	case cmd == CmdSt:
+		seconds, _ := strconv.ParseFloat(args, 64)
+		s.moveTime = int(seconds * 1000)
+	case cmd == CmdSd:
+		if depth, err := strconv.Atoi(args); err == nil && depth > 0 {
+			s.depth = depth
+		}
+	case cmd == CmdMemory:
+		if size, err := strconv.Atoi(args); err == nil && size > 0 {
+			s.stop()
+			s.e.TTable = search.NewTTable(size)
+		}
+	case cmd == CmdNew:
+		s.stop()
+		s.newGame()
+		s.resume()
+	case cmd == CmdForce:
+		s.stop()
+		s.force = true
+	case cmd == CmdGo:
+		s.stop()
+		s.force = false
+		s.engineSide = s.e.Board.Side
+		s.resume()
+	case cmd == CmdAnalyze:
+		s.stop()
+		s.analyzing = true
+		s.resume()
+	case cmd == CmdExit:
+		s.stop()
+		s.analyzing = false
+	case cmd == CmdResult:
+		s.stop()
+		s.force = true
+		result, _, _ := strings.Cut(args, " ")
+		if score, ok := pgn.Score(result); ok {
+			s.e.EndGame(score)
+		}
+	case cmd == CmdUndo:
+		s.stop()
+		s.takeBack(1)
+		s.resume()
+	case cmd == CmdRemove:
+		s.stop()
+		s.takeBack(2)
+		s.resume()
+	case cmd == CmdSetboard:
+		s.stop()
+		s.setBoard(args)
+		s.resume()
+	case cmd == CmdUsermove:
+		s.userMove(args)
+	default:
+		// Protocol version 1 sends moves without the usermove prefix.
+		if line == cmd && isMove(cmd) {
+			s.userMove(cmd)
+			break
+		}
+		s.send("Error (unknown command): " + line)
+	}
+
+	return false
+}
+
+// isMove reports whether a token looks like a coordinate move such as e2e4 or a7a8q.
+func isMove(s string) bool {
+	if len(s) != 4 && len(s) != 5 {
+		return false
+	}
+	return s[0] >= 'a' && s[0] <= 'h' && s[1] >= '1' && s[1] <= '8' &&
+		s[2] >= 'a' && s[2] <= 'h' && s[3] >= '1' && s[3] <= '8'
+}
+
+// gameResult returns the CECP result string when the game is over, or "" otherwise.
+func gameResult(e *search.Engine) string {
+	if len(e.Board.LegalMoves()) == 0 {
+		switch {
+		case !e.Board.InCheck:
+			return "1/2-1/2 {Stalemate}"
+		case e.Board.Side == board.White:
+			return "0-1 {Black mates}"
+		default:
+			return "1-0 {White mates}"
+		}
+	}
+	if e.Board.HalfMoveCounter >= 100 {
+		return "1/2-1/2 {Fifty move rule}"
+	}
+	if e.Board.InsufficientMaterial() {
+		return "1/2-1/2 {Insufficient material}"
+	}
+	return ""
+}
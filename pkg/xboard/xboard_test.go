@@ -0,0 +1,42 @@
+package xboard
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/likeawizard/tofiks/pkg/search"
+)
+
+func run(t *testing.T, commands ...string) []string {
+	t.Helper()
+	var out bytes.Buffer
+	input := bufio.NewScanner(strings.NewReader(strings.Join(commands, "\n")))
+	Run(input, &out, search.NewEngine())
+	return strings.Split(strings.TrimSpace(out.String()), "\n")
+}
+
+func TestEngineReplies(t *testing.T) {
+	lines := run(t, "protover 2", "new", "sd 3", "usermove e2e4", "ping 1")
+	assert.Contains(t, lines[0], "done=1")
+	assert.Contains(t, lines, "pong 1")
+	assert.True(t, strings.HasPrefix(lines[len(lines)-1], "move "), "last line %q", lines[len(lines)-1])
+}
+
+func TestForceAndUndo(t *testing.T) {
+	lines := run(t, "new", "force", "e2e4", "e7e5", "undo", "remove", "e2e5", "ping 2")
+	assert.Equal(t, []string{"Illegal move: e2e5", "pong 2"}, lines)
+}
+
+func TestGameEnd(t *testing.T) {
+	lines := run(t, "new", "force", "setboard 7k/5Q2/6K1/8/8/8/8/8 w - - 0 1", "sd 2", "go")
+	assert.Equal(t, []string{"move f7h7", "1-0 {White mates}"}, lines)
+}
+
+func TestPostMateScore(t *testing.T) {
+	lines := run(t, "new", "post", "setboard 7k/5Q2/6K1/8/8/8/8/8 w - - 0 1", "sd 2", "go")
+	assert.True(t, strings.HasPrefix(lines[0], "1 100001 "), "thinking line %q", lines[0])
+}
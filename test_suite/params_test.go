@@ -0,0 +1,47 @@
+package testsuite
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/likeawizard/tofiks/pkg/board"
+	"github.com/likeawizard/tofiks/pkg/eval"
+	"github.com/likeawizard/tofiks/pkg/search"
+	"github.com/likeawizard/tofiks/pkg/texel"
+	"github.com/likeawizard/tofiks/pkg/uci"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEvalParamsRoundTrip exports the weights the way cmd/texel does, loads
+// them back through the EvalParams option and checks that every eval is
+// unchanged.
+func TestEvalParamsRoundTrip(t *testing.T) {
+	defer eval.ResetParams()
+	e := search.NewEngine()
+	evaluate := func() []int {
+		e.Eval.PawnTable.Clear()
+		evals := make([]int, len(positions))
+		for i, fen := range positions {
+			evals[i] = e.Eval.GetEvaluation(board.NewBoard(fen))
+		}
+		return evals
+	}
+	want := evaluate()
+
+	weights := texel.InitialParams()
+	path := filepath.Join(t.TempDir(), "params.json")
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	assert.NoError(t, texel.ExportParams(&weights).Write(f))
+	assert.NoError(t, f.Close())
+
+	// Load a different set first so the round trip has something to undo.
+	eval.PieceWeights[board.Queens] += 100
+	eval.Tempo = eval.S(0, 0)
+	assert.NotEqual(t, want, evaluate())
+
+	assert.NoError(t, uci.SetEvalParams(e, path))
+	assert.Equal(t, want, evaluate())
+	assert.Equal(t, eval.CurrentParams(), texel.ExportParams(&weights))
+}